@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ridoystarlord/migrato/database"
+	"github.com/ridoystarlord/migrato/diff"
+	"github.com/ridoystarlord/migrato/generator"
+	"github.com/ridoystarlord/migrato/introspect"
+	"github.com/ridoystarlord/migrato/loader"
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/spf13/cobra"
+)
+
+var baselineSchemaFile string
+var baselineModelsDir string
+var dryRunBaseline bool
+
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Adopt migrato on an existing database",
+	Long: `Record the current schema of an existing database as version zero.
+
+baseline introspects the database in DATABASE_URL and writes:
+- migrations/<timestamp>_baseline.sql, creating every table, index and foreign key
+- matching Go structs in models/baseline.go (or schema.yaml with --yaml)
+
+The baseline migration is recorded as applied in schema_migrations without
+being executed, so the next 'migrato generate' starts from the real schema.
+Fresh environments run the baseline like any other migration.
+
+Examples:
+  migrato baseline                 # Write migration and Go structs
+  migrato baseline --yaml          # Write migration and schema.yaml
+  migrato baseline --dry-run       # Print the baseline migration only
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		d, err := database.GetDialect()
+		if err != nil {
+			fmt.Println("❌ Detecting database dialect:", err)
+			os.Exit(1)
+		}
+
+		existing, err := introspect.IntrospectDatabase(cmd.Context())
+		if err != nil {
+			fmt.Println("❌ Introspecting database:", err)
+			os.Exit(1)
+		}
+
+		models := introspect.ToModels(existing)
+		if len(models) == 0 {
+			fmt.Println("❌ The database has no tables to baseline. Use 'migrato generate' instead.")
+			os.Exit(1)
+		}
+
+		ops := diff.CreateOperations(models, d.SupportsConstraints())
+		sqls, err := generator.GenerateSQL(d, ops)
+		if err != nil {
+			fmt.Println("❌ Generating SQL:", err)
+			os.Exit(1)
+		}
+		rollbackSqls, err := generator.GenerateRollbackSQL(d, ops)
+		if err != nil {
+			fmt.Println("❌ Generating rollback SQL:", err)
+			os.Exit(1)
+		}
+
+		if dryRunBaseline {
+			fmt.Println("\n================ DRY RUN: Baseline Preview ================")
+			fmt.Println("-- Up Migration SQL --")
+			for _, stmt := range sqls {
+				fmt.Println(stmt)
+			}
+			fmt.Println("\n-- Down Migration (Rollback) SQL --")
+			for _, stmt := range rollbackSqls {
+				fmt.Println(stmt)
+			}
+			fmt.Println("===========================================================")
+			fmt.Println("(Dry run only. No files were written.)")
+			return
+		}
+
+		// A baseline is version zero; anything already tracked would run before it
+		if existingMigrations, _ := filepath.Glob(filepath.Join(runner.MigrationsDir, "*.sql")); len(existingMigrations) > 0 {
+			fmt.Printf("❌ %s/ already contains migrations. baseline is for databases not yet managed by migrato.\n", runner.MigrationsDir)
+			os.Exit(1)
+		}
+		applied, err := runner.AppliedMigrations(cmd.Context())
+		if err != nil {
+			fmt.Println("❌ Reading migration status:", err)
+			os.Exit(1)
+		}
+		if len(applied) > 0 {
+			fmt.Printf("❌ The database already has %d applied migration(s).\n", len(applied))
+			os.Exit(1)
+		}
+
+		// Render the models before writing anything so a failure leaves no files behind
+		modelsPath := filepath.Join(baselineModelsDir, "baseline.go")
+		var modelsSource []byte
+		var warnings []string
+		if useYAML {
+			modelsPath = baselineSchemaFile
+			modelsSource, err = loader.ModelsToYAML(models)
+		} else {
+			modelsSource, warnings, err = loader.ModelsToStructs(baselineModelsDir, models)
+		}
+		if err != nil {
+			fmt.Println("❌ Rendering models:", err)
+			os.Exit(1)
+		}
+		if _, err := os.Stat(modelsPath); err == nil {
+			fmt.Printf("❌ %s already exists!\n", modelsPath)
+			os.Exit(1)
+		}
+
+		filename, err := generator.WriteMigration(generator.MigrationFile{
+			Name:        "baseline",
+			Description: "Baseline of the existing database",
+			Up:          sqls,
+			Down:        rollbackSqls,
+		})
+		if err != nil {
+			fmt.Println("❌ Writing migration file:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ Baseline migration written:", filename)
+
+		if err := os.MkdirAll(filepath.Dir(modelsPath), 0755); err != nil {
+			fmt.Println("❌ Creating models directory:", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(modelsPath, modelsSource, 0644); err != nil {
+			fmt.Println("❌ Writing models:", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ Models written:", modelsPath)
+		for _, warning := range warnings {
+			fmt.Println("⚠️ ", warning)
+		}
+
+		if err := runner.MarkApplied(cmd.Context(), filepath.Base(filename)); err != nil {
+			fmt.Println("❌ Recording baseline:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Recorded %s as applied (%d tables)\n", filepath.Base(filename), len(models))
+	},
+}
+
+func init() {
+	baselineCmd.Flags().StringVarP(&baselineSchemaFile, "file", "f", "schema.yaml", "Schema YAML file to write (with --yaml)")
+	baselineCmd.Flags().StringVarP(&baselineModelsDir, "models", "m", "models", "Models directory to write structs to")
+	baselineCmd.Flags().BoolVar(&dryRunBaseline, "dry-run", false, "Print the baseline migration without writing files")
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/ridoystarlord/migrato/database"
+	"github.com/ridoystarlord/migrato/runner"
 	"github.com/spf13/cobra"
 )
 
@@ -55,27 +56,27 @@ func checkDatabaseSchema() error {
 		return fmt.Errorf("failed to ping database: %v", err)
 	}
 
-	// Check if schema_migrations table exists
+	// Check if the migrations table exists
 	var tableExists bool
 	query := `SELECT EXISTS (
 		SELECT FROM information_schema.tables 
-		WHERE table_schema = 'public' 
-		AND table_name = 'schema_migrations'
+		WHERE table_schema = current_schema()
+		AND table_name = $1
 	)`
 	
-	if err := pool.QueryRow(ctx, query).Scan(&tableExists); err != nil {
-		return fmt.Errorf("failed to check schema_migrations table: %v", err)
+	if err := pool.QueryRow(ctx, query, runner.TrackingTables.Migrations).Scan(&tableExists); err != nil {
+		return fmt.Errorf("failed to check %s table: %v", runner.TrackingTables.Migrations, err)
 	}
 
 	if !tableExists {
-		fmt.Println("⚠️  schema_migrations table not found")
+		fmt.Printf("⚠️  %s table not found\n", runner.TrackingTables.Migrations)
 		fmt.Println("   Run 'migrato init' to set up the migration tracking table")
 		return nil
 	}
 
 	// Check migration count
 	var count int
-	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM schema_migrations").Scan(&count); err != nil {
+	if err := pool.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", runner.TrackingTables.Migrations)).Scan(&count); err != nil {
 		return fmt.Errorf("failed to count migrations: %v", err)
 	}
 
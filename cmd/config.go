@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ridoystarlord/migrato/config"
+	"github.com/ridoystarlord/migrato/database"
+	"github.com/ridoystarlord/migrato/generator"
+	"github.com/ridoystarlord/migrato/introspect"
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/ridoystarlord/migrato/utils"
+)
+
+// loadConfig resolves the settings of the selected environment and applies
+// them to every package, so that all commands read them from one place.
+// Flags given on the command line take precedence.
+func loadConfig(cmd *cobra.Command) error {
+	c, err := config.Load(configFile, configEnv)
+	if err != nil {
+		return err
+	}
+	projectConfig = c
+
+	utils.EnvFile = c.EnvFile
+	database.URL = c.URL
+	database.Schemas = c.Schemas
+
+	runner.MigrationsDir = c.MigrationsDir
+	generator.MigrationsDir = c.MigrationsDir
+	tables := runner.Tables{Migrations: c.Tables.Migrations, Logs: c.Tables.Logs, History: c.Tables.History, Seeds: c.Tables.Seeds}.WithDefaults()
+	seen := map[string]bool{}
+	for _, name := range []string{tables.Migrations, tables.Logs, tables.History, tables.Seeds} {
+		if seen[name] {
+			return fmt.Errorf("%s: the migrations, logs, history and seeds tables must differ", c.File)
+		}
+		seen[name] = true
+	}
+	runner.TrackingTables = tables
+	introspect.TrackingTables = []string{tables.Migrations, tables.Logs, tables.History, tables.Version(), tables.Seeds}
+	introspect.IgnoredTables = c.Ignore
+	if len(c.Schemas) > 0 {
+		introspect.DefaultSchema = c.Schemas[0]
+	}
+
+	flags := cmd.Flags()
+	unset := func(name string) bool {
+		f := flags.Lookup(name)
+		return f == nil || !f.Changed
+	}
+	if unset("lock-key") {
+		runner.LockKey = c.Safety.LockKey
+		if runner.LockKey == 0 {
+			runner.LockKey = runner.DefaultLockKey
+		}
+	}
+	if unset("lock-wait") {
+		runner.LockWait = c.Safety.LockWait
+	}
+	if unset("lock-timeout") {
+		runner.LockTimeout = c.Safety.LockTimeout
+	}
+	if unset("statement-timeout") {
+		runner.StatementTimeout = c.Safety.StatementTimeout
+	}
+	if unset("retries") {
+		runner.Retries = c.Safety.Retries
+	}
+	if unset("allow-out-of-order") {
+		runner.AllowOutOfOrder = c.Safety.AllowOutOfOrder
+	}
+	if unset("templates") {
+		generator.TemplateDir = c.TemplatesDir
+	}
+
+	// Command flags defaulting to a configured path
+	defaults := map[string]string{
+		"file":   c.SchemaFile,
+		"schema": c.SchemaFile,
+		"models": c.ModelsDir,
+		"dir":    c.SeedsDir,
+		"port":   strconv.Itoa(c.Studio.Port),
+	}
+	if cmd == templatesCmd {
+		defaults["output"] = c.TemplatesDir
+	}
+	for name, value := range defaults {
+		if f := flags.Lookup(name); f != nil && !f.Changed {
+			if err := f.Value.Set(value); err != nil {
+				return fmt.Errorf("--%s from %s: %v", name, c.File, err)
+			}
+		}
+	}
+	return nil
+}
@@ -48,7 +48,7 @@ Examples:
 		if useYAML {
 			schemaFile := diffFile
 			if schemaFile == "" {
-				schemaFile = "schema.yaml"
+				schemaFile = projectConfig.SchemaFile
 			}
 			models, err = loader.LoadModelsFromYAML(schemaFile)
 			if err != nil {
@@ -58,7 +58,7 @@ Examples:
 		} else {
 			modelsDir := diffModelsDir
 			if modelsDir == "" {
-				modelsDir = "models"
+				modelsDir = projectConfig.ModelsDir
 			}
 			models, err = loader.LoadModelsFromTags(modelsDir)
 			if err != nil {
@@ -68,7 +68,7 @@ Examples:
 		}
 
 		// Introspect database
-		existing, err := introspect.IntrospectDatabase()
+		existing, err := introspect.IntrospectDatabase(cmd.Context())
 		if err != nil {
 			fmt.Printf("❌ Error introspecting database: %v\n", err)
 			os.Exit(1)
@@ -52,7 +52,7 @@ Examples:
 		if useYAML {
 			schemaFilePath := docsFile
 			if schemaFilePath == "" {
-				schemaFilePath = "schema.yaml"
+				schemaFilePath = projectConfig.SchemaFile
 			}
 			models, err = loader.LoadModelsFromYAML(schemaFilePath)
 			if err != nil {
@@ -60,7 +60,7 @@ Examples:
 				os.Exit(1)
 			}
 		} else {
-			models, err = loader.LoadModelsFromTags("models")
+			models, err = loader.LoadModelsFromTags(projectConfig.ModelsDir)
 			if err != nil {
 				fmt.Printf("❌ Error loading Go structs: %v\n", err)
 				os.Exit(1)
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat string
+	exportDir    string
+	exportForce  bool
+	exportDryRun bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the migrations in the format of golang-migrate, goose or Flyway",
+	Long: `Convert migrato's migration files into the layout and naming of another
+tool, so migrations authored with migrato can be delivered to projects that
+run that tool. The database is not touched.
+
+Formats and the files written for each migration:
+  golang-migrate   <version>_<name>.up.sql and <version>_<name>.down.sql
+  goose            <version>_<name>.sql with -- +goose Up/Down annotations
+  flyway           V<version>__<name>.sql and the undo file U<version>__<name>.sql
+  plain-sql        up/<version>_<name>.sql and down/<version>_<name>.sql
+
+The no-transaction directive becomes goose's NO TRANSACTION annotation or a
+Flyway script configuration with executeInTransaction=false. What the target
+tool cannot express, such as COPY ... FROM stdin, is reported as a warning.
+
+Examples:
+  migrato export --format golang-migrate --dir dist/migrations
+  migrato export --format flyway --dir dist/flyway --dry-run
+  migrato export --format goose --dir dist/goose --force
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := runner.ExportMigrations(runner.ExportOptions{
+			Format: exportFormat,
+			Dir:    exportDir,
+			Force:  exportForce,
+			DryRun: exportDryRun,
+		})
+		if err != nil {
+			fmt.Println("❌ Export failed:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", "", "Format to write: golang-migrate, goose, flyway or plain-sql")
+	exportCmd.Flags().StringVar(&exportDir, "dir", "", "Directory the converted files are written to")
+	exportCmd.Flags().BoolVar(&exportForce, "force", false, "Overwrite files already in the directory")
+	exportCmd.Flags().BoolVar(&exportDryRun, "dry-run", false, "List the files without writing them")
+	exportCmd.MarkFlagRequired("format")
+	exportCmd.MarkFlagRequired("dir")
+}
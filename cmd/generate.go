@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/ridoystarlord/migrato/database"
 	"github.com/ridoystarlord/migrato/diff"
 	"github.com/ridoystarlord/migrato/generator"
 	"github.com/ridoystarlord/migrato/introspect"
@@ -20,6 +21,7 @@ func init() {
 	generateCmd.Flags().StringVarP(&schemaFile, "file", "f", "schema.yaml", "Schema YAML file to load")
 	generateCmd.Flags().StringVarP(&generateModelsDir, "models", "m", "models", "Models directory to load structs from")
 	generateCmd.Flags().BoolVar(&dryRunGenerate, "dry-run", false, "Preview the SQL that would be generated without writing files")
+	generateCmd.Flags().StringVar(&generator.TemplateDir, "templates", "templates", "Directory with SQL template overrides")
 }
 
 var generateCmd = &cobra.Command{
@@ -39,6 +41,7 @@ Examples:
   migrato generate -m mymodels/       # Generate from custom models directory
   migrato generate --yaml             # Generate from schema.yaml
   migrato generate --yaml -f custom.yaml  # Generate from custom YAML file
+  migrato generate --templates sql/      # Render SQL with project templates from sql/
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 
@@ -59,7 +62,13 @@ Examples:
 			}
 		}
 
-		existing, err := introspect.IntrospectDatabase()
+		d, err := database.GetDialect()
+		if err != nil {
+			fmt.Println("❌ Detecting database dialect:", err)
+			os.Exit(1)
+		}
+
+		existing, err := introspect.IntrospectDatabase(cmd.Context())
 		if err != nil {
 			fmt.Println("❌ Introspecting database:", err)
 			os.Exit(1)
@@ -71,13 +80,13 @@ Examples:
 			return
 		}
 
-		sqls, err := generator.GenerateSQL(ops)
+		sqls, err := generator.GenerateSQL(d, ops)
 		if err != nil {
 			fmt.Println("❌ Generating SQL:", err)
 			os.Exit(1)
 		}
 
-		rollbackSqls, err := generator.GenerateRollbackSQL(ops)
+		rollbackSqls, err := generator.GenerateRollbackSQL(d, ops)
 		if err != nil {
 			fmt.Println("❌ Generating rollback SQL:", err)
 			os.Exit(1)
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"os"
 	"sort"
@@ -11,7 +10,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
-	"github.com/ridoystarlord/migrato/introspect"
+	"github.com/ridoystarlord/migrato/database"
 	"github.com/ridoystarlord/migrato/runner"
 )
 
@@ -19,6 +18,7 @@ var (
 	historyLimit int
 	historyTable string
 	historyDetailed bool
+	historyMigration string
 )
 
 var historyCmd = &cobra.Command{
@@ -26,23 +26,26 @@ var historyCmd = &cobra.Command{
 	Short: "Show detailed migration history",
 	Long: `Show detailed migration history with timestamps, execution times, and user information.
 
+Every apply and rollback is kept as an event, so a migration that was applied,
+rolled back and applied again shows up three times.
+
 Examples:
   migrato history                    # Show all migration history
-  migrato history --limit 10         # Show last 10 migrations
+  migrato history --limit 10         # Show last 10 events
   migrato history --table users      # Show migrations for specific table
+  migrato history --migration 2024   # Show the events of matching migrations
   migrato history --detailed         # Show detailed information
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Connect to database
-		db, err := introspect.Connect()
+		db, d, err := database.GetDB()
 		if err != nil {
 			fmt.Printf("❌ Error connecting to database: %v\n", err)
 			os.Exit(1)
 		}
-		defer db.Close(context.Background())
 
 		// Get migration history
-		history, err := runner.GetMigrationHistory(db, historyLimit, historyTable)
+		history, err := runner.GetMigrationHistory(cmd.Context(), db, d, historyLimit, historyTable, historyMigration)
 		if err != nil {
 			fmt.Printf("❌ Error getting migration history: %v\n", err)
 			os.Exit(1)
@@ -53,8 +56,8 @@ Examples:
 			return
 		}
 
-		// Sort by timestamp (newest first)
-		sort.Slice(history, func(i, j int) bool {
+		// Sort by timestamp (newest first); events of the same second keep their order
+		sort.SliceStable(history, func(i, j int) bool {
 			return history[i].ExecutedAt.After(history[j].ExecutedAt)
 		})
 
@@ -62,6 +65,47 @@ Examples:
 	},
 }
 
+// eventAction names what an event of the history did
+func eventAction(record runner.MigrationRecord) string {
+	action := "applied"
+	if record.Direction == "down" {
+		action = "rolled back"
+	}
+	switch record.Status {
+	case "failed":
+		if record.Direction == "down" {
+			return "rollback failed"
+		}
+		return "apply failed"
+	case "recorded":
+		return "recorded as applied"
+	case "imported":
+		return "imported as applied"
+	case "resolved":
+		return "resolved as " + action
+	}
+	return action
+}
+
+// eventLine describes an event of the history on one line
+func eventLine(record runner.MigrationRecord) string {
+	icon := "⬆️ "
+	if record.Direction == "down" {
+		icon = "⬇️ "
+	}
+	if record.Status == "failed" {
+		icon = "❌"
+	}
+	line := fmt.Sprintf("%s %s  %-20s %s", icon, record.ExecutedAt.Format("2006-01-02 15:04:05"), eventAction(record), record.MigrationName)
+	if record.ExecutedBy != "" {
+		line += fmt.Sprintf(" by %s", record.ExecutedBy)
+	}
+	if record.ExecutionTime > 0 {
+		line += fmt.Sprintf(" in %v", record.ExecutionTime)
+	}
+	return line
+}
+
 func showMigrationHistory(history []runner.MigrationRecord, detailed bool) {
 	fmt.Println("📋 Migration History")
 	fmt.Println(strings.Repeat("=", 60))
@@ -84,17 +128,20 @@ func showDetailedHistory(history []runner.MigrationRecord) {
 		fmt.Printf("\n%d. ", i+1)
 		
 		// Status indicator
-		if record.Status == "success" {
-			green.Print("✅ ")
-		} else if record.Status == "failed" {
+		if record.Status == "failed" {
 			red.Print("❌ ")
+		} else if record.Direction == "down" {
+			yellow.Print("⬇️  ")
 		} else {
-			yellow.Print("⚠️ ")
+			green.Print("✅ ")
 		}
 
 		// Migration name
 		blue.Printf("%s\n", record.MigrationName)
 		
+		// What happened
+		cyan.Printf("   🔁 Event: %s\n", eventAction(record))
+		
 		// Timestamp
 		cyan.Printf("   📅 Executed: %s\n", record.ExecutedAt.Format("2006-01-02 15:04:05"))
 		
@@ -129,18 +176,18 @@ func showSummaryHistory(history []runner.MigrationRecord) {
 	red := color.New(color.FgRed, color.Bold)
 	blue := color.New(color.FgBlue, color.Bold)
 
-	fmt.Printf("%-4s %-8s %-25s %-12s %-10s %s\n", "ID", "Status", "Migration", "Duration", "User", "Date")
-	fmt.Println(strings.Repeat("-", 80))
+	fmt.Printf("%-4s %-8s %-20s %-25s %-12s %-10s %s\n", "ID", "Status", "Event", "Migration", "Duration", "User", "Date")
+	fmt.Println(strings.Repeat("-", 100))
 
 	for i, record := range history {
 		// Status indicator
 		var status string
-		if record.Status == "success" {
-			status = green.Sprint("✅")
-		} else if record.Status == "failed" {
+		if record.Status == "failed" {
 			status = red.Sprint("❌")
+		} else if record.Direction == "down" {
+			status = yellow.Sprint("⬇️")
 		} else {
-			status = yellow.Sprint("⚠️")
+			status = green.Sprint("✅")
 		}
 
 		// Duration
@@ -163,9 +210,10 @@ func showSummaryHistory(history []runner.MigrationRecord) {
 			migrationName = migrationName[:20] + "..."
 		}
 
-		fmt.Printf("%-4d %-8s %-25s %-12s %-10s %s\n",
+		fmt.Printf("%-4d %-8s %-20s %-25s %-12s %-10s %s\n",
 			i+1,
 			status,
+			eventAction(record),
 			blue.Sprint(migrationName),
 			duration,
 			user,
@@ -174,25 +222,28 @@ func showSummaryHistory(history []runner.MigrationRecord) {
 	}
 
 	// Summary statistics
-	fmt.Println(strings.Repeat("-", 80))
+	fmt.Println(strings.Repeat("-", 100))
 	
 	successCount := 0
 	failedCount := 0
+	rolledBackCount := 0
 	totalDuration := time.Duration(0)
 	
 	for _, record := range history {
-		if record.Status == "success" {
-			successCount++
-		} else if record.Status == "failed" {
+		if record.Status == "failed" {
 			failedCount++
+		} else if record.Direction == "down" {
+			rolledBackCount++
+		} else {
+			successCount++
 		}
 		if record.ExecutionTime > 0 {
 			totalDuration += record.ExecutionTime
 		}
 	}
 
-	fmt.Printf("📊 Summary: %d total, %d successful, %d failed\n", 
-		len(history), successCount, failedCount)
+	fmt.Printf("📊 Summary: %d total, %d successful, %d rolled back, %d failed\n", 
+		len(history), successCount, rolledBackCount, failedCount)
 	
 	if totalDuration > 0 {
 		fmt.Printf("⏱️  Total execution time: %v\n", totalDuration)
@@ -203,4 +254,5 @@ func init() {
 	historyCmd.Flags().IntVarP(&historyLimit, "limit", "l", 0, "Limit number of records to show (0 = all)")
 	historyCmd.Flags().StringVarP(&historyTable, "table", "t", "", "Filter by table name")
 	historyCmd.Flags().BoolVarP(&historyDetailed, "detailed", "d", false, "Show detailed information")
+	historyCmd.Flags().StringVarP(&historyMigration, "migration", "m", "", "Filter by migration filename")
 } 
\ No newline at end of file
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importFrom   string
+	importDir    string
+	importTable  string
+	importDryRun bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Adopt migrations managed by goose, golang-migrate, Flyway or Atlas",
+	Long: `Convert the migrations of another tool into migrato files and carry over
+which of them the database has applied.
+
+Supported tools and the version tables they are read from:
+  goose            -- +goose Up/Down files; goose_db_version
+  golang-migrate   <version>_<name>.up.sql/.down.sql; schema_migrations(version, dirty)
+  flyway           V<version>__<name>.sql with U<version> undo files; flyway_schema_history
+  atlas            <version>_<name>.sql without down SQL; atlas_schema_revisions
+
+Applied versions are recorded in schema_migrations with their original time,
+user and duration where the tool keeps them, so they are not run again. A
+dirty or failed version is recorded as failed; settle it with 'migrato
+resolve'. golang-migrate's schema_migrations table is kept as
+schema_migrations_golang_migrate.
+
+Examples:
+  migrato import --from goose --dir db/goose --dry-run
+  migrato import --from golang-migrate --dir db/migrations-old
+  migrato import --from flyway --dir src/main/resources/db/migration
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := runner.ImportMigrations(cmd.Context(), runner.ImportOptions{
+			From:   importFrom,
+			Dir:    importDir,
+			Table:  importTable,
+			DryRun: importDryRun,
+		})
+		if err != nil {
+			fmt.Println("❌ Import failed:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importFrom, "from", "", "Tool the migrations come from: goose, golang-migrate, flyway or atlas")
+	importCmd.Flags().StringVar(&importDir, "dir", "", "Directory holding the tool's migration files")
+	importCmd.Flags().StringVar(&importTable, "table", "", "Version table of the tool (default: the tool's default table)")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Show the conversion without writing files or recording anything")
+	importCmd.MarkFlagRequired("from")
+	importCmd.MarkFlagRequired("dir")
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 )
@@ -31,8 +32,8 @@ Examples:
 		// Determine which approach to use (default to structs)
 		if useYAML {
 			// Initialize with YAML schema
-			if _, err := os.Stat("schema.yaml"); err == nil {
-				fmt.Println("❌ schema.yaml already exists!")
+			if _, err := os.Stat(projectConfig.SchemaFile); err == nil {
+				fmt.Printf("❌ %s already exists!\n", projectConfig.SchemaFile)
 				return
 			}
 			
@@ -138,24 +139,24 @@ tables:
 # - default: CURRENT_TIME             # Current time
 # - default: 'default-value'          # Quoted string for values with spaces
 `
-			err := os.WriteFile("schema.yaml", []byte(content), 0644)
+			err := os.WriteFile(projectConfig.SchemaFile, []byte(content), 0644)
 			if err != nil {
-				fmt.Println("❌ Error creating schema.yaml:", err)
+				fmt.Printf("❌ Error creating %s: %v\n", projectConfig.SchemaFile, err)
 				return
 			}
-			fmt.Println("✅ Created schema.yaml example file.")
-			fmt.Println("📝 Edit schema.yaml to define your database schema")
+			fmt.Printf("✅ Created %s example file.\n", projectConfig.SchemaFile)
+			fmt.Printf("📝 Edit %s to define your database schema\n", projectConfig.SchemaFile)
 			fmt.Println("🚀 Run 'migrato generate' to create migrations from your schema")
 			return
 		}
 
 		// Initialize with Go structs (default)
-		if _, err := os.Stat("models"); err == nil {
+		if _, err := os.Stat(projectConfig.ModelsDir); err == nil {
 			fmt.Println("❌ models directory already exists!")
 			return
 		}
 		// Create models directory
-		if err := os.MkdirAll("models", 0755); err != nil {
+		if err := os.MkdirAll(projectConfig.ModelsDir, 0755); err != nil {
 			fmt.Println("❌ Failed to create models directory:", err)
 			return
 		}
@@ -225,7 +226,7 @@ type Category struct {
 }
 `
 
-		mainPath := "models/main.go"
+		mainPath := filepath.Join(projectConfig.ModelsDir, "main.go")
 		if err := os.WriteFile(mainPath, []byte(mainContent), 0644); err != nil {
 			fmt.Println("❌ Failed to create main.go:", err)
 			return
@@ -331,7 +332,7 @@ See the examples in main.go for complete struct definitions including:
 - Self-referencing foreign keys (Category)
 `
 
-		readmePath := "models/README.md"
+		readmePath := filepath.Join(projectConfig.ModelsDir, "README.md")
 		if err := os.WriteFile(readmePath, []byte(readmeContent), 0644); err != nil {
 			fmt.Println("❌ Failed to create README.md:", err)
 			return
@@ -339,7 +340,7 @@ See the examples in main.go for complete struct definitions including:
 
 		fmt.Println("✅ Models directory created successfully!")
 		fmt.Println("📁 Directory: models")
-		fmt.Println("📝 Edit the structs in models/main.go to define your database schema")
+		fmt.Printf("📝 Edit the structs in %s to define your database schema\n", mainPath)
 		fmt.Println("🚀 Run 'migrato generate --structs' to create migrations from your structs")
 	},
 }
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
 	"os"
 	"sort"
@@ -10,13 +9,14 @@ import (
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
-	"github.com/ridoystarlord/migrato/introspect"
+	"github.com/ridoystarlord/migrato/database"
 	"github.com/ridoystarlord/migrato/runner"
 )
 
 var (
 	logLimit int
 	logFollow bool
+	logMigration string
 )
 
 var logCmd = &cobra.Command{
@@ -27,19 +27,19 @@ var logCmd = &cobra.Command{
 Examples:
   migrato log                    # Show recent migration logs
   migrato log --limit 20         # Show last 20 log entries
+  migrato log --migration 2024   # Show every apply and rollback of matching migrations
   migrato log --follow           # Follow logs in real-time (future feature)
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Connect to database
-		db, err := introspect.Connect()
+		db, _, err := database.GetDB()
 		if err != nil {
 			fmt.Printf("❌ Error connecting to database: %v\n", err)
 			os.Exit(1)
 		}
-		defer db.Close(context.Background())
 
 		// Get recent logs
-		logs, err := runner.GetMigrationLogs(db, logLimit)
+		logs, err := runner.GetMigrationLogs(cmd.Context(), db, logLimit, logMigration)
 		if err != nil {
 			fmt.Printf("❌ Error getting migration logs: %v\n", err)
 			os.Exit(1)
@@ -51,7 +51,7 @@ Examples:
 		}
 
 		// Sort by timestamp (newest first)
-		sort.Slice(logs, func(i, j int) bool {
+		sort.SliceStable(logs, func(i, j int) bool {
 			return logs[i].Timestamp.After(logs[j].Timestamp)
 		})
 
@@ -113,4 +113,5 @@ func showMigrationLogs(logs []runner.MigrationLog) {
 func init() {
 	logCmd.Flags().IntVarP(&logLimit, "limit", "l", 50, "Limit number of log entries to show")
 	logCmd.Flags().BoolVarP(&logFollow, "follow", "f", false, "Follow logs in real-time (future feature)")
+	logCmd.Flags().StringVarP(&logMigration, "migration", "m", "", "Filter by migration filename")
 } 
\ No newline at end of file
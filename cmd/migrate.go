@@ -3,20 +3,60 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/ridoystarlord/migrato/runner"
 	"github.com/spf13/cobra"
 )
 
 var dryRunMigrate bool
+var migrateTarget string
 
 var migrateCmd = &cobra.Command{
 	Use:   "migrate",
 	Short: "Apply pending migrations",
+	Long: `Apply pending migrations, in version order.
+
+Examples:
+  migrato migrate                        # Apply all pending migrations
+  migrato migrate --to 20240601093000    # Apply pending migrations up to this version
+  migrato migrate --dry-run              # Print the SQL that would be executed
+
+On a protected environment, pending migrations that drop tables or columns,
+change column types or delete rows need --confirm=<env> or the environment
+name typed at the prompt.
+
+With --schemas, --schemas-file or --schemas-query the migrations run on each
+PostgreSQL schema of the database, and with --urls or --urls-file on each
+database, --parallel at a time. Every target keeps its own tracking tables;
+a failing target does not stop the others and the summary lists it.
+
+  migrato migrate --schemas-query "SELECT schema_name FROM tenants" --parallel 8
+  migrato migrate --urls-file databases.txt
+`,
 	Run: func(cmd *cobra.Command, args []string) {
+		verifySum()
+
+		targets, err := fanOutTargets(cmd.Context())
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		if len(targets) > 0 {
+			if dryRunMigrate {
+				fmt.Println("❌ --dry-run runs on one database; drop the target flags")
+				os.Exit(1)
+			}
+			confirmDestructive()
+			results := runner.MigrateTargets(cmd.Context(), targets, targetParallel, migrateTarget)
+			if runner.PrintTargetSummary(results) > 0 {
+				os.Exit(1)
+			}
+			return
+		}
 
 		if dryRunMigrate {
-			err := runner.PreviewMigrations()
+			err := runner.PreviewMigrationsTo(cmd.Context(), migrateTarget)
 			if err != nil {
 				fmt.Println("❌ Dry run failed:", err)
 				os.Exit(1)
@@ -24,7 +64,8 @@ var migrateCmd = &cobra.Command{
 			return
 		}
 
-		err := runner.ApplyMigrations()
+		confirmDestructive()
+		err = runner.ApplyMigrationsTo(cmd.Context(), migrateTarget)
 		if err != nil {
 			fmt.Println("❌ Migration failed:", err)
 			os.Exit(1)
@@ -33,5 +74,14 @@ var migrateCmd = &cobra.Command{
 }
 
 func init() {
+	migrateCmd.Flags().StringVar(&migrateTarget, "to", "", "Apply pending migrations up to and including this version")
+	migrateCmd.Flags().BoolVar(&runner.AllowOutOfOrder, "allow-out-of-order", false, "Apply pending migrations that sort before the latest applied one")
 	migrateCmd.Flags().BoolVar(&dryRunMigrate, "dry-run", false, "Preview the SQL that would be executed without applying migrations")
+	migrateCmd.Flags().Int64Var(&runner.LockKey, "lock-key", runner.DefaultLockKey, "PostgreSQL advisory lock key serializing concurrent runs")
+	migrateCmd.Flags().DurationVar(&runner.LockWait, "lock-wait", time.Minute, "How long to wait for another run to release the lock")
+	migrateCmd.Flags().DurationVar(&runner.LockTimeout, "lock-timeout", 0, "Fail a migration statement waiting longer than this for a table lock (PostgreSQL)")
+	migrateCmd.Flags().DurationVar(&runner.StatementTimeout, "statement-timeout", 0, "Fail a migration statement running longer than this (PostgreSQL)")
+	migrateCmd.Flags().StringVar(&confirmEnv, "confirm", "", "Confirm destructive migrations on a protected environment by naming it")
+	migrateCmd.Flags().IntVar(&runner.Retries, "retries", 3, "Retry a transactional migration failing with a lock timeout, serialization failure or deadlock")
+	addTargetFlags(migrateCmd)
 }
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ridoystarlord/migrato/generator"
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/spf13/cobra"
+)
+
+var newNoTransaction bool
+
+// migrationNameChars are replaced in the names of new migrations
+var migrationNameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+var newCmd = &cobra.Command{
+	Use:   "new <name>",
+	Short: "Create an empty migration to write by hand",
+	Long: `Create a timestamped migration file with empty up and down sections, for
+changes generate cannot derive from the schema such as data fixes, views or
+functions. The name is lower-cased and spaces become underscores.
+
+Like generate, new refuses to write while the migrations differ from
+migrations.sum and rewrites the file afterwards, so the new migration is
+recorded in it. After filling in the SQL, run 'migrato sum' to record the
+edit.
+
+Examples:
+  migrato new backfill_user_emails
+  migrato new "add search index" --no-transaction
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := strings.Trim(migrationNameChars.ReplaceAllString(strings.ToLower(args[0]), "_"), "_")
+		if name == "" {
+			fmt.Printf("❌ %q is not a usable migration name; use letters, digits and underscores\n", args[0])
+			os.Exit(1)
+		}
+
+		var directives []string
+		if newNoTransaction {
+			directives = append(directives, "no-transaction")
+		}
+		version, err := newVersion()
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		filename, err := generator.WriteMigration(generator.MigrationFile{
+			Version:     version,
+			Name:        name,
+			Description: "Written by hand",
+			Directives:  directives,
+			Up:          []string{"-- Write the up SQL here"},
+			Down:        []string{"-- Write the down SQL that undoes it here"},
+		})
+		if err != nil {
+			printSumError(err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Created %s\n", filename)
+		fmt.Println("💡 Fill in the SQL, then run 'migrato sum' to record the edit.")
+	},
+}
+
+// newVersion returns the current timestamp, moved past the versions already
+// in the migrations directory so that two quick runs do not share one
+func newVersion() (string, error) {
+	taken := map[string]bool{}
+	entries, err := os.ReadDir(runner.MigrationsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("read migrations dir: %v", err)
+	}
+	for _, entry := range entries {
+		if i := strings.Index(entry.Name(), "_"); i > 0 {
+			taken[entry.Name()[:i]] = true
+		}
+	}
+
+	t := time.Now()
+	for taken[t.Format(runner.VersionLayout)] {
+		t = t.Add(time.Second)
+	}
+	return t.Format(runner.VersionLayout), nil
+}
+
+func init() {
+	newCmd.Flags().BoolVar(&newNoTransaction, "no-transaction", false, "Run the migration outside a transaction, e.g. for CREATE INDEX CONCURRENTLY")
+}
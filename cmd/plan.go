@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/spf13/cobra"
+)
+
+var planOutput string
+var planTarget string
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Plan pending migrations for review",
+	Long: `Record the pending migrations for a reviewed-then-executed deploy.
+
+The plan lists the pending migration files with their checksums, the
+migrations already applied and a fingerprint of the database schema.
+'migrato apply <plan>' runs exactly these migrations and refuses to start if
+any of it changed in between.
+
+Examples:
+  migrato plan                           # Show what would be applied
+  migrato plan -o plan.json              # Save the plan for review
+  migrato plan --to 20240601093000 -o plan.json
+  migrato apply plan.json                # Apply the reviewed plan
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		verifySum()
+
+		plan, err := runner.CreatePlan(cmd.Context(), planTarget)
+		if err != nil {
+			fmt.Println("❌ Planning failed:", err)
+			os.Exit(1)
+		}
+
+		if len(plan.Pending) == 0 {
+			fmt.Println("✅ No pending migrations.")
+		} else {
+			fmt.Printf("📋 %d migration(s) to apply:\n", len(plan.Pending))
+			for _, m := range plan.Pending {
+				fmt.Printf("   - %s\n", m.Filename)
+			}
+		}
+		fmt.Printf("🔑 Schema fingerprint: %s\n", plan.SchemaFingerprint)
+
+		if planOutput == "" {
+			return
+		}
+		if err := runner.WritePlan(plan, planOutput); err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		fmt.Printf("💾 Plan saved to %s. Review it, then run 'migrato apply %s'.\n", planOutput, planOutput)
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <plan>",
+	Short: "Apply a saved migration plan",
+	Long: `Apply the migrations of a plan saved by 'migrato plan -o'.
+
+Under the migration lock, apply first checks that the applied migrations,
+the pending files and their checksums, and the schema fingerprint are
+exactly as planned. Anything that changed since the plan was created
+refuses the run; create and review a new plan instead.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		verifySum()
+
+		plan, err := runner.ReadPlan(args[0])
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+
+		confirmDestructive()
+		if err := runner.ApplyPlan(cmd.Context(), plan); err != nil {
+			fmt.Println("❌ Apply failed:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	planCmd.Flags().StringVarP(&planOutput, "output", "o", "", "File to save the plan to")
+	planCmd.Flags().StringVar(&planTarget, "to", "", "Plan the pending migrations up to and including this version")
+	applyCmd.Flags().StringVar(&confirmEnv, "confirm", "", "Confirm destructive migrations on a protected environment by naming it")
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/ridoystarlord/migrato/runner"
+)
+
+// confirmEnv is --confirm=<env>, confirming a protected environment without a prompt
+var confirmEnv string
+
+// protectedEnv returns the name of the selected environment when it is protected
+func protectedEnv() (string, bool) {
+	if !projectConfig.Safety.Protected {
+		return "", false
+	}
+	if projectConfig.Env == "" {
+		return "default", true
+	}
+	return projectConfig.Env, true
+}
+
+// git runs a git command in the working directory and returns its trimmed output
+func git(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// checkReleaseState refuses to touch a protected environment from a dirty
+// git tree or from a branch that is not a release branch. A detached HEAD
+// passes when it is exactly at a tag. It returns where the run comes from.
+func checkReleaseState(env string) (string, error) {
+	if _, err := git("rev-parse", "--is-inside-work-tree"); err != nil {
+		return "", fmt.Errorf("%s is protected and the git state cannot be checked outside a git work tree", env)
+	}
+
+	status, err := git("status", "--porcelain")
+	if err != nil {
+		return "", fmt.Errorf("git status: %v", err)
+	}
+	if status != "" {
+		return "", fmt.Errorf("%s is protected; commit or stash your changes first, the git tree is dirty", env)
+	}
+
+	commit, err := git("rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse: %v", err)
+	}
+	branch, err := git("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse: %v", err)
+	}
+	if branch == "HEAD" {
+		tag, err := git("describe", "--tags", "--exact-match")
+		if err != nil {
+			return "", fmt.Errorf("%s is protected; HEAD is detached and not at a release tag", env)
+		}
+		return fmt.Sprintf("tag %s at %s", tag, commit), nil
+	}
+	for _, pattern := range projectConfig.Safety.ReleaseBranches {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return fmt.Sprintf("branch %s at %s", branch, commit), nil
+		}
+	}
+	return "", fmt.Errorf("%s is protected; %s is not a release branch (%s)", env, branch, strings.Join(projectConfig.Safety.ReleaseBranches, ", "))
+}
+
+// protectedConfirmation guards an action against a protected environment.
+// It checks the git state, then asks for the environment name unless
+// --confirm gives it. It returns nil for unprotected environments.
+func protectedConfirmation(action string) (*runner.Confirmation, error) {
+	env, ok := protectedEnv()
+	if !ok {
+		return nil, nil
+	}
+
+	source, err := checkReleaseState(env)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmation := confirmEnv
+	if confirmation == "" {
+		if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return nil, fmt.Errorf("%s is protected; pass --confirm=%s to %s", env, env, action)
+		}
+		fmt.Printf("⚠️  About to %s on the protected environment %s.\n", action, env)
+		fmt.Printf("   Type %s to continue: ", env)
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		confirmation = strings.TrimSpace(line)
+	}
+	if confirmation != env {
+		return nil, fmt.Errorf("confirmation %q does not match the environment %s", confirmation, env)
+	}
+	return &runner.Confirmation{Env: env, Action: action, Details: "Run from " + source}, nil
+}
+
+// confirmProtected guards an action against a protected environment and
+// records the confirmation in migration_logs. Unprotected environments pass
+// without a word. Fan-out runs use protectedConfirmation instead, so each
+// target logs the confirmation itself.
+func confirmProtected(ctx context.Context, action string) error {
+	confirmation, err := protectedConfirmation(action)
+	if err != nil || confirmation == nil {
+		return err
+	}
+	return runner.RecordConfirmation(ctx, confirmation.Env, confirmation.Action, confirmation.Details)
+}
+
+// confirmDestructive has migrate ask for a confirmation when the pending
+// migrations contain destructive statements and the environment is
+// protected. The runner asks under the migration lock, once the pending
+// migrations are settled. Fan-out runs ask once, on the first target with
+// destructive statements; the others reuse the answer.
+func confirmDestructive() {
+	if _, protected := protectedEnv(); !protected {
+		return
+	}
+
+	var mu sync.Mutex
+	var asked bool
+	var confirmation *runner.Confirmation
+	var err error
+	runner.ConfirmDestructive = func(destructive []runner.DestructiveStatement) (*runner.Confirmation, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !asked {
+			asked = true
+			fmt.Println("⚠️  Pending migrations contain destructive statements:")
+			for _, stmt := range destructive {
+				fmt.Printf("   - %s\n", stmt)
+			}
+			confirmation, err = protectedConfirmation(fmt.Sprintf("apply %d destructive statement(s)", len(destructive)))
+		}
+		return confirmation, err
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/spf13/cobra"
+)
+
+var dryRunRebase bool
+
+var rebaseCmd = &cobra.Command{
+	Use:   "rebase",
+	Short: "Renumber pending migrations that sort before applied ones",
+	Long: `Rename pending migrations whose version sorts before the latest applied migration.
+
+When two branches both add migrations, the merged files can end up in the
+wrong relative order: an unapplied migration from one branch sorts before
+one already applied from the other. migrate refuses to apply it unless
+--allow-out-of-order is given. rebase instead gives such migrations new
+versions after the latest applied one, keeping their relative order, and
+updates the "-- Migration:" header.
+
+Only rebase migrations that have not been deployed to another environment.
+
+Examples:
+  migrato rebase            # Renumber out-of-order migrations
+  migrato rebase --dry-run  # Show the renames only
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runner.RebaseMigrations(cmd.Context(), dryRunRebase); err != nil {
+			fmt.Println("❌ Rebase failed:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rebaseCmd.Flags().BoolVar(&dryRunRebase, "dry-run", false, "Show the renames without changing files")
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/spf13/cobra"
+)
+
+var redoSteps int
+
+var redoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: "Rollback and re-apply the last migrations",
+	Long: `Roll back the last migrations and apply them again, exercising their down
+SQL against the real database.
+
+Examples:
+  migrato redo              # Redo the last migration
+  migrato redo --steps 3    # Redo the last 3 migrations
+
+On a protected environment a redo needs --confirm=<env> or the environment
+name typed at the prompt.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if redoSteps < 1 {
+			fmt.Println("❌ Steps must be at least 1")
+			os.Exit(1)
+		}
+
+		if err := confirmProtected(cmd.Context(), fmt.Sprintf("redo %d migration(s)", redoSteps)); err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+
+		if err := runner.RedoMigrations(cmd.Context(), redoSteps); err != nil {
+			fmt.Println("❌ Redo failed:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that every migration rolls back cleanly",
+	Long: `Replay every migration into a scratch schema as up, down and up again.
+
+After each step the scratch schema is introspected. verify fails when the
+down SQL does not restore exactly the schema the migration started from, or
+when applying the migration again gives a different schema. The real tables
+are never touched: PostgreSQL uses a temporary schema, SQLite an in-memory
+database.
+
+Examples:
+  migrato verify            # Verify all migrations, e.g. in CI
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runner.VerifyMigrations(cmd.Context()); err != nil {
+			fmt.Println("❌ Verification failed:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	redoCmd.Flags().IntVarP(&redoSteps, "steps", "s", 1, "Number of migrations to redo")
+	redoCmd.Flags().StringVar(&confirmEnv, "confirm", "", "Confirm a redo on a protected environment by naming it")
+}
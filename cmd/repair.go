@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/spf13/cobra"
+)
+
+var dropMissingRepair bool
+var dryRunRepair bool
+
+var repairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Accept intentional edits to applied migrations",
+	Long: `Re-record the checksums of applied migrations whose files were edited on purpose.
+
+migrate and status compare each applied migration with its file and refuse to
+continue when a file was changed, removed or renamed. repair accepts the files
+as they are now:
+- changed files get their current checksum recorded
+- renamed files get their new name recorded
+- rows of missing files are deleted with --drop-missing, otherwise kept
+
+repair only updates schema_migrations; it never runs SQL from the files.
+
+Examples:
+  migrato repair                 # Accept changed and renamed files
+  migrato repair --dry-run       # Show what would be repaired
+  migrato repair --drop-missing  # Also forget migrations whose file is gone
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runner.RepairChecksums(cmd.Context(), dropMissingRepair, dryRunRepair); err != nil {
+			fmt.Println("❌ Repair failed:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	repairCmd.Flags().BoolVar(&dropMissingRepair, "drop-missing", false, "Delete records of applied migrations whose file no longer exists")
+	repairCmd.Flags().BoolVar(&dryRunRepair, "dry-run", false, "Show what would be repaired without changing anything")
+}
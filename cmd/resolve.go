@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/spf13/cobra"
+)
+
+var resolveApplied bool
+var resolveRolledBack bool
+var resolveRetry bool
+var resolveRunDown bool
+
+var resolveCmd = &cobra.Command{
+	Use:   "resolve <migration>",
+	Short: "Resolve a failed migration",
+	Long: `Settle a failed migration so that migrate can continue.
+
+A migration that fails outside a transaction is recorded as failed and blocks
+every later run. After inspecting the database, resolve it one of three ways:
+  --applied      The changes were completed by hand; record it as applied
+  --rolled-back  The changes were undone; leave it pending
+  --retry        Clear the failure and apply the migration again
+
+With --run-down the migration's down SQL runs first, to clean up a partial
+apply before --rolled-back or --retry. The failure stays in the migration
+log, and the resolution is logged with your user name.
+
+On a protected environment --retry and --run-down need --confirm=<env> or
+the environment name typed at the prompt.
+
+Examples:
+  migrato resolve 20240601093000 --applied
+  migrato resolve 20240601093000_migration.sql --retry --run-down
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var resolutions []runner.Resolution
+		if resolveApplied {
+			resolutions = append(resolutions, runner.ResolveApplied)
+		}
+		if resolveRolledBack {
+			resolutions = append(resolutions, runner.ResolveRolledBack)
+		}
+		if resolveRetry {
+			resolutions = append(resolutions, runner.ResolveRetry)
+		}
+		if len(resolutions) != 1 {
+			fmt.Println("❌ Specify exactly one of --applied, --rolled-back or --retry")
+			os.Exit(1)
+		}
+
+		// Running the down or up SQL changes the schema like rollback and migrate do
+		if resolveRunDown || resolveRetry {
+			var actions []string
+			if resolveRunDown {
+				actions = append(actions, "run the down SQL of "+args[0])
+			}
+			if resolveRetry {
+				actions = append(actions, "apply "+args[0]+" again")
+			}
+			if err := confirmProtected(cmd.Context(), strings.Join(actions, " and ")); err != nil {
+				fmt.Println("❌", err)
+				os.Exit(1)
+			}
+		}
+
+		if err := runner.ResolveFailedMigration(cmd.Context(), args[0], resolutions[0], resolveRunDown); err != nil {
+			fmt.Println("❌ Resolve failed:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	resolveCmd.Flags().BoolVar(&resolveApplied, "applied", false, "Record the migration as applied")
+	resolveCmd.Flags().BoolVar(&resolveRolledBack, "rolled-back", false, "Clear the failure and leave the migration pending")
+	resolveCmd.Flags().BoolVar(&resolveRetry, "retry", false, "Clear the failure and apply the migration again")
+	resolveCmd.Flags().BoolVar(&resolveRunDown, "run-down", false, "Run the down SQL first to clean up a partial apply")
+	resolveCmd.Flags().StringVar(&confirmEnv, "confirm", "", "Confirm --retry or --run-down on a protected environment by naming it")
+}
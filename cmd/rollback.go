@@ -3,15 +3,24 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/ridoystarlord/migrato/runner"
 	"github.com/spf13/cobra"
 )
 
 var steps int
+var rollbackTarget string
 
 func init() {
 	rollbackCmd.Flags().IntVarP(&steps, "steps", "s", 1, "Number of migrations to rollback")
+	rollbackCmd.Flags().StringVar(&rollbackTarget, "to", "", "Rollback every migration newer than this version (0 for all)")
+	rollbackCmd.Flags().Int64Var(&runner.LockKey, "lock-key", runner.DefaultLockKey, "PostgreSQL advisory lock key serializing concurrent runs")
+	rollbackCmd.Flags().DurationVar(&runner.LockWait, "lock-wait", time.Minute, "How long to wait for another run to release the lock")
+	rollbackCmd.Flags().DurationVar(&runner.LockTimeout, "lock-timeout", 0, "Fail a rollback statement waiting longer than this for a table lock (PostgreSQL)")
+	rollbackCmd.Flags().DurationVar(&runner.StatementTimeout, "statement-timeout", 0, "Fail a rollback statement running longer than this (PostgreSQL)")
+	rollbackCmd.Flags().StringVar(&confirmEnv, "confirm", "", "Confirm a rollback on a protected environment by naming it")
+	addTargetFlags(rollbackCmd)
 }
 
 var rollbackCmd = &cobra.Command{
@@ -23,14 +32,62 @@ Examples:
   migrato rollback          # Rollback the last migration
   migrato rollback --steps=3 # Rollback the last 3 migrations
   migrato rollback -s 5      # Rollback the last 5 migrations
+  migrato rollback --to 20240601093000  # Rollback everything after this version
+
+On a protected environment a rollback needs --confirm=<env> or the
+environment name typed at the prompt.
+
+The target flags of migrate (--schemas, --schemas-file, --schemas-query,
+--urls, --urls-file and --parallel) roll back each schema or database.
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		if steps < 1 {
+		if rollbackTarget != "" && cmd.Flags().Changed("steps") {
+			fmt.Println("❌ Use either --steps or --to, not both")
+			os.Exit(1)
+		}
+		if rollbackTarget == "" && steps < 1 {
 			fmt.Println("❌ Steps must be at least 1")
 			os.Exit(1)
 		}
 
-		err := runner.RollbackMigrations(steps)
+		targets, err := fanOutTargets(cmd.Context())
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+
+		action := fmt.Sprintf("roll back %d migration(s)", steps)
+		if rollbackTarget != "" {
+			action = fmt.Sprintf("roll back to %s", rollbackTarget)
+		}
+		if len(targets) > 0 {
+			action += fmt.Sprintf(" on %d targets", len(targets))
+			confirmation, err := protectedConfirmation(action)
+			if err != nil {
+				fmt.Println("❌", err)
+				os.Exit(1)
+			}
+			results := runner.RollbackTargets(cmd.Context(), targets, targetParallel, steps, rollbackTarget, confirmation)
+			if runner.PrintTargetSummary(results) > 0 {
+				os.Exit(1)
+			}
+			return
+		}
+
+		if err := confirmProtected(cmd.Context(), action); err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+
+		if rollbackTarget != "" {
+			if err := runner.RollbackTo(cmd.Context(), rollbackTarget); err != nil {
+				fmt.Println("❌ Rollback failed:", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		err = runner.RollbackMigrations(cmd.Context(), steps)
 		if err != nil {
 			fmt.Println("❌ Rollback failed:", err)
 			os.Exit(1)
@@ -1,15 +1,27 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
+
+	"github.com/ridoystarlord/migrato/config"
 )
 
 var Version string
 var useYAML bool
 
+var (
+	configFile string
+	configEnv  string
+	// projectConfig holds the settings of the selected environment
+	projectConfig = config.Defaults()
+)
+
 var rootCmd = &cobra.Command{
 	Use:     "migrato",
 	Short:   "A lightweight Prisma-like migration tool for Go",
@@ -22,6 +34,15 @@ Examples:
   migrato generate
   migrato migrate
 `,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(cmd); err != nil {
+			// Execute reports the error; the usage is no help here
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			return err
+		}
+		return nil
+	},
 }
 
 var versionCmd = &cobra.Command{
@@ -32,9 +53,23 @@ var versionCmd = &cobra.Command{
 	},
 }
 
-// Execute runs the CLI
+// Execute runs the CLI. SIGINT and SIGTERM cancel the command's context, which
+// stops the running statement; a second signal exits immediately.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		// Restore the default handlers so that a second signal terminates
+		signal.Stop(signals)
+		fmt.Println("\n⚠️  Interrupted, cancelling the running statement (press Ctrl-C again to quit immediately)")
+		cancel()
+	}()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Println("❌", err)
 		os.Exit(1)
 	}
@@ -43,11 +78,22 @@ func Execute() {
 // Register subcommands
 func init() {
 	rootCmd.PersistentFlags().BoolVar(&useYAML, "yaml", false, "Use YAML schema instead of Go structs")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Project configuration file (default: migrato.yaml or migrato.toml)")
+	rootCmd.PersistentFlags().StringVar(&configEnv, "env", os.Getenv("MIGRATO_ENV"), "Environment of the configuration file to use (default $MIGRATO_ENV)")
 	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(newCmd)
 	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(applyCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(repairCmd)
+	rootCmd.AddCommand(resolveCmd)
+	rootCmd.AddCommand(rebaseCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(rollbackCmd)
+	rootCmd.AddCommand(redoCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(selfUpgradeCmd)
 
 	rootCmd.AddCommand(healthCmd)
 	rootCmd.AddCommand(validateCmd)
@@ -57,6 +103,13 @@ func init() {
 	rootCmd.AddCommand(historyCmd)
 	rootCmd.AddCommand(logCmd)
 	rootCmd.AddCommand(studioCmd)
+	rootCmd.AddCommand(templatesCmd)
+	rootCmd.AddCommand(squashCmd)
+	rootCmd.AddCommand(baselineCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(sumCmd)
+	rootCmd.AddCommand(seedCmd)
 	rootCmd.AddCommand(versionCmd)
 
 	rootCmd.SetVersionTemplate("migrato version {{.Version}}\n")
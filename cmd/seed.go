@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/ridoystarlord/migrato/seed"
+	"github.com/spf13/cobra"
+)
+
+var seedDir string
+var forceSeed bool
+var dryRunSeed bool
+
+var seedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "Load seed data into the database",
+	Long: `Upsert reference data and fixtures from seed files.
+
+Each YAML, JSON or CSV file in the seeds directory fills one table. Files
+directly in seeds/ are applied in every environment; files in seeds/<env>/
+only with the global --env <env> (or MIGRATO_ENV). Rows are upserted with
+INSERT ... ON CONFLICT on the file's key columns, so seeding is repeatable.
+
+  # seeds/02_cities.yaml
+  table: cities
+  key: name
+  references:
+    country_id: countries.code   # store countries.id of the row with this code
+  rows:
+    - name: Dhaka
+      country_id: BD
+
+In CSV files the header marks key columns as "code:key" and references as
+"country_id->countries.code". Empty cells are NULL.
+
+Applied files are recorded with their checksum in schema_seeds (tables.seeds
+in migrato.yaml); unchanged files are skipped on the next run.
+
+Examples:
+  migrato seed                  # Apply the shared seeds
+  migrato seed --env dev        # Also apply seeds/dev
+  migrato seed --dry-run        # List the files that would be seeded
+  migrato seed --force          # Reapply unchanged files too
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := seed.Apply(cmd.Context(), seed.Options{
+			Dir:    seedDir,
+			Env:    configEnv,
+			Table:  runner.TrackingTables.Seeds,
+			Force:  forceSeed,
+			DryRun: dryRunSeed,
+		})
+		if err != nil {
+			fmt.Println("❌ Seeding failed:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	seedCmd.Flags().StringVar(&seedDir, "dir", "seeds", "Directory containing seed files")
+	seedCmd.Flags().BoolVar(&forceSeed, "force", false, "Reapply seed files even if unchanged")
+	seedCmd.Flags().BoolVar(&dryRunSeed, "dry-run", false, "Show the seed files that would be applied")
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/spf13/cobra"
+)
+
+var selfUpgradeDryRun bool
+
+var selfUpgradeCmd = &cobra.Command{
+	Use:   "self-upgrade",
+	Short: "Upgrade migrato's own tracking tables",
+	Long: `Upgrade the tables migrato keeps its records in (schema_migrations,
+migration_logs and schema_migration_history by default) to the layout of this
+release.
+
+The layout is versioned in schema_migrations_version. Every command that
+connects to the database upgrades it automatically, in one transaction and
+under the migration lock; self-upgrade does the same explicitly, and with
+--dry-run prints the pending upgrades and their SQL without changing anything.
+
+Examples:
+  migrato self-upgrade --dry-run   # Show what would change
+  migrato self-upgrade             # Upgrade the tracking tables
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runner.SelfUpgrade(cmd.Context(), selfUpgradeDryRun); err != nil {
+			fmt.Println("❌ Upgrade failed:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	selfUpgradeCmd.Flags().BoolVar(&selfUpgradeDryRun, "dry-run", false, "Show the pending upgrades without applying them")
+}
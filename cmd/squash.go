@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/spf13/cobra"
+)
+
+var squashUpTo string
+var dryRunSquash bool
+
+var squashCmd = &cobra.Command{
+	Use:   "squash",
+	Short: "Squash old migrations into a single baseline",
+	Long: `Replace every migration up to and including a version with one baseline migration.
+
+The migrations are replayed into a scratch schema (a temporary PostgreSQL
+schema, or an in-memory SQLite database) and the resulting tables, columns,
+indexes and foreign keys are written out as <version>_squash.sql. The squashed
+files are moved to migrations/squashed and listed in the baseline's header:
+
+  -- migrato:squashes 20240101120000_migration.sql
+
+Fresh databases run the baseline. Databases that already applied the squashed
+migrations record the baseline as applied without running it and mark the old
+entries as superseded.
+
+Examples:
+  migrato squash --up-to 20240601093000            # Squash up to this version
+  migrato squash --up-to 20240601093000 --dry-run  # Print the baseline only
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if squashUpTo == "" {
+			fmt.Println("❌ --up-to is required")
+			os.Exit(1)
+		}
+
+		if err := runner.SquashMigrations(cmd.Context(), squashUpTo, dryRunSquash); err != nil {
+			fmt.Println("❌ Squash failed:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	squashCmd.Flags().StringVar(&squashUpTo, "up-to", "", "Version (or filename) of the last migration to squash")
+	squashCmd.Flags().BoolVar(&dryRunSquash, "dry-run", false, "Print the baseline without writing or moving files")
+}
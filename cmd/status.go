@@ -8,32 +8,94 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var statusTimeline bool
+
+// statusTimelineEvents is how many of the latest events status shows without --timeline
+const statusTimelineEvents = 10
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show applied and pending migrations",
 	Run: func(cmd *cobra.Command, args []string) {
 
-		applied, pending, failed, err := runner.Status()
+		targets, err := fanOutTargets(cmd.Context())
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		if len(targets) > 0 {
+			results := runner.StatusTargets(cmd.Context(), targets, targetParallel)
+			if runner.PrintTargetSummary(results) > 0 {
+				os.Exit(1)
+			}
+			return
+		}
+
+		report, err := runner.Status(cmd.Context())
 		if err != nil {
 			fmt.Println("❌ Status error:", err)
 			os.Exit(1)
 		}
 
 		fmt.Println("✅ Applied migrations:")
-		for _, f := range applied {
+		for _, f := range report.Applied {
 			fmt.Println("   -", f)
 		}
 
-		if len(failed) > 0 {
+		if len(report.Failed) > 0 {
 			fmt.Println("\n❌ Failed migrations:")
-			for _, f := range failed {
+			for _, f := range report.Failed {
 				fmt.Printf("   - %s: %s\n", f.MigrationName, f.ErrorMessage)
 			}
 		}
 
+		// Pending migrations that were applied before say when they were rolled back
+		lastEvents := map[string]runner.MigrationRecord{}
+		for _, event := range report.History {
+			if _, ok := lastEvents[event.MigrationName]; !ok {
+				lastEvents[event.MigrationName] = event
+			}
+		}
 		fmt.Println("\n🕒 Pending migrations:")
-		for _, f := range pending {
+		for _, f := range report.Pending {
+			if event, ok := lastEvents[f]; ok && event.Direction == "down" && event.Status == "success" {
+				fmt.Printf("   - %s (rolled back %s by %s)\n", f, event.ExecutedAt.Format("2006-01-02 15:04"), event.ExecutedBy)
+				continue
+			}
 			fmt.Println("   -", f)
 		}
+
+		if len(report.OutOfOrder) > 0 {
+			fmt.Println("\n⚠️  Out of order (sort before the latest applied migration):")
+			for _, f := range report.OutOfOrder {
+				fmt.Println("   -", f)
+			}
+			fmt.Println("💡 Run 'migrato rebase' to renumber them, or migrate with --allow-out-of-order.")
+		}
+
+		if len(report.History) > 0 {
+			fmt.Println("\n📜 Timeline:")
+			events := report.History
+			if !statusTimeline && len(events) > statusTimelineEvents {
+				events = events[:statusTimelineEvents]
+			}
+			for i := len(events) - 1; i >= 0; i-- {
+				fmt.Println("   " + eventLine(events[i]))
+			}
+			if len(events) < len(report.History) {
+				fmt.Printf("   (%d earlier events; run 'migrato status --timeline' or 'migrato history' to see them)\n", len(report.History)-len(events))
+			}
+		}
+
+		if len(report.ChecksumIssues) > 0 {
+			fmt.Println()
+			runner.PrintChecksumIssues(report.ChecksumIssues)
+			os.Exit(1)
+		}
 	},
 }
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusTimeline, "timeline", false, "Show every apply and rollback event, not only the latest")
+	addTargetFlags(statusCmd)
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ridoystarlord/migrato/database"
+	"github.com/ridoystarlord/migrato/introspect"
+	"github.com/ridoystarlord/migrato/runner"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -566,9 +568,8 @@ func (s *StudioServer) handleTables(w http.ResponseWriter, r *http.Request) {
 	query := `
 		SELECT table_name 
 		FROM information_schema.tables 
-		WHERE table_schema = 'public' 
+		WHERE table_schema = current_schema() 
 		AND table_type = 'BASE TABLE'
-		AND table_name NOT IN ('schema_migrations', 'migration_logs')
 		ORDER BY table_name
 	`
 
@@ -586,6 +587,9 @@ func (s *StudioServer) handleTables(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Failed to scan table name: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if introspect.IsIgnoredTable(tableName) {
+			continue
+		}
 		tables = append(tables, tableName)
 	}
 
@@ -636,7 +640,7 @@ func (s *StudioServer) handleTableData(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
 
 	// Get columns in schema order
-	colQuery := `SELECT column_name FROM information_schema.columns WHERE table_name = $1 AND table_schema = 'public' ORDER BY ordinal_position`
+	colQuery := `SELECT column_name FROM information_schema.columns WHERE table_name = $1 AND table_schema = current_schema() ORDER BY ordinal_position`
 	colRows, err := pool.Query(ctx, colQuery, path)
 	if err != nil {
 		http.Error(w, "Failed to get column order: "+err.Error(), http.StatusInternalServerError)
@@ -670,7 +674,7 @@ func (s *StudioServer) handleTableData(w http.ResponseWriter, r *http.Request) {
 		query = "SELECT * FROM \"" + path + "\" WHERE "
 		
 		// Get column names first
-		colQuery := "SELECT column_name, data_type FROM information_schema.columns WHERE table_name = $1 AND table_schema = 'public'"
+		colQuery := "SELECT column_name, data_type FROM information_schema.columns WHERE table_name = $1 AND table_schema = current_schema()"
 		colRows, err := pool.Query(ctx, colQuery, path)
 		if err != nil {
 			http.Error(w, "Failed to get column info: "+err.Error(), http.StatusInternalServerError)
@@ -915,7 +919,7 @@ func (s *StudioServer) validateUpdateData(ctx context.Context, pool *pgxpool.Poo
 	query := `
 		SELECT column_name, data_type, is_nullable, column_default
 		FROM information_schema.columns 
-		WHERE table_name = $1 AND table_schema = 'public'
+		WHERE table_name = $1 AND table_schema = current_schema()
 		ORDER BY ordinal_position`
 
 	rows, err := pool.Query(ctx, query, tableName)
@@ -1549,7 +1553,7 @@ func (s *StudioServer) validateColumns(ctx context.Context, pool *pgxpool.Pool,
 	query := `
 		SELECT column_name 
 		FROM information_schema.columns 
-		WHERE table_name = $1 AND table_schema = 'public'
+		WHERE table_name = $1 AND table_schema = current_schema()
 		ORDER BY ordinal_position`
 
 	rows, err := pool.Query(ctx, query, tableName)
@@ -1601,7 +1605,8 @@ func (s *StudioServer) handleBulkDeleteTableData(w http.ResponseWriter, r *http.
 	}
 	// Parse JSON body
 	var req struct {
-		IDs []interface{} `json:"ids"`
+		IDs     []interface{} `json:"ids"`
+		Confirm string        `json:"confirm"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
@@ -1611,6 +1616,25 @@ func (s *StudioServer) handleBulkDeleteTableData(w http.ResponseWriter, r *http.
 		http.Error(w, "No IDs provided", http.StatusBadRequest)
 		return
 	}
+	// A protected environment needs its name typed in the browser
+	if env, protected := protectedEnv(); protected {
+		source, err := checkReleaseState(env)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if req.Confirm != env {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusPreconditionRequired)
+			json.NewEncoder(w).Encode(map[string]string{"confirm": env})
+			return
+		}
+		action := fmt.Sprintf("bulk delete of %d row(s) from %s", len(req.IDs), path)
+		if err := runner.RecordConfirmation(r.Context(), env, action, "Run from "+source+" in studio"); err != nil {
+			http.Error(w, "Failed to record confirmation: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 	// Get PK column
 	pk, err := getPrimaryKeyColumn(path)
 	if err != nil || pk == "" {
@@ -1640,12 +1664,10 @@ func (s *StudioServer) handleBulkDeleteTableData(w http.ResponseWriter, r *http.
 
 // Helper to get PK column
 func getPrimaryKeyColumn(tableName string) (string, error) {
-	dsn := os.Getenv("DATABASE_URL")
-	pool, err := pgxpool.New(context.Background(), dsn)
+	pool, err := database.GetPool()
 	if err != nil {
 		return "", err
 	}
-	defer pool.Close()
 	ctx := context.Background()
 	query := `SELECT kcu.column_name
 		FROM information_schema.table_constraints tc
@@ -1653,7 +1675,7 @@ func getPrimaryKeyColumn(tableName string) (string, error) {
 		  ON tc.constraint_name = kcu.constraint_name
 		WHERE tc.constraint_type = 'PRIMARY KEY'
 		  AND tc.table_name = $1
-		  AND tc.table_schema = 'public'
+		  AND tc.table_schema = current_schema()
 		LIMIT 1`
 	var pk string
 	err = pool.QueryRow(ctx, query, tableName).Scan(&pk)
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ridoystarlord/migrato/generator"
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/spf13/cobra"
+)
+
+var sumCheck bool
+
+var sumCmd = &cobra.Command{
+	Use:   "sum",
+	Short: "Write or check the migrations.sum integrity file",
+	Long: `Hash every migration file into migrations/migrations.sum, together with a
+hash over the whole directory.
+
+Commands writing migrations, such as generate, new, baseline, import, squash
+and rebase, keep the file up to date. migrate, plan and apply refuse to run when
+the migrations no longer match it, which catches hand edits and migrations
+merged from two branches before anything touches a database. Run 'migrato
+sum' after reviewing such changes; run 'migrato sum --check' in CI.
+
+Examples:
+  migrato sum            # Rewrite migrations.sum from the files on disk
+  migrato sum --check    # Fail when the files do not match migrations.sum
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if sumCheck {
+			if err := generator.VerifySum(runner.MigrationsDir); err != nil {
+				printSumError(err)
+				os.Exit(1)
+			}
+			fmt.Printf("✅ Migrations match %s\n", generator.SumFile)
+			return
+		}
+
+		if err := generator.WriteSum(runner.MigrationsDir); err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Wrote %s\n", generator.SumFile)
+	},
+}
+
+func init() {
+	sumCmd.Flags().BoolVar(&sumCheck, "check", false, "Verify the migrations against migrations.sum without changing it")
+}
+
+// verifySum refuses to go on when the migrations do not match
+// migrations.sum. Projects without the file only get a warning.
+func verifySum() {
+	err := generator.VerifySum(runner.MigrationsDir)
+	if errors.Is(err, generator.ErrNoSum) {
+		fmt.Println("⚠️ ", err)
+		return
+	}
+	if err != nil {
+		printSumError(err)
+		os.Exit(1)
+	}
+}
+
+// printSumError prints a sum verification failure with the differing files
+func printSumError(err error) {
+	fmt.Println("❌", err)
+	var mismatch *generator.SumError
+	if errors.As(err, &mismatch) {
+		for _, line := range mismatch.Details() {
+			fmt.Println("   " + line)
+		}
+	}
+}
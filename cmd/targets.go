@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/spf13/cobra"
+)
+
+// Fan-out targets shared by migrate, rollback and status
+var (
+	targetSchemas      []string
+	targetSchemasFile  string
+	targetSchemasQuery string
+	targetURLs         []string
+	targetURLsFile     string
+	targetParallel     int
+)
+
+// addTargetFlags registers the flags selecting schemas or databases to fan out to
+func addTargetFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&targetSchemas, "schemas", nil, "Run on each of these PostgreSQL schemas of the database (comma-separated)")
+	cmd.Flags().StringVar(&targetSchemasFile, "schemas-file", "", "Run on each schema listed in this file, one per line (- for stdin)")
+	cmd.Flags().StringVar(&targetSchemasQuery, "schemas-query", "", "Run on each schema returned by this query on the database")
+	cmd.Flags().StringSliceVar(&targetURLs, "urls", nil, "Run on each of these database URLs (comma-separated)")
+	cmd.Flags().StringVar(&targetURLsFile, "urls-file", "", "Run on each database URL listed in this file, one per line (- for stdin)")
+	cmd.Flags().IntVar(&targetParallel, "parallel", 4, "How many targets to run at a time")
+}
+
+// fanOutTargets resolves the targets selected by the flags. It returns no
+// targets and no error when none of the flags is set.
+func fanOutTargets(ctx context.Context) ([]runner.Target, error) {
+	schemas := append([]string{}, targetSchemas...)
+	if targetSchemasFile != "" {
+		list, err := runner.ReadTargetList(targetSchemasFile)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, list...)
+	}
+	if targetSchemasQuery != "" {
+		list, err := runner.QueryTargetList(ctx, targetSchemasQuery)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, list...)
+	}
+
+	urls := append([]string{}, targetURLs...)
+	if targetURLsFile != "" {
+		list, err := runner.ReadTargetList(targetURLsFile)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, list...)
+	}
+
+	selected := len(targetSchemas) > 0 || targetSchemasFile != "" || targetSchemasQuery != "" ||
+		len(targetURLs) > 0 || targetURLsFile != ""
+	targets, err := runner.SchemaTargets(schemas)
+	if err != nil {
+		return nil, err
+	}
+	targets = append(targets, runner.URLTargets(urls)...)
+	if selected && len(targets) == 0 {
+		return nil, fmt.Errorf("the target flags select no schemas or databases")
+	}
+	if targetParallel < 1 {
+		return nil, fmt.Errorf("--parallel must be at least 1")
+	}
+	return targets, nil
+}
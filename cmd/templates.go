@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ridoystarlord/migrato/generator"
+	"github.com/spf13/cobra"
+)
+
+var templatesOutputDir string
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "Write the default SQL templates for customization",
+	Long: `Write the built-in SQL templates to a project directory so they can be customized.
+
+Every operation (create_table, add_column, create_index, ...) is rendered
+through a text/template file. Files in the templates directory override the
+embedded defaults; a file under <dir>/<dialect>/ applies to one dialect only.
+Existing files are never overwritten.
+
+Examples:
+  migrato templates                 # Write defaults to templates/
+  migrato templates -o sql/         # Write defaults to sql/
+  migrato generate --templates sql/ # Generate using the customized templates
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		written, err := generator.WriteDefaultTemplates(templatesOutputDir)
+		if err != nil {
+			fmt.Println("❌ Writing templates:", err)
+			os.Exit(1)
+		}
+
+		if len(written) == 0 {
+			fmt.Printf("✅ All templates already exist in %s\n", templatesOutputDir)
+			return
+		}
+		for _, path := range written {
+			fmt.Println("📝", path)
+		}
+		fmt.Printf("✅ Wrote %d template(s) to %s\n", len(written), templatesOutputDir)
+	},
+}
+
+func init() {
+	templatesCmd.Flags().StringVarP(&templatesOutputDir, "output", "o", "templates", "Directory to write the templates to")
+}
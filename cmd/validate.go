@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -46,7 +47,7 @@ Examples:
   DATABASE_URL=postgres://... migrato validate  # Online validation
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := validateSchema(); err != nil {
+		if err := validateSchema(cmd.Context()); err != nil {
 			fmt.Printf("❌ Schema validation failed: %v\n", err)
 			os.Exit(1)
 		}
@@ -63,7 +64,7 @@ func init() {
 	validateCmd.Flags().StringVarP(&validateFormat, "format", "f", "text", "Output format (text, json)")
 }
 
-func validateSchema() error {
+func validateSchema(ctx context.Context) error {
 	// Load schema
 	var models []schema.Model
 	var err error
@@ -74,7 +75,7 @@ func validateSchema() error {
 			return fmt.Errorf("failed to load YAML schema: %v", err)
 		}
 	} else {
-		models, err = loader.LoadModelsFromTags("models")
+		models, err = loader.LoadModelsFromTags(projectConfig.ModelsDir)
 		if err != nil {
 			return fmt.Errorf("failed to load Go structs: %v", err)
 		}
@@ -82,6 +83,9 @@ func validateSchema() error {
 
 	// Check for DATABASE_URL in environment
 	dbURL := os.Getenv("DATABASE_URL")
+	if projectConfig.URL != "" {
+		dbURL = projectConfig.URL
+	}
 	if dbURL == "" {
 		fmt.Println("[DEBUG] DATABASE_URL not set, using offline schema validation.")
 		return validateSchemaOffline(models)
@@ -94,7 +98,7 @@ func validateSchema() error {
 	}
 
 	// Validate schema with database
-	result, err := dbValidator.ValidateSchema(models)
+	result, err := dbValidator.ValidateSchema(ctx, models)
 	if err != nil {
 		return fmt.Errorf("failed to validate schema: %v", err)
 	}
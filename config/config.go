@@ -0,0 +1,192 @@
+// Package config loads the project configuration file, migrato.yaml or
+// migrato.toml. Settings at the top level apply to every environment; each
+// entry under environments overrides them for the environment selected with
+// --env.
+//
+//	migrations_dir: db/migrations
+//	ignore: ["tmp_*"]
+//	environments:
+//	  production:
+//	    url: ${PRODUCTION_DATABASE_URL}
+//	    schemas: [app]
+//	    safety:
+//	      lock_timeout: 5s
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Config holds the settings of one environment
+type Config struct {
+	// URL is the database URL; ${VAR} references are expanded once the env
+	// file is loaded. DATABASE_URL is used when it is empty.
+	URL           string   `mapstructure:"url"`
+	EnvFile       string   `mapstructure:"env_file"`
+	Schemas       []string `mapstructure:"schemas"` // PostgreSQL search_path; the first is introspected
+	MigrationsDir string   `mapstructure:"migrations_dir"`
+	ModelsDir     string   `mapstructure:"models_dir"`
+	SchemaFile    string   `mapstructure:"schema_file"`
+	SeedsDir      string   `mapstructure:"seeds_dir"`
+	TemplatesDir  string   `mapstructure:"templates_dir"`
+	Ignore        []string `mapstructure:"ignore"` // glob patterns of tables migrato leaves alone
+	Tables        Tables   `mapstructure:"tables"`
+	Safety        Safety   `mapstructure:"safety"`
+	Studio        Studio   `mapstructure:"studio"`
+
+	// Env is the selected environment, empty when none
+	Env string `mapstructure:"-"`
+	// File is the configuration file read, empty when there is none
+	File string `mapstructure:"-"`
+}
+
+// Tables names the tracking tables; empty names keep migrato's defaults
+type Tables struct {
+	Migrations string `mapstructure:"migrations"`
+	Logs       string `mapstructure:"logs"`
+	History    string `mapstructure:"history"`
+	Seeds      string `mapstructure:"seeds"`
+}
+
+// Safety holds the settings guarding migration runs
+type Safety struct {
+	LockKey          int64         `mapstructure:"lock_key"` // zero keeps the default key
+	LockWait         time.Duration `mapstructure:"lock_wait"`
+	LockTimeout      time.Duration `mapstructure:"lock_timeout"`
+	StatementTimeout time.Duration `mapstructure:"statement_timeout"`
+	Retries          int           `mapstructure:"retries"`
+	AllowOutOfOrder  bool          `mapstructure:"allow_out_of_order"`
+	// Protected environments require a confirmation, a clean git tree and a
+	// release branch for rollbacks, destructive migrations and bulk deletes
+	Protected       bool     `mapstructure:"protected"`
+	ReleaseBranches []string `mapstructure:"release_branches"` // glob patterns
+}
+
+// Studio holds the settings of migrato studio
+type Studio struct {
+	Port int `mapstructure:"port"`
+}
+
+// Defaults returns the settings used when nothing is configured
+func Defaults() Config {
+	return Config{
+		EnvFile:       ".env",
+		MigrationsDir: "migrations",
+		ModelsDir:     "models",
+		SchemaFile:    "schema.yaml",
+		SeedsDir:      "seeds",
+		TemplatesDir:  "templates",
+		Safety: Safety{
+			LockWait:        time.Minute,
+			Retries:         3,
+			ReleaseBranches: []string{"main", "master", "release/*"},
+		},
+		Studio: Studio{Port: 7777},
+	}
+}
+
+// environmentsKey holds the per-environment overrides
+const environmentsKey = "environments"
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Load reads the configuration for env. An empty file searches the working
+// directory for migrato.yaml or migrato.toml and falls back to the defaults
+// when there is none.
+func Load(file, env string) (Config, error) {
+	v := viper.New()
+	if file != "" {
+		v.SetConfigFile(file)
+	} else {
+		v.SetConfigName("migrato")
+		v.AddConfigPath(".")
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		if _, notFound := err.(viper.ConfigFileNotFoundError); file != "" || !notFound {
+			return Config{}, fmt.Errorf("read config: %v", err)
+		}
+	}
+
+	settings, err := resolve(v, env)
+	if err != nil {
+		return Config{}, err
+	}
+
+	c := Defaults()
+	if err := settings.Unmarshal(&c); err != nil {
+		return Config{}, fmt.Errorf("parse %s: %v", v.ConfigFileUsed(), err)
+	}
+	c.Env = env
+	c.File = v.ConfigFileUsed()
+
+	if err := c.validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %v", c.File, err)
+	}
+	return c, nil
+}
+
+// resolve merges the overrides of env into the top-level settings
+func resolve(v *viper.Viper, env string) (*viper.Viper, error) {
+	top := v.AllSettings()
+	environments, _ := top[environmentsKey].(map[string]interface{})
+	delete(top, environmentsKey)
+
+	merged := viper.New()
+	if err := merged.MergeConfigMap(top); err != nil {
+		return nil, fmt.Errorf("merge config: %v", err)
+	}
+	if env == "" || len(environments) == 0 {
+		// Without environments in the file, --env only selects seeds/<env>
+		return merged, nil
+	}
+
+	overrides, ok := environments[strings.ToLower(env)].(map[string]interface{})
+	if !ok {
+		var names []string
+		for name := range environments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown environment %q in %s (available: %s)", env, v.ConfigFileUsed(), strings.Join(names, ", "))
+	}
+	if err := merged.MergeConfigMap(overrides); err != nil {
+		return nil, fmt.Errorf("merge environment %s: %v", env, err)
+	}
+	return merged, nil
+}
+
+// validate rejects settings that would be interpolated into SQL unchecked
+func (c Config) validate() error {
+	for _, name := range []string{c.Tables.Migrations, c.Tables.Logs, c.Tables.History, c.Tables.Seeds} {
+		if name != "" && !identifier.MatchString(name) {
+			return fmt.Errorf("invalid tracking table name %q", name)
+		}
+	}
+	for _, schema := range c.Schemas {
+		if !identifier.MatchString(schema) {
+			return fmt.Errorf("invalid schema name %q", schema)
+		}
+	}
+	for _, pattern := range c.Ignore {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid ignore pattern %q: %v", pattern, err)
+		}
+	}
+	for _, pattern := range c.Safety.ReleaseBranches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid release branch pattern %q: %v", pattern, err)
+		}
+	}
+	if c.Safety.Retries < 0 {
+		return fmt.Errorf("safety.retries must not be negative")
+	}
+	return nil
+}
@@ -2,12 +2,16 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"net/url"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ridoystarlord/migrato/dialect"
 	"github.com/ridoystarlord/migrato/utils"
 )
 
@@ -15,15 +19,79 @@ var (
 	pool     *pgxpool.Pool
 	poolOnce sync.Once
 	poolErr  error
+
+	db     *sql.DB
+	dbOnce sync.Once
+	dbErr  error
 )
 
+// URL is the configured database URL; ${VAR} references are expanded after
+// the env file is loaded. DATABASE_URL is used when it is empty.
+var URL string
+
+// Schemas is the PostgreSQL search_path set on every connection
+var Schemas []string
+
+// databaseURL loads the environment and returns the configured URL, or
+// DATABASE_URL
+func databaseURL() (string, error) {
+	connStr, err := configuredURL()
+	if err != nil {
+		return "", err
+	}
+	if len(Schemas) > 0 {
+		if d, err := dialect.FromURL(connStr); err == nil && d == dialect.Postgres {
+			return withSearchPath(connStr, Schemas)
+		}
+	}
+	return connStr, nil
+}
+
+// configuredURL loads the environment and returns the configured URL, or
+// DATABASE_URL, as written
+func configuredURL() (string, error) {
+	utils.LoadEnv()
+	connStr := os.Getenv("DATABASE_URL")
+	if URL != "" {
+		connStr = os.ExpandEnv(URL)
+	}
+	if connStr == "" {
+		return "", fmt.Errorf("DATABASE_URL not set in environment")
+	}
+	return connStr, nil
+}
+
+// withSearchPath adds a search_path runtime parameter to a PostgreSQL URL
+func withSearchPath(connStr string, schemas []string) (string, error) {
+	u, err := url.Parse(connStr)
+	if err != nil {
+		return "", fmt.Errorf("parse database URL: %v", err)
+	}
+	query := u.Query()
+	query.Set("search_path", strings.Join(schemas, ","))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// GetDialect returns the SQL dialect of the configured database
+func GetDialect() (dialect.Dialect, error) {
+	connStr, err := databaseURL()
+	if err != nil {
+		return nil, err
+	}
+	return dialect.FromURL(connStr)
+}
+
 // GetPool returns a singleton connection pool for the application
 func GetPool() (*pgxpool.Pool, error) {
 	poolOnce.Do(func() {
-		utils.LoadEnv()
-		connStr := os.Getenv("DATABASE_URL")
-		if connStr == "" {
-			poolErr = fmt.Errorf("DATABASE_URL not set in environment")
+		connStr, err := databaseURL()
+		if err != nil {
+			poolErr = err
+			return
+		}
+		if d, err := dialect.FromURL(connStr); err == nil && d != dialect.Postgres {
+			poolErr = fmt.Errorf("this command requires PostgreSQL, DATABASE_URL points to %s", d.Name())
 			return
 		}
 
@@ -45,6 +113,33 @@ func GetPool() (*pgxpool.Pool, error) {
 	return pool, poolErr
 }
 
+// GetDB returns a singleton database/sql handle opened with the driver
+// matching the dialect of DATABASE_URL
+func GetDB() (*sql.DB, dialect.Dialect, error) {
+	d, err := GetDialect()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dbOnce.Do(func() {
+		connStr, _ := databaseURL()
+		db, dbErr = sql.Open(d.DriverName(), d.DataSourceName(connStr))
+		if dbErr != nil {
+			dbErr = fmt.Errorf("unable to open database: %v", dbErr)
+			return
+		}
+
+		// Test the connection
+		if err := db.PingContext(context.Background()); err != nil {
+			db.Close()
+			dbErr = fmt.Errorf("unable to ping database: %v", err)
+			return
+		}
+	})
+
+	return db, d, dbErr
+}
+
 // GetConnection returns a single connection from the pool
 func GetConnection(ctx context.Context) (*pgx.Conn, error) {
 	pool, err := GetPool()
@@ -60,9 +155,53 @@ func GetConnection(ctx context.Context) (*pgx.Conn, error) {
 	return conn.Conn(), nil
 }
 
+// SchemaURL returns the configured PostgreSQL URL with schema first on the
+// search_path, followed by the configured schemas
+func SchemaURL(schema string) (string, error) {
+	connStr, err := configuredURL()
+	if err != nil {
+		return "", err
+	}
+	if d, err := dialect.FromURL(connStr); err != nil {
+		return "", err
+	} else if d != dialect.Postgres {
+		return "", fmt.Errorf("schema targets require PostgreSQL, DATABASE_URL points to %s", d.Name())
+	}
+
+	schemas := []string{schema}
+	for _, s := range Schemas {
+		if s != schema {
+			schemas = append(schemas, s)
+		}
+	}
+	return withSearchPath(connStr, schemas)
+}
+
+// Open opens and pings a database/sql handle for connStr with the driver
+// matching its dialect. Unlike GetDB the handle is not shared; the caller
+// closes it.
+func Open(ctx context.Context, connStr string) (*sql.DB, dialect.Dialect, error) {
+	d, err := dialect.FromURL(connStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	handle, err := sql.Open(d.DriverName(), d.DataSourceName(connStr))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to open database: %v", err)
+	}
+	if err := handle.PingContext(ctx); err != nil {
+		handle.Close()
+		return nil, nil, fmt.Errorf("unable to ping database: %v", err)
+	}
+	return handle, d, nil
+}
+
 // ClosePool closes the connection pool (should be called on application shutdown)
 func ClosePool() {
 	if pool != nil {
 		pool.Close()
 	}
-} 
\ No newline at end of file
+	if db != nil {
+		db.Close()
+	}
+}
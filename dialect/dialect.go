@@ -0,0 +1,93 @@
+package dialect
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Dialect describes the SQL flavour spoken by a database backend: how to
+// connect to it, how schema types map onto it, which DDL it supports and how
+// migrato's own tracking tables are laid out.
+type Dialect interface {
+	// Name returns the short dialect name ("postgres", "sqlite").
+	Name() string
+	// DriverName returns the database/sql driver used for this dialect.
+	DriverName() string
+	// DataSourceName converts a DATABASE_URL into a driver specific DSN.
+	DataSourceName(url string) string
+
+	// MapType translates a schema column type into the dialect's type.
+	MapType(dataType string) string
+	// MapDefault translates a schema default expression into the dialect's syntax.
+	MapDefault(value string) string
+
+	// TrackingTablesDDL returns the statements creating the migrations table
+	// (schema_migrations by default), the logs table (migration_logs) and the
+	// history table (schema_migration_history).
+	TrackingTablesDDL(migrationsTable, logsTable, historyTable string) []string
+	// SeedTableDDL returns the statement creating the seeds table
+	// (schema_seeds by default), which tracks applied seed files.
+	SeedTableDDL(seedsTable string) string
+	// DurationValue converts an execution time into a value for the execution_time column.
+	DurationValue(d time.Duration) interface{}
+	// DurationMillis returns an expression reading a duration column as milliseconds.
+	DurationMillis(column string) string
+
+	// SupportsAlterColumn reports whether ALTER COLUMN TYPE/NOT NULL/DEFAULT is available.
+	SupportsAlterColumn() bool
+	// SupportsConstraints reports whether constraints can be added or dropped with ALTER TABLE.
+	SupportsConstraints() bool
+	// SupportsIndexMethods reports whether CREATE INDEX accepts a USING clause.
+	SupportsIndexMethods() bool
+	// SupportsExtensions reports whether CREATE EXTENSION is available.
+	SupportsExtensions() bool
+	// SupportsAdvisoryLocks reports whether session-level advisory locks are available.
+	SupportsAdvisoryLocks() bool
+	// SupportsTimeouts reports whether lock_timeout and statement_timeout can be set.
+	SupportsTimeouts() bool
+}
+
+var (
+	// Postgres is the PostgreSQL dialect
+	Postgres Dialect = postgres{}
+	// SQLite is the SQLite dialect
+	SQLite Dialect = sqlite{}
+)
+
+var dialects = map[string]Dialect{
+	"postgres":   Postgres,
+	"postgresql": Postgres,
+	"sqlite":     SQLite,
+	"sqlite3":    SQLite,
+}
+
+// Get returns the dialect registered under name
+func Get(name string) (Dialect, error) {
+	d, ok := dialects[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported dialect '%s'", name)
+	}
+	return d, nil
+}
+
+// FromURL detects the dialect from a DATABASE_URL
+func FromURL(url string) (Dialect, error) {
+	switch {
+	case strings.HasPrefix(url, "postgres://"), strings.HasPrefix(url, "postgresql://"):
+		return Postgres, nil
+	case strings.HasPrefix(url, "sqlite://"), strings.HasPrefix(url, "sqlite3://"), strings.HasPrefix(url, "file:"):
+		return SQLite, nil
+	}
+	return nil, fmt.Errorf("cannot detect database dialect from URL (expected postgres://, sqlite:// or file:)")
+}
+
+// QuoteIdent quotes a table or column name; both dialects use double quotes
+func QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// IsFunctionCall reports whether a default value is a function call like now()
+func IsFunctionCall(value string) bool {
+	return strings.Contains(value, "(") && strings.Contains(value, ")")
+}
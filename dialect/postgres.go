@@ -0,0 +1,84 @@
+package dialect
+
+import (
+	"fmt"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+type postgres struct{}
+
+func (postgres) Name() string       { return "postgres" }
+func (postgres) DriverName() string { return "pgx" }
+
+func (postgres) DataSourceName(url string) string { return url }
+
+func (postgres) MapType(dataType string) string { return dataType }
+func (postgres) MapDefault(value string) string { return value }
+
+func (postgres) TrackingTablesDDL(migrationsTable, logsTable, historyTable string) []string {
+	return []string{fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		id SERIAL PRIMARY KEY,
+		filename TEXT NOT NULL UNIQUE,
+		applied_at TIMESTAMP DEFAULT now(),
+		execution_time INTERVAL,
+		executed_by TEXT,
+		status TEXT DEFAULT 'success',
+		error_message TEXT,
+		checksum TEXT,
+		table_affected TEXT
+	);
+	`, migrationsTable), fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		id SERIAL PRIMARY KEY,
+		timestamp TIMESTAMP DEFAULT now(),
+		level TEXT NOT NULL,
+		message TEXT NOT NULL,
+		user_name TEXT,
+		details TEXT,
+		migration_name TEXT
+	);
+	`, logsTable), fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		id SERIAL PRIMARY KEY,
+		filename TEXT NOT NULL,
+		direction TEXT NOT NULL,
+		status TEXT NOT NULL,
+		executed_at TIMESTAMP DEFAULT now(),
+		execution_time INTERVAL,
+		executed_by TEXT,
+		checksum TEXT,
+		error_message TEXT,
+		table_affected TEXT
+	);
+	`, historyTable)}
+}
+
+func (postgres) SeedTableDDL(seedsTable string) string {
+	return fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		id SERIAL PRIMARY KEY,
+		filename TEXT NOT NULL UNIQUE,
+		environment TEXT,
+		checksum TEXT NOT NULL,
+		row_count INTEGER,
+		applied_at TIMESTAMP DEFAULT now(),
+		applied_by TEXT
+	);
+	`, seedsTable)
+}
+
+func (postgres) DurationValue(d time.Duration) interface{} { return d }
+
+func (postgres) DurationMillis(column string) string {
+	return fmt.Sprintf("COALESCE(CAST(EXTRACT(EPOCH FROM %s) * 1000 AS BIGINT), 0)", column)
+}
+
+func (postgres) SupportsAlterColumn() bool   { return true }
+func (postgres) SupportsConstraints() bool   { return true }
+func (postgres) SupportsIndexMethods() bool  { return true }
+func (postgres) SupportsExtensions() bool    { return true }
+func (postgres) SupportsAdvisoryLocks() bool { return true }
+func (postgres) SupportsTimeouts() bool      { return true }
@@ -0,0 +1,140 @@
+package dialect
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+type sqlite struct{}
+
+func (sqlite) Name() string       { return "sqlite" }
+func (sqlite) DriverName() string { return "sqlite" }
+
+// DataSourceName accepts sqlite://path, sqlite3://path and file: URLs and
+// enables foreign key enforcement, which SQLite leaves off by default.
+func (sqlite) DataSourceName(url string) string {
+	dsn := url
+	for _, prefix := range []string{"sqlite://", "sqlite3://"} {
+		if strings.HasPrefix(dsn, prefix) {
+			dsn = "file:" + strings.TrimPrefix(dsn, prefix)
+			break
+		}
+	}
+	if !strings.Contains(dsn, "foreign_keys") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + "_pragma=foreign_keys(1)"
+	}
+	return dsn
+}
+
+// MapType maps PostgreSQL flavoured schema types onto SQLite's type affinities.
+// Anything SQLite already accepts as a declared type is passed through.
+func (sqlite) MapType(dataType string) string {
+	lower := strings.ToLower(strings.TrimSpace(dataType))
+	switch lower {
+	case "serial", "bigserial", "smallserial":
+		return "integer"
+	case "uuid", "json", "jsonb", "inet", "cidr", "macaddr", "tsvector":
+		return "text"
+	case "timestamptz", "timestamp with time zone":
+		return "timestamp"
+	case "bytea":
+		return "blob"
+	}
+	if strings.HasSuffix(lower, "[]") {
+		return "text"
+	}
+	return dataType
+}
+
+// MapDefault rewrites PostgreSQL default functions; SQLite requires any other
+// expression default to be parenthesized.
+func (sqlite) MapDefault(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "now()", "current_timestamp", "current_timestamp()":
+		return "CURRENT_TIMESTAMP"
+	case "current_date":
+		return "CURRENT_DATE"
+	case "current_time":
+		return "CURRENT_TIME"
+	case "uuid_generate_v4()", "gen_random_uuid()":
+		return "(lower(hex(randomblob(16))))"
+	}
+	if IsFunctionCall(value) && !strings.HasPrefix(value, "(") {
+		return "(" + value + ")"
+	}
+	return value
+}
+
+func (sqlite) TrackingTablesDDL(migrationsTable, logsTable, historyTable string) []string {
+	return []string{fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		filename TEXT NOT NULL UNIQUE,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		execution_time INTEGER,
+		executed_by TEXT,
+		status TEXT DEFAULT 'success',
+		error_message TEXT,
+		checksum TEXT,
+		table_affected TEXT
+	);
+	`, migrationsTable), fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		level TEXT NOT NULL,
+		message TEXT NOT NULL,
+		user_name TEXT,
+		details TEXT,
+		migration_name TEXT
+	);
+	`, logsTable), fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		filename TEXT NOT NULL,
+		direction TEXT NOT NULL,
+		status TEXT NOT NULL,
+		executed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		execution_time INTEGER,
+		executed_by TEXT,
+		checksum TEXT,
+		error_message TEXT,
+		table_affected TEXT
+	);
+	`, historyTable)}
+}
+
+func (sqlite) SeedTableDDL(seedsTable string) string {
+	return fmt.Sprintf(`
+	CREATE TABLE IF NOT EXISTS %s (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		filename TEXT NOT NULL UNIQUE,
+		environment TEXT,
+		checksum TEXT NOT NULL,
+		row_count INTEGER,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		applied_by TEXT
+	);
+	`, seedsTable)
+}
+
+// DurationValue stores execution times as milliseconds
+func (sqlite) DurationValue(d time.Duration) interface{} { return d.Milliseconds() }
+
+func (sqlite) DurationMillis(column string) string {
+	return fmt.Sprintf("COALESCE(%s, 0)", column)
+}
+
+func (sqlite) SupportsAlterColumn() bool   { return false }
+func (sqlite) SupportsConstraints() bool   { return false }
+func (sqlite) SupportsIndexMethods() bool  { return false }
+func (sqlite) SupportsExtensions() bool    { return false }
+func (sqlite) SupportsAdvisoryLocks() bool { return false }
+func (sqlite) SupportsTimeouts() bool      { return false }
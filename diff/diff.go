@@ -30,7 +30,7 @@ type Operation struct {
 	ColumnName   string          // for DROP_COLUMN, ADD_FOREIGN_KEY
 	NewColumnName string         // for RENAME_COLUMN
 	ForeignKey   *schema.ForeignKey // for ADD_FOREIGN_KEY
-	FKName       string          // for DROP_FOREIGN_KEY
+	FKName       string          // for DROP_FOREIGN_KEY, optional name for ADD_FOREIGN_KEY
 	Index        *schema.Index   // for CREATE_INDEX
 	IndexName    string          // for DROP_INDEX
 	// For MODIFY_COLUMN operations
@@ -46,7 +46,7 @@ func DiffSchemas(models []schema.Model, existing []introspect.ExistingTable) []O
 	
 	for _, t := range existing {
 		// Skip system tables
-		if t.TableName == "schema_migrations" || t.TableName == "migration_logs" {
+		if introspect.IsIgnoredTable(t.TableName) {
 			continue
 		}
 		existingTableMap[t.TableName] = t
@@ -249,7 +249,7 @@ func DiffSchemas(models []schema.Model, existing []introspect.ExistingTable) []O
 	// Check for tables to drop (in existing but not in model) - DESTRUCTIVE
 	for _, table := range existing {
 		// Skip system tables
-		if table.TableName == "schema_migrations" || table.TableName == "migration_logs" {
+		if introspect.IsIgnoredTable(table.TableName) {
 			continue
 		}
 		if _, exists := modelTableMap[table.TableName]; !exists {
@@ -526,3 +526,84 @@ func normalizeForeignKeyAction(action string) string {
 	
 	return action
 }
+
+// CreateOperations returns the operations that build the given models from an
+// empty database: every table, referenced tables first, then the indexes, then,
+// when separateForeignKeys is set, the foreign keys as constraints of their own
+// so that tables may reference each other regardless of order.
+func CreateOperations(models []schema.Model, separateForeignKeys bool) []Operation {
+	models = orderByDependencies(models)
+
+	var ops []Operation
+	for _, model := range models {
+		ops = append(ops, Operation{
+			Type:      CreateTable,
+			TableName: model.TableName,
+			Columns:   model.Columns,
+		})
+	}
+
+	for _, model := range models {
+		for i := range model.Indexes {
+			ops = append(ops, Operation{
+				Type:      CreateIndex,
+				TableName: model.TableName,
+				Index:     &model.Indexes[i],
+			})
+		}
+	}
+
+	if !separateForeignKeys {
+		return ops
+	}
+
+	for _, model := range models {
+		for _, col := range model.Columns {
+			if col.ForeignKey == nil {
+				continue
+			}
+			ops = append(ops, Operation{
+				Type:       AddForeignKey,
+				TableName:  model.TableName,
+				ColumnName: col.Name,
+				ForeignKey: col.ForeignKey,
+				FKName:     "fk_" + model.TableName + "_" + col.Name,
+			})
+		}
+	}
+
+	return ops
+}
+
+// orderByDependencies sorts models so that a table comes after the tables its
+// foreign keys reference. Tables in a reference cycle keep their original order.
+func orderByDependencies(models []schema.Model) []schema.Model {
+	byName := map[string]schema.Model{}
+	for _, m := range models {
+		byName[m.TableName] = m
+	}
+
+	var ordered []schema.Model
+	visited := map[string]bool{}
+	var visit func(m schema.Model)
+	visit = func(m schema.Model) {
+		if visited[m.TableName] {
+			return
+		}
+		visited[m.TableName] = true
+		for _, col := range m.Columns {
+			if col.ForeignKey == nil {
+				continue
+			}
+			if ref, ok := byName[col.ForeignKey.ReferencesTable]; ok {
+				visit(ref)
+			}
+		}
+		ordered = append(ordered, m)
+	}
+
+	for _, m := range models {
+		visit(m)
+	}
+	return ordered
+}
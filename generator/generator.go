@@ -3,10 +3,13 @@ package generator
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/ridoystarlord/migrato/diff"
+	"github.com/ridoystarlord/migrato/dialect"
+	"github.com/ridoystarlord/migrato/schema"
 )
 
 // formatDefaultValue properly formats a default value for SQL
@@ -35,8 +38,23 @@ func formatDefaultValue(defaultVal string) string {
 	return fmt.Sprintf("'%s'", strings.ReplaceAll(defaultVal, "'", "''"))
 }
 
-// GenerateSQL converts a list of Operations into raw SQL statements.
-func GenerateSQL(ops []diff.Operation) ([]string, error) {
+// formatDefault formats a default value for SQL in the syntax of the given dialect
+func formatDefault(d dialect.Dialect, defaultVal string) string {
+	return d.MapDefault(formatDefaultValue(defaultVal))
+}
+
+// unsupported reports an operation the dialect cannot express
+func unsupported(d dialect.Dialect, what, tableName string) error {
+	return fmt.Errorf("%s on table %s is not supported by %s", what, tableName, d.Name())
+}
+
+// GenerateSQL converts a list of Operations into raw SQL statements for the given dialect.
+func GenerateSQL(d dialect.Dialect, ops []diff.Operation) ([]string, error) {
+	r, err := newRenderer(d)
+	if err != nil {
+		return nil, err
+	}
+
 	var sqlStatements []string
 	needsUUIDExtension := false
 
@@ -56,355 +74,263 @@ func GenerateSQL(ops []diff.Operation) ([]string, error) {
 	}
 
 	// Add UUID extension if needed
-	if needsUUIDExtension {
-		sqlStatements = append(sqlStatements, `CREATE EXTENSION IF NOT EXISTS "uuid-ossp";`)
+	if needsUUIDExtension && d.SupportsExtensions() {
+		stmt, err := r.render("create_extension", TemplateData{Extension: "uuid-ossp"})
+		if err != nil {
+			return nil, err
+		}
+		sqlStatements = append(sqlStatements, stmt)
 	}
 
 	for _, op := range ops {
+		var name string
+		var data TemplateData
+
 		switch op.Type {
 		case diff.CreateTable:
-			stmt, err := generateCreateTable(op)
-			if err != nil {
-				return nil, fmt.Errorf("generate CREATE TABLE: %v", err)
-			}
-			sqlStatements = append(sqlStatements, stmt)
+			name = "create_table"
+			data = TemplateData{TableName: op.TableName, Columns: op.Columns}
 
 		case diff.AddColumn:
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" %s`,
-				op.TableName,
-				op.Column.Name,
-				op.Column.Type,
-			)
-			if op.Column.NotNull {
-				stmt += " NOT NULL"
-			}
-			if op.Column.Default != nil {
-				stmt += fmt.Sprintf(" DEFAULT %s", formatDefaultValue(*op.Column.Default))
-			}
-			if op.Column.Unique {
-				stmt += " UNIQUE"
-			}
-			sqlStatements = append(sqlStatements, stmt+";")
+			name = "add_column"
+			data = TemplateData{TableName: op.TableName, Column: op.Column}
 
 		case diff.DropColumn:
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" DROP COLUMN "%s";`,
-				op.TableName,
-				op.ColumnName,
-			)
-			sqlStatements = append(sqlStatements, stmt)
+			name = "drop_column"
+			data = TemplateData{TableName: op.TableName, ColumnName: op.ColumnName}
 
 		case diff.ModifyColumn:
-			stmt, err := generateModifyColumn(op)
+			if !d.SupportsAlterColumn() {
+				return nil, unsupported(d, "modifying column "+op.Column.Name, op.TableName)
+			}
+			name = "modify_column"
+			data, err = modifyColumnData(op, false)
 			if err != nil {
 				return nil, fmt.Errorf("generate MODIFY COLUMN: %v", err)
 			}
-			sqlStatements = append(sqlStatements, stmt)
 
 		case diff.RenameColumn:
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" RENAME COLUMN "%s" TO "%s";`,
-				op.TableName,
-				op.ColumnName,
-				op.NewColumnName,
-			)
-			sqlStatements = append(sqlStatements, stmt)
+			name = "rename_column"
+			data = TemplateData{TableName: op.TableName, ColumnName: op.ColumnName, NewColumnName: op.NewColumnName}
 
 		case diff.DropTable:
-			stmt := fmt.Sprintf(`DROP TABLE IF EXISTS "%s";`,
-				op.TableName,
-			)
-			sqlStatements = append(sqlStatements, stmt)
+			name = "drop_table"
+			data = TemplateData{TableName: op.TableName}
 
 		case diff.AddForeignKey:
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" ADD CONSTRAINT "fk_%s_%s" FOREIGN KEY ("%s") REFERENCES "%s" ("%s")`,
-				op.TableName,
-				op.TableName,
-				op.ForeignKey.ReferencesTable,
-				op.ColumnName,
-				op.ForeignKey.ReferencesTable,
-				op.ForeignKey.ReferencesColumn,
-			)
-			if op.ForeignKey.OnDelete != "" {
-				stmt += fmt.Sprintf(" ON DELETE %s", op.ForeignKey.OnDelete)
+			if !d.SupportsConstraints() {
+				return nil, unsupported(d, "adding foreign key on "+op.ColumnName, op.TableName)
 			}
-			if op.ForeignKey.OnUpdate != "" {
-				stmt += fmt.Sprintf(" ON UPDATE %s", op.ForeignKey.OnUpdate)
+			name = "add_foreign_key"
+			data = TemplateData{
+				TableName:      op.TableName,
+				ColumnName:     op.ColumnName,
+				ConstraintName: foreignKeyName(op),
+				ForeignKey:     op.ForeignKey,
 			}
-			sqlStatements = append(sqlStatements, stmt+";")
 
 		case diff.DropForeignKey:
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" DROP CONSTRAINT "%s";`,
-				op.TableName,
-				op.FKName,
-			)
-			sqlStatements = append(sqlStatements, stmt)
+			if !d.SupportsConstraints() {
+				return nil, unsupported(d, "dropping foreign key "+op.FKName, op.TableName)
+			}
+			name = "drop_foreign_key"
+			data = TemplateData{TableName: op.TableName, ConstraintName: op.FKName}
 
 		case diff.CreateIndex:
-			stmt, err := generateCreateIndex(op)
-			if err != nil {
-				return nil, fmt.Errorf("generate CREATE INDEX: %v", err)
+			if op.Index == nil {
+				return nil, fmt.Errorf("generate CREATE INDEX: index is nil")
 			}
-			sqlStatements = append(sqlStatements, stmt)
+			name = "create_index"
+			data = TemplateData{TableName: op.TableName, Index: op.Index}
 
 		case diff.DropIndex:
-			stmt := fmt.Sprintf(`DROP INDEX IF EXISTS "%s";`,
-				op.IndexName,
-			)
-			sqlStatements = append(sqlStatements, stmt)
+			name = "drop_index"
+			data = TemplateData{TableName: op.TableName, IndexName: op.IndexName}
 
 		default:
 			return nil, fmt.Errorf("unsupported operation: %s", op.Type)
 		}
+
+		stmt, err := r.render(name, data)
+		if err != nil {
+			return nil, err
+		}
+		sqlStatements = append(sqlStatements, stmt)
 	}
 
 	return sqlStatements, nil
 }
 
-// GenerateRollbackSQL converts a list of Operations into rollback SQL statements.
-func GenerateRollbackSQL(ops []diff.Operation) ([]string, error) {
-	var sqlStatements []string
-	needsUUIDExtension := false
-
-	// Check if any operation uses UUID types
-	for _, op := range ops {
-		if op.Type == diff.CreateTable {
-			for _, col := range op.Columns {
-				if strings.Contains(strings.ToLower(col.Type), "uuid") {
-					needsUUIDExtension = true
-					break
-				}
-			}
-		}
-		if needsUUIDExtension {
-			break
-		}
+// GenerateRollbackSQL converts a list of Operations into rollback SQL statements for the given dialect.
+func GenerateRollbackSQL(d dialect.Dialect, ops []diff.Operation) ([]string, error) {
+	r, err := newRenderer(d)
+	if err != nil {
+		return nil, err
 	}
 
+	var sqlStatements []string
+
 	// Process operations in reverse order for rollback
 	for i := len(ops) - 1; i >= 0; i-- {
 		op := ops[i]
+
+		var name string
+		var data TemplateData
+
 		switch op.Type {
 		case diff.CreateTable:
-			stmt := fmt.Sprintf(`DROP TABLE IF EXISTS "%s";`,
-				op.TableName,
-			)
-			sqlStatements = append(sqlStatements, stmt)
+			name = "drop_table"
+			data = TemplateData{TableName: op.TableName}
 
 		case diff.AddColumn:
 			if op.Column == nil {
 				return nil, fmt.Errorf("rollback AddColumn: missing Column for table %s", op.TableName)
 			}
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" DROP COLUMN "%s";`,
-				op.TableName,
-				op.Column.Name,
-			)
-			sqlStatements = append(sqlStatements, stmt)
+			name = "drop_column"
+			data = TemplateData{TableName: op.TableName, Column: op.Column, ColumnName: op.Column.Name}
 
 		case diff.DropColumn:
 			// For rollback, we need to recreate the column with original definition
+			column := &schema.Column{Name: op.ColumnName, Type: "text"}
 			if op.OldColumn != nil {
-				stmt := fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" %s`,
-					op.TableName,
-					op.ColumnName,
-					op.OldColumn.DataType,
-				)
-				if !op.OldColumn.IsNullable {
-					stmt += " NOT NULL"
-				}
-				if op.OldColumn.ColumnDefault != nil {
-					stmt += fmt.Sprintf(" DEFAULT %s", formatDefaultValue(*op.OldColumn.ColumnDefault))
+				column = &schema.Column{
+					Name:    op.ColumnName,
+					Type:    op.OldColumn.DataType,
+					NotNull: !op.OldColumn.IsNullable,
+					Default: op.OldColumn.ColumnDefault,
 				}
-				sqlStatements = append(sqlStatements, stmt+";")
-			} else {
-				// Fallback: create a basic text column if we don't have the original definition
-				stmt := fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" text;`,
-					op.TableName,
-					op.ColumnName,
-				)
-				sqlStatements = append(sqlStatements, stmt)
 			}
+			// Without the original definition this falls back to a basic text column
+			name = "add_column"
+			data = TemplateData{TableName: op.TableName, Column: column}
 
 		case diff.ModifyColumn:
 			if op.Column == nil || op.OldColumn == nil {
 				return nil, fmt.Errorf("rollback ModifyColumn: missing Column or OldColumn for table %s", op.TableName)
 			}
-			stmt, err := generateModifyColumnRollback(op)
+			if !d.SupportsAlterColumn() {
+				return nil, unsupported(d, "modifying column "+op.Column.Name, op.TableName)
+			}
+			name = "modify_column"
+			data, err = modifyColumnData(op, true)
 			if err != nil {
 				return nil, fmt.Errorf("generate MODIFY COLUMN rollback: %v", err)
 			}
-			sqlStatements = append(sqlStatements, stmt)
 
 		case diff.RenameColumn:
 			if op.NewColumnName == "" || op.ColumnName == "" {
 				return nil, fmt.Errorf("rollback RenameColumn: missing NewColumnName or ColumnName for table %s", op.TableName)
 			}
 			// For rollback, rename back to original name
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" RENAME COLUMN "%s" TO "%s";`,
-				op.TableName,
-				op.NewColumnName,
-				op.ColumnName,
-			)
-			sqlStatements = append(sqlStatements, stmt)
+			name = "rename_column"
+			data = TemplateData{TableName: op.TableName, ColumnName: op.NewColumnName, NewColumnName: op.ColumnName}
 
 		case diff.DropTable:
 			// For rollback, we need to recreate the table with original definition
-			if len(op.Columns) > 0 {
-				stmt, err := generateCreateTable(op)
-				if err != nil {
-					return nil, fmt.Errorf("generate CREATE TABLE rollback: %v", err)
-				}
-				sqlStatements = append(sqlStatements, stmt)
-			} else {
+			name = "create_table"
+			data = TemplateData{TableName: op.TableName, Columns: op.Columns}
+			if len(op.Columns) == 0 {
 				// Fallback: create a basic table if we don't have the original definition
-				stmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (id serial PRIMARY KEY);`,
-					op.TableName,
-				)
-				sqlStatements = append(sqlStatements, stmt)
+				data.Columns = []schema.Column{{Name: "id", Type: "serial", Primary: true}}
+				data.IfNotExists = true
 			}
 
 		case diff.AddForeignKey:
 			if op.TableName == "" || op.ForeignKey == nil {
 				return nil, fmt.Errorf("rollback AddForeignKey: missing TableName or ForeignKey")
 			}
+			if !d.SupportsConstraints() {
+				return nil, unsupported(d, "dropping foreign key on "+op.ColumnName, op.TableName)
+			}
 			// For rollback, drop the foreign key constraint
-			constraintName := fmt.Sprintf("fk_%s_%s", op.TableName, op.ForeignKey.ReferencesTable)
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" DROP CONSTRAINT "%s";`,
-				op.TableName,
-				constraintName,
-			)
-			sqlStatements = append(sqlStatements, stmt)
+			name = "drop_foreign_key"
+			data = TemplateData{TableName: op.TableName, ConstraintName: foreignKeyName(op)}
 
 		case diff.DropForeignKey:
 			if op.TableName == "" || op.FKName == "" || op.ForeignKey == nil || op.ColumnName == "" {
 				return nil, fmt.Errorf("rollback DropForeignKey: missing TableName, FKName, ForeignKey, or ColumnName")
 			}
-			// For rollback, we need to recreate the foreign key
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" ADD CONSTRAINT "%s" FOREIGN KEY ("%s") REFERENCES "%s" ("%s")`,
-				op.TableName,
-				op.FKName,
-				op.ColumnName,
-				op.ForeignKey.ReferencesTable,
-				op.ForeignKey.ReferencesColumn,
-			)
-			if op.ForeignKey.OnDelete != "" {
-				stmt += fmt.Sprintf(" ON DELETE %s", op.ForeignKey.OnDelete)
+			if !d.SupportsConstraints() {
+				return nil, unsupported(d, "adding foreign key "+op.FKName, op.TableName)
 			}
-			if op.ForeignKey.OnUpdate != "" {
-				stmt += fmt.Sprintf(" ON UPDATE %s", op.ForeignKey.OnUpdate)
+			// For rollback, we need to recreate the foreign key
+			name = "add_foreign_key"
+			data = TemplateData{
+				TableName:      op.TableName,
+				ColumnName:     op.ColumnName,
+				ConstraintName: op.FKName,
+				ForeignKey:     op.ForeignKey,
 			}
-			sqlStatements = append(sqlStatements, stmt+";")
 
 		case diff.CreateIndex:
 			if op.Index == nil || op.Index.Name == "" {
 				return nil, fmt.Errorf("rollback CreateIndex: missing Index or Index.Name for table %s", op.TableName)
 			}
-			stmt := fmt.Sprintf(`DROP INDEX IF EXISTS "%s";`,
-				op.Index.Name,
-			)
-			sqlStatements = append(sqlStatements, stmt)
+			name = "drop_index"
+			data = TemplateData{TableName: op.TableName, IndexName: op.Index.Name}
 
 		case diff.DropIndex:
-			if op.IndexName != "" && op.TableName != "" {
-				// For rollback, we need to recreate the index
-				// Note: We don't have the original index definition, so we'll create a basic index
-				columnName := "id" // Default column name
-				if op.ColumnName != "" {
-					columnName = op.ColumnName
-				}
-				stmt := fmt.Sprintf(`CREATE INDEX "%s" ON "%s" ("%s");`,
-					op.IndexName,
-					op.TableName,
-					columnName,
-				)
-				sqlStatements = append(sqlStatements, stmt)
-			} else {
+			if op.IndexName == "" || op.TableName == "" {
 				return nil, fmt.Errorf("rollback DropIndex: missing IndexName or TableName for index rollback")
 			}
+			// For rollback, we need to recreate the index
+			// Note: We don't have the original index definition, so we'll create a basic index
+			columnName := "id" // Default column name
+			if op.ColumnName != "" {
+				columnName = op.ColumnName
+			}
+			name = "create_index"
+			data = TemplateData{
+				TableName: op.TableName,
+				Index: &schema.Index{
+					Name:    op.IndexName,
+					Table:   op.TableName,
+					Columns: []string{columnName},
+				},
+			}
 
 		default:
 			return nil, fmt.Errorf("unsupported rollback operation: %s", op.Type)
 		}
-	}
 
-	return sqlStatements, nil
-}
-
-func generateCreateTable(op diff.Operation) (string, error) {
-	stmt := fmt.Sprintf(`CREATE TABLE "%s" (`, op.TableName)
-
-	for i, col := range op.Columns {
-		stmt += fmt.Sprintf(`"%s" %s`, col.Name, col.Type)
-		if col.Primary {
-			stmt += " PRIMARY KEY"
-		}
-		if col.Unique {
-			stmt += " UNIQUE"
-		}
-		if col.NotNull {
-			stmt += " NOT NULL"
-		}
-		if col.Default != nil {
-			stmt += fmt.Sprintf(" DEFAULT %s", formatDefaultValue(*col.Default))
-		}
-		if i < len(op.Columns)-1 {
-			stmt += ", "
+		stmt, err := r.render(name, data)
+		if err != nil {
+			return nil, err
 		}
+		sqlStatements = append(sqlStatements, stmt)
 	}
 
-	stmt += ");"
-
-	return stmt, nil
+	return sqlStatements, nil
 }
 
-func generateCreateIndex(op diff.Operation) (string, error) {
-	if op.Index == nil {
-		return "", fmt.Errorf("index is nil")
-	}
-
-	stmt := "CREATE"
-	if op.Index.Unique {
-		stmt += " UNIQUE"
+// foreignKeyName returns the constraint name migrato uses for a foreign key
+func foreignKeyName(op diff.Operation) string {
+	if op.FKName != "" {
+		return op.FKName
 	}
-	
-	stmt += " INDEX"
-	if op.Index.Name != "" {
-		stmt += fmt.Sprintf(` "%s"`, op.Index.Name)
-	}
-	
-	stmt += fmt.Sprintf(` ON "%s"`, op.Index.Table)
-	
-	// Add index type if specified
-	if op.Index.Type != "" && op.Index.Type != "btree" {
-		stmt += fmt.Sprintf(" USING %s", op.Index.Type)
-	}
-	
-	// Add columns
-	stmt += " ("
-	for i, col := range op.Index.Columns {
-		if i > 0 {
-			stmt += ", "
-		}
-		stmt += fmt.Sprintf(`"%s"`, col)
-	}
-	stmt += ");"
-
-	return stmt, nil
+	return fmt.Sprintf("fk_%s_%s", op.TableName, op.ForeignKey.ReferencesTable)
 }
 
-func generateModifyColumn(op diff.Operation) (string, error) {
+// modifyColumnData works out which parts of a column changed. With rollback
+// set, the changes lead from the new definition back to the old one.
+func modifyColumnData(op diff.Operation, rollback bool) (TemplateData, error) {
 	if op.Column == nil || op.OldColumn == nil {
-		return "", fmt.Errorf("column or old column is nil")
+		return TemplateData{}, fmt.Errorf("column or old column is nil")
 	}
 
-	var statements []string
+	data := TemplateData{
+		TableName:  op.TableName,
+		ColumnName: op.Column.Name,
+	}
+	changed := false
 
 	// Type change
 	if !strings.EqualFold(op.OldColumn.DataType, op.Column.Type) {
-		stmt := fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" TYPE %s`,
-			op.TableName,
-			op.Column.Name,
-			op.Column.Type,
-		)
-		statements = append(statements, stmt)
+		data.NewType = op.Column.Type
+		if rollback {
+			data.NewType = op.OldColumn.DataType
+		}
+		changed = true
 	}
 
 	// NOT NULL constraint change
@@ -412,21 +338,13 @@ func generateModifyColumn(op diff.Operation) (string, error) {
 	newNullable := !op.Column.NotNull
 
 	if oldNullable != newNullable {
-		if newNullable {
-			// Remove NOT NULL constraint
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" DROP NOT NULL`,
-				op.TableName,
-				op.Column.Name,
-			)
-			statements = append(statements, stmt)
-		} else {
-			// Add NOT NULL constraint
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" SET NOT NULL`,
-				op.TableName,
-				op.Column.Name,
-			)
-			statements = append(statements, stmt)
+		targetNullable := newNullable
+		if rollback {
+			targetNullable = oldNullable
 		}
+		data.DropNotNull = targetNullable
+		data.SetNotNull = !targetNullable
+		changed = true
 	}
 
 	// Default value change
@@ -436,140 +354,109 @@ func generateModifyColumn(op diff.Operation) (string, error) {
 	if (oldDefault == nil && newDefault != nil) ||
 		(oldDefault != nil && newDefault == nil) ||
 		(oldDefault != nil && newDefault != nil && *oldDefault != *newDefault) {
-		
-		if newDefault == nil {
-			// Remove default
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" DROP DEFAULT`,
-				op.TableName,
-				op.Column.Name,
-			)
-			statements = append(statements, stmt)
-		} else {
-			// Set new default
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" SET DEFAULT %s`,
-				op.TableName,
-				op.Column.Name,
-				formatDefaultValue(*newDefault),
-			)
-			statements = append(statements, stmt)
-		}
-	}
-
-	if len(statements) == 0 {
-		return "", fmt.Errorf("no modifications needed")
-	}
-
-	return strings.Join(statements, ";\n") + ";", nil
-}
-
-func generateModifyColumnRollback(op diff.Operation) (string, error) {
-	if op.Column == nil || op.OldColumn == nil {
-		return "", fmt.Errorf("column or old column is nil")
-	}
-
-	var statements []string
-
-	// Type change rollback
-	if !strings.EqualFold(op.OldColumn.DataType, op.Column.Type) {
-		stmt := fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" TYPE %s`,
-			op.TableName,
-			op.Column.Name,
-			op.OldColumn.DataType,
-		)
-		statements = append(statements, stmt)
-	}
-
-	// NOT NULL constraint change rollback
-	oldNullable := op.OldColumn.IsNullable
-	newNullable := !op.Column.NotNull
 
-	if oldNullable != newNullable {
-		if oldNullable {
-			// Remove NOT NULL constraint (rollback: add it back)
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" DROP NOT NULL`,
-				op.TableName,
-				op.Column.Name,
-			)
-			statements = append(statements, stmt)
-		} else {
-			// Add NOT NULL constraint (rollback: remove it)
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" SET NOT NULL`,
-				op.TableName,
-				op.Column.Name,
-			)
-			statements = append(statements, stmt)
+		targetDefault := newDefault
+		if rollback {
+			targetDefault = oldDefault
 		}
+		data.SetDefault = targetDefault
+		data.DropDefault = targetDefault == nil
+		changed = true
 	}
 
-	// Default value change rollback
-	oldDefault := op.OldColumn.ColumnDefault
-	newDefault := op.Column.Default
-
-	if (oldDefault == nil && newDefault != nil) ||
-		(oldDefault != nil && newDefault == nil) ||
-		(oldDefault != nil && newDefault != nil && *oldDefault != *newDefault) {
-		
-		if oldDefault == nil {
-			// Remove default (rollback: add it back)
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" DROP DEFAULT`,
-				op.TableName,
-				op.Column.Name,
-			)
-			statements = append(statements, stmt)
-		} else {
-			// Set old default (rollback: restore original)
-			stmt := fmt.Sprintf(`ALTER TABLE "%s" ALTER COLUMN "%s" SET DEFAULT %s`,
-				op.TableName,
-				op.Column.Name,
-				formatDefaultValue(*oldDefault),
-			)
-			statements = append(statements, stmt)
+	if !changed {
+		if rollback {
+			return TemplateData{}, fmt.Errorf("no rollback modifications needed")
 		}
+		return TemplateData{}, fmt.Errorf("no modifications needed")
 	}
 
-	if len(statements) == 0 {
-		return "", fmt.Errorf("no rollback modifications needed")
-	}
+	return data, nil
+}
 
-	return strings.Join(statements, ";\n") + ";", nil
+// MigrationFile describes a migration file to be written
+type MigrationFile struct {
+	Version     string   // filename prefix, the current timestamp when empty
+	Name        string   // filename suffix, "migration" when empty
+	Description string   // "Auto-generated migration" when empty
+	Directives  []string // header lines written as "-- migrato:<directive>"
+	Up          []string
+	Down        []string
 }
 
+// MigrationsDir is the folder new migration files are written to
+var MigrationsDir = "migrations"
+
 // WriteMigrationFile saves the SQL statements into a timestamped .sql file with up/down sections
 func WriteMigrationFile(sqlStatements []string, rollbackStatements []string) (string, error) {
-	// Ensure migrations folder exists
-	if _, err := os.Stat("migrations"); os.IsNotExist(err) {
-		err = os.Mkdir("migrations", 0755)
-		if err != nil {
-			return "", fmt.Errorf("creating migrations folder: %v", err)
-		}
+	return WriteMigration(MigrationFile{Up: sqlStatements, Down: rollbackStatements})
+}
+
+// RenderMigration returns the path and content of a migration file without writing it
+func RenderMigration(f MigrationFile) (string, string) {
+	version := f.Version
+	if version == "" {
+		version = time.Now().Format("20060102150405")
+	}
+	name := f.Name
+	if name == "" {
+		name = "migration"
+	}
+	description := f.Description
+	if description == "" {
+		description = "Auto-generated migration"
 	}
 
 	// Create filename
-	timestamp := time.Now().Format("20060102150405")
-	filename := fmt.Sprintf("migrations/%s_migration.sql", timestamp)
+	filename := filepath.Join(MigrationsDir, fmt.Sprintf("%s_%s.sql", version, name))
 
 	// Create content with up/down sections
-	content := "-- Migration: " + timestamp + "\n"
-	content += "-- Description: Auto-generated migration\n\n"
+	content := "-- Migration: " + version + "\n"
+	content += "-- Description: " + description + "\n"
+	for _, directive := range f.Directives {
+		content += "-- migrato:" + directive + "\n"
+	}
+	content += "\n"
 	
 	// Up migration
 	content += "-- Up Migration\n"
 	content += "-- ============\n"
-	for _, stmt := range sqlStatements {
+	for _, stmt := range f.Up {
 		content += stmt + "\n"
 	}
 	
 	content += "\n-- Down Migration (Rollback)\n"
 	content += "-- =======================\n"
-	for _, stmt := range rollbackStatements {
+	for _, stmt := range f.Down {
 		content += stmt + "\n"
 	}
+	return filename, content
+}
+
+// WriteMigration saves a migration file with up/down sections into the migrations folder
+func WriteMigration(f MigrationFile) (string, error) {
+	// Ensure migrations folder exists
+	if _, err := os.Stat(MigrationsDir); os.IsNotExist(err) {
+		err = os.MkdirAll(MigrationsDir, 0755)
+		if err != nil {
+			return "", fmt.Errorf("creating migrations folder: %v", err)
+		}
+	}
+	// Edits made since the sum file was written must not be recorded with it
+	if err := VerifySum(MigrationsDir); err != nil && err != ErrNoSum {
+		return "", err
+	}
+
+	filename, content := RenderMigration(f)
 
 	// Write to file
 	err := os.WriteFile(filename, []byte(content), 0644)
 	if err != nil {
 		return "", fmt.Errorf("writing migration file: %v", err)
 	}
+	if err := WriteSum(MigrationsDir); err != nil {
+		return "", err
+	}
 
 	return filename, nil
 }
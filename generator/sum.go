@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SumFile lists the hash of every migration file and a hash over all of
+// them. It lives in the migrations directory and is committed with it.
+const SumFile = "migrations.sum"
+
+// sumPrefix marks the hashes written to the sum file
+const sumPrefix = "sha256:"
+
+// ErrNoSum is returned by VerifySum when the directory has no sum file
+var ErrNoSum = errors.New(SumFile + " not found; run 'migrato sum' to create it")
+
+// SumError describes how the migrations directory differs from its sum file
+type SumError struct {
+	Added   []string // files missing from the sum file
+	Removed []string // files listed in the sum file that are gone
+	Changed []string // files whose content changed
+	// Conflict is set when the sum file holds git merge conflict markers
+	Conflict bool
+}
+
+func (e *SumError) Error() string {
+	if e.Conflict {
+		return SumFile + " has merge conflict markers; reconcile the migrations of both branches, then run 'migrato sum'"
+	}
+	if len(e.Added)+len(e.Removed)+len(e.Changed) == 0 {
+		return SumFile + " was edited by hand; its directory hash does not match its entries, run 'migrato sum'"
+	}
+	return fmt.Sprintf("%s does not match the migrations (%d added, %d removed, %d changed); review the changes, then run 'migrato sum'",
+		SumFile, len(e.Added), len(e.Removed), len(e.Changed))
+}
+
+// Details lists the differing files, one per line
+func (e *SumError) Details() []string {
+	var lines []string
+	for _, f := range e.Added {
+		lines = append(lines, "added:   "+f)
+	}
+	for _, f := range e.Removed {
+		lines = append(lines, "removed: "+f)
+	}
+	for _, f := range e.Changed {
+		lines = append(lines, "changed: "+f)
+	}
+	return lines
+}
+
+// sumEntry is the hash of one migration file
+type sumEntry struct {
+	Name string // relative to the migrations directory, with forward slashes
+	Hash string
+}
+
+// hashMigrations hashes the migration files of dir and of its squashed
+// folder, sorted by name
+func hashMigrations(dir string) ([]sumEntry, error) {
+	var entries []sumEntry
+	for _, sub := range []string{"", "squashed"} {
+		files, err := os.ReadDir(filepath.Join(dir, sub))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read migrations dir: %v", err)
+		}
+		for _, f := range files {
+			if f.IsDir() || !strings.HasSuffix(f.Name(), ".sql") {
+				continue
+			}
+			content, err := os.ReadFile(filepath.Join(dir, sub, f.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("read %s: %v", f.Name(), err)
+			}
+			entries = append(entries, sumEntry{Name: path.Join(sub, f.Name()), Hash: fmt.Sprintf("%s%x", sumPrefix, sha256.Sum256(content))})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// dirHash hashes the entry lines, so adding, removing, renaming or
+// reordering files changes it as well as editing them
+func dirHash(entries []sumEntry) string {
+	h := sha256.New()
+	for _, e := range entries {
+		fmt.Fprintf(h, "%s %s\n", e.Name, e.Hash)
+	}
+	return fmt.Sprintf("%s%x", sumPrefix, h.Sum(nil))
+}
+
+// WriteSum rewrites the sum file of dir from the migration files on disk
+func WriteSum(dir string) error {
+	entries, err := hashMigrations(dir)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString(dirHash(entries) + "\n")
+	for _, e := range entries {
+		fmt.Fprintf(&b, "%s %s\n", e.Name, e.Hash)
+	}
+	if err := os.WriteFile(filepath.Join(dir, SumFile), []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("write %s: %v", SumFile, err)
+	}
+	return nil
+}
+
+// VerifySum checks the migration files of dir against its sum file. It
+// returns ErrNoSum when there is no sum file and a *SumError when they differ.
+func VerifySum(dir string) error {
+	f, err := os.Open(filepath.Join(dir, SumFile))
+	if os.IsNotExist(err) {
+		return ErrNoSum
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %v", SumFile, err)
+	}
+	defer f.Close()
+
+	var recordedDir string
+	recorded := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "<<<<<<<"), strings.HasPrefix(line, "======="), strings.HasPrefix(line, ">>>>>>>"):
+			return &SumError{Conflict: true}
+		case recordedDir == "":
+			recordedDir = line
+		default:
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				return fmt.Errorf("%s: malformed line %q", SumFile, line)
+			}
+			recorded[fields[0]] = fields[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read %s: %v", SumFile, err)
+	}
+
+	entries, err := hashMigrations(dir)
+	if err != nil {
+		return err
+	}
+	mismatch := &SumError{}
+	for _, e := range entries {
+		hash, ok := recorded[e.Name]
+		switch {
+		case !ok:
+			mismatch.Added = append(mismatch.Added, e.Name)
+		case hash != e.Hash:
+			mismatch.Changed = append(mismatch.Changed, e.Name)
+		}
+		delete(recorded, e.Name)
+	}
+	for name := range recorded {
+		mismatch.Removed = append(mismatch.Removed, name)
+	}
+	sort.Strings(mismatch.Removed)
+
+	if len(mismatch.Added)+len(mismatch.Removed)+len(mismatch.Changed) > 0 || recordedDir != dirHash(entries) {
+		return mismatch
+	}
+	return nil
+}
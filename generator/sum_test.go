@@ -0,0 +1,215 @@
+package generator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFile writes content to name under dir, creating its folder
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// editSum rewrites the sum file of dir with edit
+func editSum(t *testing.T, dir string, edit func(string) string) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, SumFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, SumFile, edit(string(content)))
+}
+
+func TestVerifySum(t *testing.T) {
+	tests := []struct {
+		name string
+		// change alters the directory after WriteSum
+		change  func(t *testing.T, dir string)
+		want    *SumError // the mismatch; nil means the sum matches
+		wantErr string    // an error other than a mismatch
+	}{
+		{
+			name:   "unchanged",
+			change: func(t *testing.T, dir string) {},
+		},
+		{
+			name: "other files are ignored",
+			change: func(t *testing.T, dir string) {
+				writeFile(t, dir, "README.md", "notes")
+				writeFile(t, dir, "drafts/20240101000009_draft.sql", "SELECT 1;")
+			},
+		},
+		{
+			name: "no sum file",
+			change: func(t *testing.T, dir string) {
+				if err := os.Remove(filepath.Join(dir, SumFile)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: ErrNoSum.Error(),
+		},
+		{
+			name: "file added",
+			change: func(t *testing.T, dir string) {
+				writeFile(t, dir, "20240101000002_c.sql", "CREATE TABLE c (id INT);")
+			},
+			want: &SumError{Added: []string{"20240101000002_c.sql"}},
+		},
+		{
+			name: "file removed",
+			change: func(t *testing.T, dir string) {
+				if err := os.Remove(filepath.Join(dir, "20240101000000_a.sql")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: &SumError{Removed: []string{"20240101000000_a.sql"}},
+		},
+		{
+			name: "file changed",
+			change: func(t *testing.T, dir string) {
+				writeFile(t, dir, "20240101000001_b.sql", "CREATE TABLE b (id BIGINT);")
+			},
+			want: &SumError{Changed: []string{"20240101000001_b.sql"}},
+		},
+		{
+			name: "file renamed",
+			change: func(t *testing.T, dir string) {
+				if err := os.Rename(filepath.Join(dir, "20240101000001_b.sql"), filepath.Join(dir, "20240101000003_b.sql")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: &SumError{Added: []string{"20240101000003_b.sql"}, Removed: []string{"20240101000001_b.sql"}},
+		},
+		{
+			name: "squashed file changed",
+			change: func(t *testing.T, dir string) {
+				writeFile(t, dir, "squashed/20230101000000_old.sql", "CREATE TABLE old (id BIGINT);")
+			},
+			want: &SumError{Changed: []string{"squashed/20230101000000_old.sql"}},
+		},
+		{
+			name: "merge conflict markers",
+			change: func(t *testing.T, dir string) {
+				editSum(t, dir, func(sum string) string {
+					return "<<<<<<< HEAD\n" + sum + "=======\n" + sum + ">>>>>>> feature\n"
+				})
+			},
+			want: &SumError{Conflict: true},
+		},
+		{
+			name: "directory hash edited by hand",
+			change: func(t *testing.T, dir string) {
+				editSum(t, dir, func(sum string) string {
+					return sumPrefix + strings.Repeat("0", 64) + sum[strings.Index(sum, "\n"):]
+				})
+			},
+			want: &SumError{},
+		},
+		{
+			name: "entry removed by hand",
+			change: func(t *testing.T, dir string) {
+				editSum(t, dir, func(sum string) string {
+					lines := strings.Split(sum, "\n")
+					return strings.Join(append(lines[:1], lines[2:]...), "\n")
+				})
+			},
+			want: &SumError{Added: []string{"20240101000000_a.sql"}},
+		},
+		{
+			name: "malformed line",
+			change: func(t *testing.T, dir string) {
+				editSum(t, dir, func(sum string) string { return sum + "20240101000002_c.sql\n" })
+			},
+			wantErr: `malformed line "20240101000002_c.sql"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, "20240101000000_a.sql", "CREATE TABLE a (id INT);")
+			writeFile(t, dir, "20240101000001_b.sql", "CREATE TABLE b (id INT);")
+			writeFile(t, dir, "squashed/20230101000000_old.sql", "CREATE TABLE old (id INT);")
+			if err := WriteSum(dir); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.change(t, dir)
+			err := VerifySum(dir)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("VerifySum error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("VerifySum: %v", err)
+				}
+				return
+			}
+			var mismatch *SumError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("VerifySum error = %v, want a SumError", err)
+			}
+			if !reflect.DeepEqual(mismatch, tt.want) {
+				t.Errorf("SumError = %+v, want %+v", mismatch, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteSum(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "20240101000001_b.sql", "b")
+	writeFile(t, dir, "20240101000000_a.sql", "a")
+	writeFile(t, dir, "squashed/20230101000000_old.sql", "old")
+	writeFile(t, dir, "README.md", "notes")
+	if err := WriteSum(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, SumFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	var names []string
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], sumPrefix) {
+			t.Fatalf("malformed sum line %q", line)
+		}
+		names = append(names, fields[0])
+	}
+	want := []string{"20240101000000_a.sql", "20240101000001_b.sql", "squashed/20230101000000_old.sql"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("sum entries = %q, want %q", names, want)
+	}
+	if !strings.HasPrefix(lines[0], sumPrefix) {
+		t.Errorf("first line = %q, want the directory hash", lines[0])
+	}
+
+	// Writing again from the same files gives the same sum
+	if err := WriteSum(dir); err != nil {
+		t.Fatal(err)
+	}
+	again, err := os.ReadFile(filepath.Join(dir, SumFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(content) {
+		t.Errorf("second WriteSum wrote\n%s\nwant\n%s", again, content)
+	}
+}
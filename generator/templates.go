@@ -0,0 +1,179 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/ridoystarlord/migrato/dialect"
+	"github.com/ridoystarlord/migrato/schema"
+	"github.com/ridoystarlord/migrato/templates"
+)
+
+// TemplateDir is the project directory searched for SQL template overrides.
+// A file named <dialect>/<operation>.tmpl takes precedence over
+// <operation>.tmpl, which takes precedence over the embedded default.
+var TemplateDir = "templates"
+
+// TemplateData is passed to every SQL template. Only the fields relevant to
+// the operation being rendered are set.
+type TemplateData struct {
+	Dialect string
+
+	TableName   string
+	Columns     []schema.Column // create_table
+	IfNotExists bool            // create_table
+
+	Column        *schema.Column // add_column, drop_column (when the definition is known)
+	ColumnName    string         // drop_column, rename_column, modify_column, add_foreign_key
+	NewColumnName string         // rename_column
+
+	ConstraintName string             // add_foreign_key, drop_foreign_key
+	ForeignKey     *schema.ForeignKey // add_foreign_key
+
+	Index     *schema.Index // create_index
+	IndexName string        // drop_index
+
+	Extension string // create_extension
+
+	// modify_column
+	NewType     string
+	SetNotNull  bool
+	DropNotNull bool
+	SetDefault  *string
+	DropDefault bool
+}
+
+// renderer renders operations through the templates of one dialect
+type renderer struct {
+	dialect   dialect.Dialect
+	templates map[string]*template.Template
+}
+
+// newRenderer loads every template, preferring project overrides to the embedded defaults
+func newRenderer(d dialect.Dialect) (*renderer, error) {
+	r := &renderer{
+		dialect:   d,
+		templates: map[string]*template.Template{},
+	}
+
+	for _, name := range templates.Names {
+		source, origin, err := loadTemplate(d, name)
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl, err := template.New(name).Funcs(r.funcs()).Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("parse template %s: %v", origin, err)
+		}
+		r.templates[name] = tmpl
+	}
+
+	return r, nil
+}
+
+// loadTemplate returns the source of a template and where it was loaded from
+func loadTemplate(d dialect.Dialect, name string) (string, string, error) {
+	filename := name + ".tmpl"
+
+	if TemplateDir != "" {
+		candidates := []string{
+			filepath.Join(TemplateDir, d.Name(), filename),
+			filepath.Join(TemplateDir, filename),
+		}
+		for _, path := range candidates {
+			content, err := os.ReadFile(path)
+			if err == nil {
+				return string(content), path, nil
+			}
+			if !os.IsNotExist(err) {
+				return "", "", fmt.Errorf("read template %s: %v", path, err)
+			}
+		}
+	}
+
+	content, err := templates.FS.ReadFile(filename)
+	if err != nil {
+		return "", "", fmt.Errorf("read embedded template %s: %v", filename, err)
+	}
+	return string(content), "embedded " + filename, nil
+}
+
+func (r *renderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"quote": dialect.QuoteIdent,
+		"quoteList": func(names []string) string {
+			quoted := make([]string, len(names))
+			for i, name := range names {
+				quoted[i] = dialect.QuoteIdent(name)
+			}
+			return strings.Join(quoted, ", ")
+		},
+		"type": r.dialect.MapType,
+		"default": func(value *string) string {
+			return formatDefault(r.dialect, *value)
+		},
+		"supports": func(feature string) (bool, error) {
+			switch feature {
+			case "alter_column":
+				return r.dialect.SupportsAlterColumn(), nil
+			case "constraints":
+				return r.dialect.SupportsConstraints(), nil
+			case "index_methods":
+				return r.dialect.SupportsIndexMethods(), nil
+			case "extensions":
+				return r.dialect.SupportsExtensions(), nil
+			}
+			return false, fmt.Errorf("unknown feature '%s'", feature)
+		},
+		"last": func(i int, list interface{}) bool {
+			return i == reflect.ValueOf(list).Len()-1
+		},
+	}
+}
+
+// render executes the named template and returns the trimmed SQL
+func (r *renderer) render(name string, data TemplateData) (string, error) {
+	tmpl, ok := r.templates[name]
+	if !ok {
+		return "", fmt.Errorf("unknown template %s", name)
+	}
+
+	data.Dialect = r.dialect.Name()
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("render template %s: %v", name, err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// WriteDefaultTemplates copies the embedded templates into dir so they can be
+// customized, leaving files that already exist untouched. It returns the
+// paths that were written.
+func WriteDefaultTemplates(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating templates folder: %v", err)
+	}
+
+	var written []string
+	for _, name := range templates.Names {
+		path := filepath.Join(dir, name+".tmpl")
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		content, err := templates.FS.ReadFile(name + ".tmpl")
+		if err != nil {
+			return written, fmt.Errorf("read embedded template %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return written, fmt.Errorf("writing template %s: %v", path, err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
@@ -2,12 +2,13 @@ package introspect
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"path"
 	"strings"
 
-	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ridoystarlord/migrato/database"
+	"github.com/ridoystarlord/migrato/dialect"
 )
 
 type ExistingTable struct {
@@ -20,6 +21,7 @@ type ExistingTable struct {
 type ExistingColumn struct {
 	ColumnName    string
 	DataType      string
+	ColumnType    string // full declared type, e.g. character varying(255)
 	IsNullable    bool
 	ColumnDefault *string
 	IsPrimaryKey  bool
@@ -41,23 +43,88 @@ type ExistingIndex struct {
 	Columns   []string
 	IsUnique  bool
 	IndexType string
+	// Constraint is set for indexes that back a primary key or unique constraint
+	Constraint bool
 }
 
-func IntrospectDatabase() ([]ExistingTable, error) {
-	ctx := context.Background()
-	pool, err := database.GetPool()
+// TrackingTables are the tables migrato keeps its own bookkeeping in
+var TrackingTables = []string{"schema_migrations", "migration_logs", "schema_migration_history", "schema_migrations_version", "schema_seeds"}
+
+// IsTrackingTable reports whether a table belongs to migrato rather than the application
+func IsTrackingTable(tableName string) bool {
+	for _, t := range TrackingTables {
+		if t == tableName {
+			return true
+		}
+	}
+	return false
+}
+
+// IgnoredTables are glob patterns, such as "tmp_*", of tables that migrato
+// leaves out of diffs and generated models
+var IgnoredTables []string
+
+// IsIgnoredTable reports whether a table is a tracking table or matches IgnoredTables
+func IsIgnoredTable(tableName string) bool {
+	if IsTrackingTable(tableName) {
+		return true
+	}
+	for _, pattern := range IgnoredTables {
+		if matched, _ := path.Match(pattern, tableName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultSchema is the PostgreSQL schema introspected when none is given,
+// "public" when empty
+var DefaultSchema string
+
+// Querier is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// IntrospectDatabase reads the current schema of the database in DATABASE_URL
+func IntrospectDatabase(ctx context.Context) ([]ExistingTable, error) {
+	db, d, err := database.GetDB()
 	if err != nil {
-		return nil, fmt.Errorf("unable to get connection pool: %v", err)
+		return nil, fmt.Errorf("unable to get database: %v", err)
 	}
 
+	return Introspect(ctx, db, d)
+}
+
+// Introspect reads the current schema using the catalog queries of the given dialect
+func Introspect(ctx context.Context, q Querier, d dialect.Dialect) ([]ExistingTable, error) {
+	return IntrospectSchema(ctx, q, d, DefaultSchema)
+}
+
+// IntrospectSchema reads the tables of one PostgreSQL schema, "public" when
+// schemaName is empty. SQLite has no schemas and ignores schemaName.
+func IntrospectSchema(ctx context.Context, q Querier, d dialect.Dialect, schemaName string) ([]ExistingTable, error) {
+	if schemaName == "" {
+		schemaName = "public"
+	}
+	switch d {
+	case dialect.Postgres:
+		return introspectPostgres(ctx, q, schemaName)
+	case dialect.SQLite:
+		return introspectSQLite(ctx, q)
+	}
+	return nil, fmt.Errorf("introspection is not supported for %s", d.Name())
+}
+
+func introspectPostgres(ctx context.Context, pool Querier, schemaName string) ([]ExistingTable, error) {
 	tablesQuery := `
 	SELECT table_name
 	FROM information_schema.tables
-	WHERE table_schema = 'public' AND table_type='BASE TABLE'
+	WHERE table_schema = $1 AND table_type='BASE TABLE'
 	ORDER BY table_name;
 	`
 
-	rows, err := pool.Query(ctx, tablesQuery)
+	rows, err := pool.QueryContext(ctx, tablesQuery, schemaName)
 	if err != nil {
 		return nil, fmt.Errorf("querying tables: %v", err)
 	}
@@ -75,20 +142,21 @@ func IntrospectDatabase() ([]ExistingTable, error) {
 	if rows.Err() != nil {
 		return nil, fmt.Errorf("iterating table rows: %v", rows.Err())
 	}
+	rows.Close()
 
 	var tables []ExistingTable
 	for _, tableName := range tableNames {
-		columns, err := getColumns(ctx, pool, tableName)
+		columns, err := getColumns(ctx, pool, schemaName, tableName)
 		if err != nil {
 			return nil, fmt.Errorf("getting columns for table %s: %v", tableName, err)
 		}
 
-		foreignKeys, err := getForeignKeys(ctx, pool, tableName)
+		foreignKeys, err := getForeignKeys(ctx, pool, schemaName, tableName)
 		if err != nil {
 			return nil, fmt.Errorf("getting foreign keys for table %s: %v", tableName, err)
 		}
 
-		indexes, err := getIndexes(ctx, pool, tableName)
+		indexes, err := getIndexes(ctx, pool, schemaName, tableName)
 		if err != nil {
 			return nil, fmt.Errorf("getting indexes for table %s: %v", tableName, err)
 		}
@@ -104,43 +172,44 @@ func IntrospectDatabase() ([]ExistingTable, error) {
 	return tables, nil
 }
 
-// Connect returns a database connection for use by other packages
-func Connect() (*pgx.Conn, error) {
-	ctx := context.Background()
-	return database.GetConnection(ctx)
-}
-
-func getColumns(ctx context.Context, pool *pgxpool.Pool, tableName string) ([]ExistingColumn, error) {
+func getColumns(ctx context.Context, pool Querier, schemaName, tableName string) ([]ExistingColumn, error) {
 	columnsQuery := `
 	SELECT
 		c.column_name,
 		c.data_type,
+		format_type(a.atttypid, a.atttypmod) as column_type,
 		(c.is_nullable = 'YES') as is_nullable,
 		c.column_default,
 		(CASE WHEN tc.constraint_type = 'PRIMARY KEY' THEN true ELSE false END) as is_primary,
 		(CASE WHEN tc.constraint_type = 'UNIQUE' THEN true ELSE false END) as is_unique
 	FROM information_schema.columns c
+	JOIN pg_attribute a
+		ON a.attrelid = format('%I.%I', c.table_schema, c.table_name)::regclass AND a.attname = c.column_name
 	LEFT JOIN information_schema.key_column_usage kcu
-		ON c.table_name = kcu.table_name AND c.column_name = kcu.column_name
+		ON c.table_schema = kcu.table_schema AND c.table_name = kcu.table_name AND c.column_name = kcu.column_name
 	LEFT JOIN information_schema.table_constraints tc
-		ON kcu.constraint_name = tc.constraint_name AND kcu.table_name = tc.table_name
-	WHERE c.table_schema = 'public' AND c.table_name = $1
+		ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name AND kcu.table_name = tc.table_name
+	WHERE c.table_schema = $1 AND c.table_name = $2
 	ORDER BY c.ordinal_position;
 	`
 
-	rows, err := pool.Query(ctx, columnsQuery, tableName)
+	rows, err := pool.QueryContext(ctx, columnsQuery, schemaName, tableName)
 	if err != nil {
 		return nil, fmt.Errorf("querying columns: %v", err)
 	}
 	defer rows.Close()
 
+	// A column that takes part in several constraints comes back once per
+	// constraint, so rows are merged by column name
 	var columns []ExistingColumn
+	seen := map[string]int{}
 	for rows.Next() {
 		var col ExistingColumn
 		var nullable bool
 		if err := rows.Scan(
 			&col.ColumnName,
 			&col.DataType,
+			&col.ColumnType,
 			&nullable,
 			&col.ColumnDefault,
 			&col.IsPrimaryKey,
@@ -149,6 +218,12 @@ func getColumns(ctx context.Context, pool *pgxpool.Pool, tableName string) ([]Ex
 			return nil, fmt.Errorf("scanning column: %v", err)
 		}
 		col.IsNullable = nullable
+		if i, ok := seen[col.ColumnName]; ok {
+			columns[i].IsPrimaryKey = columns[i].IsPrimaryKey || col.IsPrimaryKey
+			columns[i].IsUnique = columns[i].IsUnique || col.IsUnique
+			continue
+		}
+		seen[col.ColumnName] = len(columns)
 		columns = append(columns, col)
 	}
 
@@ -159,7 +234,7 @@ func getColumns(ctx context.Context, pool *pgxpool.Pool, tableName string) ([]Ex
 	return columns, nil
 }
 
-func getForeignKeys(ctx context.Context, pool *pgxpool.Pool, tableName string) ([]ExistingForeignKey, error) {
+func getForeignKeys(ctx context.Context, pool Querier, schemaName, tableName string) ([]ExistingForeignKey, error) {
 	foreignKeysQuery := `
 	SELECT
 		tc.constraint_name,
@@ -177,12 +252,13 @@ func getForeignKeys(ctx context.Context, pool *pgxpool.Pool, tableName string) (
 		AND ccu.table_schema = tc.table_schema
 	LEFT JOIN information_schema.referential_constraints AS rc
 		ON tc.constraint_name = rc.constraint_name
+		AND tc.constraint_schema = rc.constraint_schema
 	WHERE tc.constraint_type = 'FOREIGN KEY' 
-		AND tc.table_schema = 'public'
-		AND tc.table_name = $1;
+		AND tc.table_schema = $1
+		AND tc.table_name = $2;
 	`
 
-	rows, err := pool.Query(ctx, foreignKeysQuery, tableName)
+	rows, err := pool.QueryContext(ctx, foreignKeysQuery, schemaName, tableName)
 	if err != nil {
 		return nil, fmt.Errorf("querying foreign keys: %v", err)
 	}
@@ -211,41 +287,34 @@ func getForeignKeys(ctx context.Context, pool *pgxpool.Pool, tableName string) (
 	return foreignKeys, nil
 }
 
-func getIndexes(ctx context.Context, pool *pgxpool.Pool, tableName string) ([]ExistingIndex, error) {
-	// Use a more reliable query that works across PostgreSQL versions
+func getIndexes(ctx context.Context, pool Querier, schemaName, tableName string) ([]ExistingIndex, error) {
+	// Read pg_index directly so that index names are resolved within the schema
 	indexesQuery := `
 	SELECT
-		i.indexname,
-		i.tablename,
+		ic.relname,
+		t.relname,
 		COALESCE(
-			(SELECT string_agg(a.attname, ',' ORDER BY array_position(idx.indkey, a.attnum))
-			 FROM pg_index idx
-			 JOIN pg_class c ON c.oid = idx.indexrelid
-			 JOIN pg_attribute a ON a.attrelid = idx.indrelid AND a.attnum = ANY(idx.indkey)
-			 WHERE c.relname = i.indexname), 
+			(SELECT string_agg(a.attname, ',' ORDER BY array_position(idx.indkey::int2[], a.attnum))
+			 FROM pg_attribute a
+			 WHERE a.attrelid = idx.indrelid AND a.attnum = ANY(idx.indkey::int2[])),
 			''
 		) as column_names,
-		COALESCE(
-			(SELECT idx.indisunique
-			 FROM pg_index idx
-			 JOIN pg_class c ON c.oid = idx.indexrelid
-			 WHERE c.relname = i.indexname),
-			false
-		) as is_unique,
-		COALESCE(
-			(SELECT am.amname
-			 FROM pg_index idx
-			 JOIN pg_class c ON c.oid = idx.indexrelid
-			 JOIN pg_am am ON am.oid = c.relam
-			 WHERE c.relname = i.indexname),
-			'btree'
-		) as index_type
-	FROM pg_indexes i
-	WHERE i.tablename = $1 AND i.schemaname = 'public'
-	ORDER BY i.indexname;
+		idx.indisunique as is_unique,
+		am.amname as index_type,
+		(idx.indisprimary OR EXISTS (
+			SELECT 1 FROM pg_constraint con
+			WHERE con.conindid = idx.indexrelid AND con.contype IN ('p', 'u')
+		)) as is_constraint
+	FROM pg_index idx
+	JOIN pg_class ic ON ic.oid = idx.indexrelid
+	JOIN pg_class t ON t.oid = idx.indrelid
+	JOIN pg_namespace n ON n.oid = t.relnamespace
+	JOIN pg_am am ON am.oid = ic.relam
+	WHERE n.nspname = $1 AND t.relname = $2
+	ORDER BY ic.relname;
 	`
 
-	rows, err := pool.Query(ctx, indexesQuery, tableName)
+	rows, err := pool.QueryContext(ctx, indexesQuery, schemaName, tableName)
 	if err != nil {
 		return nil, fmt.Errorf("querying indexes: %v", err)
 	}
@@ -261,10 +330,13 @@ func getIndexes(ctx context.Context, pool *pgxpool.Pool, tableName string) ([]Ex
 			&columnNames,
 			&idx.IsUnique,
 			&idx.IndexType,
+			&idx.Constraint,
 		); err != nil {
 			return nil, fmt.Errorf("scanning index: %v", err)
 		}
-		idx.Columns = extractColumnsFromIndexDef(columnNames)
+		if columnNames != "" {
+			idx.Columns = strings.Split(columnNames, ",")
+		}
 		indexes = append(indexes, idx)
 	}
 
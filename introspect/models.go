@@ -0,0 +1,183 @@
+package introspect
+
+import (
+	"strings"
+
+	"github.com/ridoystarlord/migrato/schema"
+)
+
+// ToModels converts introspected tables back into schema models, so that a
+// database schema can be rendered as migrations again. Indexes that only back
+// a primary key or single-column unique constraint are folded into the column
+// definitions; migrato's tracking tables are skipped.
+func ToModels(tables []ExistingTable) []schema.Model {
+	var models []schema.Model
+	for _, table := range tables {
+		if IsIgnoredTable(table.TableName) {
+			continue
+		}
+
+		model := schema.Model{TableName: table.TableName}
+
+		var primaryKey []string
+		for _, col := range table.Columns {
+			if col.IsPrimaryKey {
+				primaryKey = append(primaryKey, col.ColumnName)
+			}
+		}
+
+		// Single-column unique constraints become column flags; anything
+		// wider is kept as an index
+		uniqueColumns := map[string]bool{}
+		for _, idx := range table.Indexes {
+			if !idx.Constraint {
+				continue
+			}
+			if sameColumns(idx.Columns, primaryKey) {
+				continue
+			}
+			if idx.IsUnique && len(idx.Columns) == 1 {
+				uniqueColumns[idx.Columns[0]] = true
+				continue
+			}
+			model.Indexes = append(model.Indexes, toIndex(table.TableName, idx))
+		}
+
+		foreignKeys := map[string]ExistingForeignKey{}
+		for _, fk := range table.ForeignKeys {
+			foreignKeys[fk.ColumnName] = fk
+		}
+
+		for _, col := range table.Columns {
+			column := schema.Column{
+				Name:    col.ColumnName,
+				Type:    col.ColumnType,
+				Primary: col.IsPrimaryKey && len(primaryKey) == 1,
+				Unique:  uniqueColumns[col.ColumnName],
+				NotNull: !col.IsNullable && !col.IsPrimaryKey,
+			}
+			if column.Type == "" {
+				column.Type = col.DataType
+			}
+			// Composite primary keys cannot be expressed per column, so the
+			// columns stay NOT NULL and get a unique index instead
+			if col.IsPrimaryKey && len(primaryKey) > 1 {
+				column.NotNull = true
+			}
+
+			if col.ColumnDefault != nil {
+				value := *col.ColumnDefault
+				if strings.HasPrefix(strings.ToLower(value), "nextval(") {
+					column.Type = serialType(column.Type)
+				} else {
+					value = modelDefault(value)
+					column.Default = &value
+				}
+			}
+
+			if fk, ok := foreignKeys[col.ColumnName]; ok {
+				column.ForeignKey = &schema.ForeignKey{
+					ReferencesTable:  fk.ReferencesTable,
+					ReferencesColumn: fk.ReferencesColumn,
+					OnDelete:         foreignKeyAction(fk.OnDelete),
+					OnUpdate:         foreignKeyAction(fk.OnUpdate),
+				}
+			}
+
+			model.Columns = append(model.Columns, column)
+		}
+
+		if len(primaryKey) > 1 {
+			model.Indexes = append(model.Indexes, schema.Index{
+				Name:    table.TableName + "_pkey",
+				Table:   table.TableName,
+				Columns: primaryKey,
+				Unique:  true,
+			})
+		}
+
+		for _, idx := range table.Indexes {
+			if !idx.Constraint {
+				model.Indexes = append(model.Indexes, toIndex(table.TableName, idx))
+			}
+		}
+
+		models = append(models, model)
+	}
+	return models
+}
+
+func toIndex(tableName string, idx ExistingIndex) schema.Index {
+	index := schema.Index{
+		Name:    idx.IndexName,
+		Table:   tableName,
+		Columns: idx.Columns,
+		Unique:  idx.IsUnique,
+	}
+	if idx.IndexType != "btree" {
+		index.Type = idx.IndexType
+	}
+	return index
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) || len(a) == 0 {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// serialType maps the integer type behind a sequence default to its serial type
+func serialType(dataType string) string {
+	switch strings.ToLower(dataType) {
+	case "bigint", "int8":
+		return "bigserial"
+	case "smallint", "int2":
+		return "smallserial"
+	}
+	return "serial"
+}
+
+// modelDefault turns a default as reported by the catalog into the form used
+// in schema files: casts are dropped and CURRENT_TIMESTAMP is written as now()
+func modelDefault(value string) string {
+	value = strings.TrimSpace(value)
+
+	if strings.HasPrefix(value, "'") {
+		// Keep the quoted literal and drop a trailing cast such as ::character varying
+		for i := 1; i < len(value); i++ {
+			if value[i] != '\'' {
+				continue
+			}
+			if i+1 < len(value) && value[i+1] == '\'' {
+				i++
+				continue
+			}
+			return value[:i+1]
+		}
+		return value
+	}
+
+	if idx := strings.Index(value, "::"); idx != -1 {
+		value = value[:idx]
+	}
+
+	switch strings.ToLower(value) {
+	case "current_timestamp", "current_timestamp()":
+		return "now()"
+	}
+	return value
+}
+
+func foreignKeyAction(action string) string {
+	action = strings.ToUpper(strings.TrimSpace(action))
+	if action == "NO ACTION" {
+		return ""
+	}
+	return action
+}
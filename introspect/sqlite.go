@@ -0,0 +1,165 @@
+package introspect
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+func introspectSQLite(ctx context.Context, q Querier) ([]ExistingTable, error) {
+	rows, err := q.QueryContext(ctx, `
+	SELECT name
+	FROM sqlite_master
+	WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+	ORDER BY name;
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("querying tables: %v", err)
+	}
+	defer rows.Close()
+
+	var tableNames []string
+	for rows.Next() {
+		var tableName string
+		if err := rows.Scan(&tableName); err != nil {
+			return nil, fmt.Errorf("scanning table name: %v", err)
+		}
+		tableNames = append(tableNames, tableName)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("iterating table rows: %v", rows.Err())
+	}
+	rows.Close()
+
+	var tables []ExistingTable
+	for _, tableName := range tableNames {
+		indexes, uniqueColumns, err := getSQLiteIndexes(ctx, q, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("getting indexes for table %s: %v", tableName, err)
+		}
+
+		columns, err := getSQLiteColumns(ctx, q, tableName, uniqueColumns)
+		if err != nil {
+			return nil, fmt.Errorf("getting columns for table %s: %v", tableName, err)
+		}
+
+		foreignKeys, err := getSQLiteForeignKeys(ctx, q, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("getting foreign keys for table %s: %v", tableName, err)
+		}
+
+		tables = append(tables, ExistingTable{
+			TableName:   tableName,
+			Columns:     columns,
+			ForeignKeys: foreignKeys,
+			Indexes:     indexes,
+		})
+	}
+
+	return tables, nil
+}
+
+func getSQLiteColumns(ctx context.Context, q Querier, tableName string, uniqueColumns map[string]bool) ([]ExistingColumn, error) {
+	rows, err := q.QueryContext(ctx, `SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info($1);`, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("querying columns: %v", err)
+	}
+	defer rows.Close()
+
+	var columns []ExistingColumn
+	for rows.Next() {
+		var col ExistingColumn
+		var notNull, pk int
+		var columnDefault sql.NullString
+		if err := rows.Scan(&col.ColumnName, &col.DataType, &notNull, &columnDefault, &pk); err != nil {
+			return nil, fmt.Errorf("scanning column: %v", err)
+		}
+		col.DataType = strings.ToLower(col.DataType)
+		col.ColumnType = col.DataType
+		col.IsPrimaryKey = pk > 0
+		// INTEGER PRIMARY KEY columns are rowid aliases and never NULL
+		col.IsNullable = notNull == 0 && !col.IsPrimaryKey
+		col.IsUnique = uniqueColumns[col.ColumnName]
+		if columnDefault.Valid {
+			col.ColumnDefault = &columnDefault.String
+		}
+		columns = append(columns, col)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("iterating column rows: %v", rows.Err())
+	}
+
+	return columns, nil
+}
+
+// getSQLiteForeignKeys reads foreign keys. SQLite does not keep constraint
+// names, so they are reported under the name migrato generates for them.
+func getSQLiteForeignKeys(ctx context.Context, q Querier, tableName string) ([]ExistingForeignKey, error) {
+	rows, err := q.QueryContext(ctx, `SELECT "table", "from", COALESCE("to", ''), on_update, on_delete FROM pragma_foreign_key_list($1);`, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("querying foreign keys: %v", err)
+	}
+	defer rows.Close()
+
+	var foreignKeys []ExistingForeignKey
+	for rows.Next() {
+		var fk ExistingForeignKey
+		if err := rows.Scan(&fk.ReferencesTable, &fk.ColumnName, &fk.ReferencesColumn, &fk.OnUpdate, &fk.OnDelete); err != nil {
+			return nil, fmt.Errorf("scanning foreign key: %v", err)
+		}
+		fk.ConstraintName = fmt.Sprintf("fk_%s_%s", tableName, fk.ReferencesTable)
+		foreignKeys = append(foreignKeys, fk)
+	}
+
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("iterating foreign key rows: %v", rows.Err())
+	}
+
+	return foreignKeys, nil
+}
+
+// getSQLiteIndexes reads the indexes of a table and also reports which
+// columns are covered by a single-column unique constraint
+func getSQLiteIndexes(ctx context.Context, q Querier, tableName string) ([]ExistingIndex, map[string]bool, error) {
+	rows, err := q.QueryContext(ctx, `
+	SELECT il.name, il."unique", il.origin, COALESCE(group_concat(ii.name, ','), '')
+	FROM pragma_index_list($1) AS il
+	JOIN pragma_index_info(il.name) AS ii
+	GROUP BY il.name, il."unique", il.origin
+	ORDER BY il.name;
+	`, tableName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("querying indexes: %v", err)
+	}
+	defer rows.Close()
+
+	var indexes []ExistingIndex
+	uniqueColumns := map[string]bool{}
+	for rows.Next() {
+		var idx ExistingIndex
+		var unique int
+		var origin, columnNames string
+		if err := rows.Scan(&idx.IndexName, &unique, &origin, &columnNames); err != nil {
+			return nil, nil, fmt.Errorf("scanning index: %v", err)
+		}
+		idx.TableName = tableName
+		idx.IsUnique = unique == 1
+		idx.IndexType = "btree"
+		// origin is c for CREATE INDEX, u for UNIQUE and pk for PRIMARY KEY constraints
+		idx.Constraint = origin != "c"
+		idx.Columns = strings.Split(columnNames, ",")
+		if origin == "u" && len(idx.Columns) == 1 {
+			uniqueColumns[idx.Columns[0]] = true
+		}
+		indexes = append(indexes, idx)
+	}
+
+	if rows.Err() != nil {
+		return nil, nil, fmt.Errorf("iterating index rows: %v", rows.Err())
+	}
+
+	return indexes, uniqueColumns, nil
+}
@@ -0,0 +1,257 @@
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"github.com/ridoystarlord/migrato/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// ModelsToYAML renders models as a schema file that LoadModelsFromYAML reads back
+func ModelsToYAML(models []schema.Model) ([]byte, error) {
+	var yf yamlFile
+	for _, m := range models {
+		table := yamlTable{Name: m.TableName}
+		for _, c := range m.Columns {
+			column := yamlColumn{
+				Name:    c.Name,
+				Type:    c.Type,
+				Primary: c.Primary,
+				Unique:  c.Unique,
+				NotNull: c.NotNull,
+				Default: plainDefault(c.Default),
+			}
+			if c.ForeignKey != nil {
+				column.ForeignKey = &yamlForeignKey{
+					ReferencesTable:  c.ForeignKey.ReferencesTable,
+					ReferencesColumn: c.ForeignKey.ReferencesColumn,
+					OnDelete:         c.ForeignKey.OnDelete,
+					OnUpdate:         c.ForeignKey.OnUpdate,
+				}
+			}
+			table.Columns = append(table.Columns, column)
+		}
+		for _, idx := range m.Indexes {
+			table.Indexes = append(table.Indexes, yamlIndex{
+				Name:    idx.Name,
+				Columns: idx.Columns,
+				Unique:  idx.Unique,
+				Type:    idx.Type,
+			})
+		}
+		yf.Tables = append(yf.Tables, table)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Schema generated by migrato baseline from the existing database\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(yf); err != nil {
+		return nil, fmt.Errorf("marshalling YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("marshalling YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ModelsToStructs renders models as a Go file of structs with migrato tags for
+// the models directory dir. Struct tags cannot express multi-column indexes;
+// those are returned as warnings.
+func ModelsToStructs(dir string, models []schema.Model) ([]byte, []string, error) {
+	tl := NewTagLoader(dir)
+	var warnings []string
+
+	packageName := filepath.Base(filepath.Clean(dir))
+	if !token.IsIdentifier(packageName) {
+		packageName = "models"
+	}
+
+	var body bytes.Buffer
+	usesTime := false
+	for _, m := range models {
+		structName, err := tl.structName(m.TableName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Single-column indexes move into the column's tag
+		columnIndexes := map[string]schema.Index{}
+		for _, idx := range m.Indexes {
+			if len(idx.Columns) == 1 {
+				columnIndexes[idx.Columns[0]] = idx
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf("index %s on %s spans several columns and cannot be expressed in struct tags", idx.Name, m.TableName))
+		}
+
+		fmt.Fprintf(&body, "\n// %s maps the %s table\n", structName, m.TableName)
+		fmt.Fprintf(&body, "type %s struct {\n", structName)
+		for _, c := range m.Columns {
+			fieldName := fieldName(c.Name)
+			goType := goType(c)
+			if strings.Contains(goType, "time.Time") {
+				usesTime = true
+			}
+
+			var tag []string
+			if tl.toSnakeCase(fieldName) != c.Name {
+				tag = append(tag, "column:"+c.Name)
+			}
+			if c.Primary {
+				tag = append(tag, "primary")
+			}
+			tag = append(tag, "type:"+c.Type)
+			if c.Unique {
+				tag = append(tag, "unique")
+			}
+			if c.NotNull {
+				tag = append(tag, "not_null")
+			}
+			if value := plainDefault(c.Default); value != nil {
+				tag = append(tag, "default:"+*value)
+			}
+			if fk := c.ForeignKey; fk != nil {
+				spec := fk.ReferencesTable + "." + fk.ReferencesColumn
+				if fk.OnDelete != "" || fk.OnUpdate != "" {
+					spec += ":" + fk.OnDelete
+				}
+				if fk.OnUpdate != "" {
+					spec += ":" + fk.OnUpdate
+				}
+				tag = append(tag, "fk:"+spec)
+			}
+			if idx, ok := columnIndexes[c.Name]; ok {
+				spec := idx.Name + ":" + idx.Type
+				if idx.Type == "" {
+					spec = idx.Name + ":btree"
+				}
+				if idx.Unique {
+					spec += ":unique"
+				}
+				tag = append(tag, "index:"+spec)
+			}
+
+			fmt.Fprintf(&body, "\t%s %s `migrato:%q`\n", fieldName, goType, strings.Join(tag, ";"))
+		}
+		body.WriteString("}\n")
+	}
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by migrato baseline from the existing database. Edit freely.\n\n")
+	fmt.Fprintf(&src, "package %s\n", packageName)
+	if usesTime {
+		src.WriteString("\nimport \"time\"\n")
+	}
+	src.Write(body.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, nil, fmt.Errorf("formatting structs: %v", err)
+	}
+	return formatted, warnings, nil
+}
+
+// structName finds a struct name that getTableName maps back to the table
+func (tl *TagLoader) structName(tableName string) (string, error) {
+	var candidates []string
+	switch {
+	case strings.HasSuffix(tableName, "ies"):
+		candidates = append(candidates, strings.TrimSuffix(tableName, "ies")+"y")
+	case strings.HasSuffix(tableName, "s"):
+		candidates = append(candidates, strings.TrimSuffix(tableName, "s"))
+	}
+	candidates = append(candidates, tableName)
+
+	for _, candidate := range candidates {
+		name := fieldName(candidate)
+		if tl.getTableName(name) == tableName {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("table %s has no struct name that maps back to it; use --yaml to baseline this database", tableName)
+}
+
+// commonInitialisms are written in upper case in field names
+var commonInitialisms = map[string]bool{
+	"id": true, "url": true, "uuid": true, "api": true, "ip": true,
+	"json": true, "html": true, "http": true, "sql": true,
+}
+
+// fieldName converts a snake_case column name to an exported Go identifier
+func fieldName(column string) string {
+	var name string
+	for _, part := range strings.Split(column, "_") {
+		if part == "" {
+			continue
+		}
+		if commonInitialisms[strings.ToLower(part)] {
+			name += strings.ToUpper(part)
+			continue
+		}
+		name += strings.ToUpper(part[:1]) + part[1:]
+	}
+	if name == "" || !token.IsIdentifier(name) || !token.IsExported(name) {
+		name = "Col" + name
+	}
+	return name
+}
+
+// goType picks the Go type of a field from the column's database type
+func goType(c schema.Column) string {
+	base := strings.ToLower(strings.TrimSpace(c.Type))
+	if strings.HasSuffix(base, "[]") {
+		return "string"
+	}
+	if i := strings.Index(base, "("); i != -1 {
+		base = strings.TrimSpace(base[:i])
+	}
+
+	var t string
+	switch {
+	case base == "integer" || base == "int" || base == "int4" || base == "serial":
+		t = "int"
+	case base == "bigint" || base == "int8" || base == "bigserial":
+		t = "int64"
+	case base == "smallint" || base == "int2" || base == "smallserial":
+		t = "int16"
+	case base == "boolean" || base == "bool":
+		t = "bool"
+	case base == "numeric" || base == "decimal" || base == "real" || base == "double precision" || base == "float4" || base == "float8":
+		t = "float64"
+	case strings.HasPrefix(base, "timestamp") || base == "date" || strings.HasPrefix(base, "time"):
+		t = "time.Time"
+	case base == "bytea" || base == "blob":
+		return "[]byte"
+	default:
+		t = "string"
+	}
+
+	if !c.NotNull && !c.Primary {
+		return "*" + t
+	}
+	return t
+}
+
+// plainDefault unquotes simple string literals, which the generator quotes
+// again, so schema files read the way they are written by hand
+func plainDefault(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	v := *value
+	if len(v) < 2 || !strings.HasPrefix(v, "'") || !strings.HasSuffix(v, "'") {
+		return value
+	}
+	inner := v[1 : len(v)-1]
+	if inner == "" || inner == "true" || inner == "false" || strings.ContainsAny(inner, "'();") ||
+		!strings.ContainsAny(strings.ToLower(inner), "abcdefghijklmnopqrstuvwxyz") {
+		return value
+	}
+	return &inner
+}
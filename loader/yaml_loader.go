@@ -22,10 +22,10 @@ type yamlTable struct {
 type yamlColumn struct {
 	Name        string         `yaml:"name"`
 	Type        string         `yaml:"type"`
-	Primary     bool           `yaml:"primary"`
-	Unique      bool           `yaml:"unique"`
-	NotNull     bool           `yaml:"not_null"`
-	Default     *string        `yaml:"default"`
+	Primary     bool           `yaml:"primary,omitempty"`
+	Unique      bool           `yaml:"unique,omitempty"`
+	NotNull     bool           `yaml:"not_null,omitempty"`
+	Default     *string        `yaml:"default,omitempty"`
 	ForeignKey  *yamlForeignKey `yaml:"foreign_key,omitempty"`
 	Index       interface{}    `yaml:"index,omitempty"`
 }
@@ -0,0 +1,188 @@
+// Package migrator embeds migrato in Go programs. It applies migration files
+// from any fs.FS, such as an embed.FS, to a database handle owned by the
+// caller, and returns structured results instead of printing.
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	m, err := migrator.NewFromPool(pool, migrations, migrator.Options{Dir: "migrations"})
+//	results, err := m.Up(ctx)
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/ridoystarlord/migrato/dialect"
+	"github.com/ridoystarlord/migrato/runner"
+)
+
+// Result describes one migration handled by a run
+type Result = runner.Result
+
+// Status summarizes applied, pending and failed migrations
+type Status = runner.StatusReport
+
+// ChecksumIssue describes an applied migration that no longer matches its file
+type ChecksumIssue = runner.ChecksumIssue
+
+// MigrationRecord is a row of schema_migrations or an apply or rollback
+// event of the history
+type MigrationRecord = runner.MigrationRecord
+
+// Tables names the tracking tables
+type Tables = runner.Tables
+
+// Plan is a reviewed set of pending migrations; see Migrator.Plan
+type Plan = runner.Plan
+
+// Errors returned before any migration runs; use errors.As to inspect them
+type (
+	FailedMigrationsError = runner.FailedMigrationsError
+	ChecksumError         = runner.ChecksumError
+	OutOfOrderError       = runner.OutOfOrderError
+	PlanMismatchError     = runner.PlanMismatchError
+	MetadataTooNewError   = runner.MetadataTooNewError
+)
+
+// Result statuses
+const (
+	Applied    = runner.ResultApplied
+	Recorded   = runner.ResultRecorded
+	RolledBack = runner.ResultRolledBack
+	Failed     = runner.ResultFailed
+)
+
+// Options configures a Migrator
+type Options struct {
+	// Dir is the directory of fsys holding the migrations; defaults to the root
+	Dir string
+	// Dialect is "postgres" or "sqlite". It is detected for pgx handles and
+	// must be set otherwise.
+	Dialect string
+	// LockKey is the PostgreSQL advisory lock key serializing runs
+	LockKey int64
+	// LockWait is how long to wait for another run to release the lock
+	LockWait time.Duration
+	// AllowOutOfOrder applies pending migrations sorting before the latest applied one
+	AllowOutOfOrder bool
+	// LockTimeout and StatementTimeout set the PostgreSQL lock_timeout and
+	// statement_timeout of each migration; zero leaves them unset
+	LockTimeout      time.Duration
+	StatementTimeout time.Duration
+	// Retries is how many times a transactional migration failing with a lock
+	// timeout, serialization failure or deadlock is run again
+	Retries int
+	// Tables overrides the names of the tracking tables
+	Tables Tables
+	// Log receives the progress messages the CLI prints; nil discards them
+	Log io.Writer
+}
+
+// Migrator applies migrations to one database
+type Migrator struct {
+	runner *runner.Runner
+}
+
+// New returns a Migrator applying the migrations in fsys to db. The caller
+// keeps ownership of db.
+func New(db *sql.DB, fsys fs.FS, opts Options) (*Migrator, error) {
+	if db == nil {
+		return nil, fmt.Errorf("migrator: nil database handle")
+	}
+
+	d, err := detectDialect(db, opts.Dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Dir != "" && opts.Dir != "." {
+		fsys, err = fs.Sub(fsys, opts.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("migrator: migrations dir %s: %v", opts.Dir, err)
+		}
+	}
+
+	return &Migrator{runner: runner.New(db, d, fsys, runner.Options{
+		LockKey:          opts.LockKey,
+		LockWait:         opts.LockWait,
+		AllowOutOfOrder:  opts.AllowOutOfOrder,
+		LockTimeout:      opts.LockTimeout,
+		StatementTimeout: opts.StatementTimeout,
+		Retries:          opts.Retries,
+		Tables:           opts.Tables,
+		Output:           opts.Log,
+	})}, nil
+}
+
+// NewFromPool returns a Migrator using a pgx connection pool. The caller
+// keeps ownership of pool.
+func NewFromPool(pool *pgxpool.Pool, fsys fs.FS, opts Options) (*Migrator, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("migrator: nil pool")
+	}
+	if opts.Dialect == "" {
+		opts.Dialect = dialect.Postgres.Name()
+	}
+	return New(stdlib.OpenDBFromPool(pool), fsys, opts)
+}
+
+func detectDialect(db *sql.DB, name string) (dialect.Dialect, error) {
+	if name != "" {
+		return dialect.Get(name)
+	}
+	if _, ok := db.Driver().(*stdlib.Driver); ok {
+		return dialect.Postgres, nil
+	}
+	return nil, fmt.Errorf("migrator: cannot detect the dialect of %T, set Options.Dialect", db.Driver())
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up(ctx context.Context) ([]Result, error) {
+	return m.runner.Migrate(ctx, "")
+}
+
+// UpTo applies the pending migrations up to and including version
+func (m *Migrator) UpTo(ctx context.Context, version string) ([]Result, error) {
+	return m.runner.Migrate(ctx, version)
+}
+
+// Down rolls back the given number of most recently applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Result, error) {
+	return m.runner.Rollback(ctx, steps)
+}
+
+// DownTo rolls back every migration newer than version; "0" rolls back all
+func (m *Migrator) DownTo(ctx context.Context, version string) ([]Result, error) {
+	return m.runner.RollbackTo(ctx, version)
+}
+
+// Status reports applied, pending, failed and out-of-order migrations,
+// checksum mismatches and the history of events
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	return m.runner.Status(ctx)
+}
+
+// History returns the apply and rollback events, newest first; limit caps
+// their number, zero returns all of them
+func (m *Migrator) History(ctx context.Context, limit int) ([]MigrationRecord, error) {
+	return m.runner.History(ctx, limit)
+}
+
+// Plan records the pending migrations with the applied set and a schema
+// fingerprint, for ApplyPlan to run after review
+func (m *Migrator) Plan(ctx context.Context) (*Plan, error) {
+	return m.runner.Plan(ctx, "")
+}
+
+// ApplyPlan applies a plan, returning a PlanMismatchError when the database
+// or the migrations changed since it was created
+func (m *Migrator) ApplyPlan(ctx context.Context, plan *Plan) ([]Result, error) {
+	return m.runner.ApplyPlan(ctx, plan)
+}
@@ -0,0 +1,228 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ridoystarlord/migrato/dialect"
+)
+
+// testDB opens an empty SQLite database in a temporary directory
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open(dialect.SQLite.DriverName(), dialect.SQLite.DataSourceName("sqlite://"+path))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// migrationFile renders a migration creating and dropping one table
+func migrationFile(version, table string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(fmt.Sprintf(
+		"-- Migration: %s\n\n-- Up Migration\nCREATE TABLE %s (id INTEGER PRIMARY KEY);\n\n-- Down Migration (Rollback)\nDROP TABLE %s;\n",
+		version, table, table))}
+}
+
+// embedded lays the migrations out as an embed.FS of a migrations directory would
+func embedded() fstest.MapFS {
+	return fstest.MapFS{
+		"migrations/20240101000000_users.sql": migrationFile("20240101000000", "users"),
+		"migrations/20240101000001_posts.sql": migrationFile("20240101000001", "posts"),
+		"migrations/20240101000002_tags.sql":  migrationFile("20240101000002", "tags"),
+	}
+}
+
+// summary lists the filename and status of each result
+func summary(results []Result) []string {
+	var s []string
+	for _, r := range results {
+		s = append(s, r.Filename+" "+r.Status)
+	}
+	return s
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		db      *sql.DB
+		opts    Options
+		wantErr string
+	}{
+		{name: "sqlite", db: testDB(t), opts: Options{Dialect: "sqlite", Dir: "migrations"}},
+		{name: "nil handle", opts: Options{Dialect: "sqlite"}, wantErr: "nil database handle"},
+		{name: "undetectable dialect", db: testDB(t), wantErr: "set Options.Dialect"},
+		{name: "unknown dialect", db: testDB(t), opts: Options{Dialect: "oracle"}, wantErr: "oracle"},
+		{name: "missing dir", db: testDB(t), opts: Options{Dialect: "sqlite", Dir: "../migrations"}, wantErr: "migrations dir ../migrations"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.db, embedded(), tt.opts)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("New: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("New error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	m, err := New(testDB(t), embedded(), Options{Dialect: "sqlite", Dir: "migrations"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name string
+		run  func() ([]Result, error)
+		want []string
+	}{
+		{"up to", func() ([]Result, error) { return m.UpTo(ctx, "20240101000001") }, []string{
+			"20240101000000_users.sql " + Applied,
+			"20240101000001_posts.sql " + Applied,
+		}},
+		{"up", func() ([]Result, error) { return m.Up(ctx) }, []string{
+			"20240101000002_tags.sql " + Applied,
+		}},
+		{"up with nothing pending", func() ([]Result, error) { return m.Up(ctx) }, nil},
+		{"down", func() ([]Result, error) { return m.Down(ctx, 1) }, []string{
+			"20240101000002_tags.sql " + RolledBack,
+		}},
+		{"down to", func() ([]Result, error) { return m.DownTo(ctx, "0") }, []string{
+			"20240101000001_posts.sql " + RolledBack,
+			"20240101000000_users.sql " + RolledBack,
+		}},
+	}
+	for _, step := range steps {
+		results, err := step.run()
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := summary(results); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s = %q, want %q", step.name, got, step.want)
+		}
+	}
+
+	history, err := m.History(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].MigrationName != "20240101000000_users.sql" {
+		t.Errorf("History(2) = %+v, want the two latest events, newest first", history)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	m, err := New(testDB(t), embedded(), Options{Dialect: "sqlite", Dir: "migrations"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.UpTo(ctx, "20240101000000"); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"20240101000000_users.sql"}; !reflect.DeepEqual(status.Applied, want) {
+		t.Errorf("applied = %q, want %q", status.Applied, want)
+	}
+	if want := []string{"20240101000001_posts.sql", "20240101000002_tags.sql"}; !reflect.DeepEqual(status.Pending, want) {
+		t.Errorf("pending = %q, want %q", status.Pending, want)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	ctx := context.Background()
+
+	// Only migrations outside a transaction leave a failed row behind
+	t.Run("failed migration", func(t *testing.T) {
+		fsys := fstest.MapFS{"20240101000000_bad.sql": &fstest.MapFile{Data: []byte("-- migrato:no-transaction\n-- Up Migration\nCREATE TABLE;\n-- Down Migration (Rollback)\n")}}
+		m, err := New(testDB(t), fsys, Options{Dialect: "sqlite"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := m.Up(ctx)
+		if err == nil || len(results) != 1 || results[0].Status != Failed || results[0].Err == nil {
+			t.Fatalf("Up = %+v, %v; want one failed result", results, err)
+		}
+
+		_, err = m.Up(ctx)
+		var failed *FailedMigrationsError
+		if !errors.As(err, &failed) || len(failed.Migrations) != 1 {
+			t.Fatalf("second Up error = %v, want a FailedMigrationsError", err)
+		}
+	})
+
+	t.Run("edited migration", func(t *testing.T) {
+		fsys := embedded()
+		m, err := New(testDB(t), fsys, Options{Dialect: "sqlite", Dir: "migrations"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.UpTo(ctx, "20240101000000"); err != nil {
+			t.Fatal(err)
+		}
+		fsys["migrations/20240101000000_users.sql"] = migrationFile("20240101000000", "members")
+
+		_, err = m.Up(ctx)
+		var checksum *ChecksumError
+		if !errors.As(err, &checksum) || len(checksum.Issues) != 1 {
+			t.Fatalf("Up error = %v, want a ChecksumError", err)
+		}
+	})
+}
+
+func TestOptions(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	var log bytes.Buffer
+	m, err := New(db, embedded(), Options{
+		Dialect: "sqlite",
+		Dir:     "migrations",
+		Tables:  Tables{Migrations: "app_migrations", Logs: "app_logs", History: "app_history"},
+		Log:     &log,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var applied int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM app_migrations;`).Scan(&applied); err != nil {
+		t.Fatalf("read the configured migrations table: %v", err)
+	}
+	if applied != 3 {
+		t.Errorf("app_migrations has %d rows, want 3", applied)
+	}
+	var defaults int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_migrations';`).Scan(&defaults); err != nil {
+		t.Fatal(err)
+	}
+	if defaults != 0 {
+		t.Error("the default migrations table was created as well")
+	}
+	if !strings.Contains(log.String(), "20240101000002_tags.sql") {
+		t.Errorf("Log received %q, want the progress messages", log.String())
+	}
+}
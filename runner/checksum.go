@@ -0,0 +1,254 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ChecksumIssue describes an applied migration that no longer matches its file
+type ChecksumIssue struct {
+	Filename  string
+	Kind      string // "changed", "missing" or "renamed"
+	RenamedTo string // for "renamed", the file now holding the recorded content
+	Recorded  string
+	Current   string // checksum of the file on disk, for "changed"
+}
+
+func (i ChecksumIssue) String() string {
+	switch i.Kind {
+	case "changed":
+		return fmt.Sprintf("changed: %s (recorded %s, file %s)", i.Filename, shortChecksum(i.Recorded), shortChecksum(i.Current))
+	case "renamed":
+		return fmt.Sprintf("renamed: %s -> %s", i.Filename, i.RenamedTo)
+	}
+	return fmt.Sprintf("missing: %s", i.Filename)
+}
+
+// ChecksumError is returned when applied migrations no longer match their files
+type ChecksumError struct {
+	Issues []ChecksumIssue
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum verification failed for %d migration(s)", len(e.Issues))
+}
+
+func shortChecksum(checksum string) string {
+	if len(checksum) > 12 {
+		return checksum[:12]
+	}
+	return checksum
+}
+
+// fileChecksum returns the checksum of a migration's up section, as recorded
+// in schema_migrations when it is applied
+func (r *Runner) fileChecksum(filename string) (string, error) {
+	upSQL, _, err := r.parseMigrationFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return calculateChecksum(upSQL), nil
+}
+
+// verifyChecksums compares the checksums recorded for applied migrations with
+// the files on disk. A missing file whose recorded checksum matches an
+// unapplied file is reported as renamed.
+func (r *Runner) verifyChecksums(conn *sql.Conn, ctx context.Context) ([]ChecksumIssue, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT filename, checksum FROM %s WHERE status = 'success';`, r.tables.Migrations))
+	if err != nil {
+		return nil, fmt.Errorf("query applied migrations: %v", err)
+	}
+	recorded := map[string]string{}
+	for rows.Next() {
+		var filename string
+		var checksum sql.NullString
+		if err := rows.Scan(&filename, &checksum); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan migration: %v", err)
+		}
+		recorded[filename] = checksum.String
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Unapplied files, by checksum, are the candidates for renames
+	unapplied := map[string]string{}
+	files, err := r.migrationFiles()
+	if err != nil {
+		return nil, err
+	}
+	squashed, _ := fs.Glob(r.fsys, path.Join(squashedFolder, "*.sql"))
+	for _, p := range squashed {
+		files = append(files, path.Base(p))
+	}
+	for _, f := range files {
+		if _, ok := recorded[f]; ok {
+			continue
+		}
+		checksum, err := r.fileChecksum(f)
+		if err != nil {
+			return nil, err
+		}
+		if _, taken := unapplied[checksum]; !taken {
+			unapplied[checksum] = f
+		}
+	}
+
+	var issues []ChecksumIssue
+	for filename, checksum := range recorded {
+		// Rows recorded before checksums were stored cannot be verified
+		if checksum == "" {
+			continue
+		}
+
+		if !r.migrationFileExists(filename) {
+			issue := ChecksumIssue{Filename: filename, Kind: "missing", Recorded: checksum}
+			if renamed, ok := unapplied[checksum]; ok {
+				issue.Kind = "renamed"
+				issue.RenamedTo = renamed
+			}
+			issues = append(issues, issue)
+			continue
+		}
+
+		current, err := r.fileChecksum(filename)
+		if err != nil {
+			return nil, err
+		}
+		if current != checksum {
+			issues = append(issues, ChecksumIssue{Filename: filename, Kind: "changed", Recorded: checksum, Current: current})
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Filename < issues[j].Filename })
+	return issues, nil
+}
+
+// VerifyChecksums reports applied migrations whose files were changed, removed or renamed
+func VerifyChecksums(ctx context.Context) ([]ChecksumIssue, error) {
+	r, err := cliRunner()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := r.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return r.verifyChecksums(conn, ctx)
+}
+
+// PrintChecksumIssues prints a checksum verification report
+func PrintChecksumIssues(issues []ChecksumIssue) {
+	printChecksumIssues(os.Stdout, issues)
+}
+
+func printChecksumIssues(w io.Writer, issues []ChecksumIssue) {
+	fmt.Fprintln(w, "❌ Applied migrations no longer match their files:")
+	for _, issue := range issues {
+		fmt.Fprintf(w, "   - %s\n", issue)
+	}
+	fmt.Fprintln(w, "💡 Restore the original files, or run 'migrato repair' to accept intentional changes.")
+}
+
+// RepairChecksums accepts the current migration files: changed files get their
+// checksum re-recorded and renamed files their new name. Rows of missing files
+// are deleted only when dropMissing is set.
+func RepairChecksums(ctx context.Context, dropMissing, dryRun bool) error {
+	r, err := cliRunner()
+	if err != nil {
+		return err
+	}
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	unlock, err := r.acquireLock(conn, ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := r.ensureMigrationsTable(conn, ctx); err != nil {
+		return fmt.Errorf("ensure migrations table: %v", err)
+	}
+
+	issues, err := r.verifyChecksums(conn, ctx)
+	if err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		fmt.Println("✅ All applied migrations match their files. Nothing to repair.")
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var repaired, skipped []string
+	for _, issue := range issues {
+		var query string
+		var args []interface{}
+		switch {
+		case issue.Kind == "changed":
+			query = fmt.Sprintf(`UPDATE %s SET checksum = $1 WHERE filename = $2 AND status = 'success';`, r.tables.Migrations)
+			args = []interface{}{issue.Current, issue.Filename}
+		case issue.Kind == "renamed":
+			query = fmt.Sprintf(`UPDATE %s SET filename = $1 WHERE filename = $2;`, r.tables.Migrations)
+			args = []interface{}{issue.RenamedTo, issue.Filename}
+		case dropMissing:
+			query = fmt.Sprintf(`DELETE FROM %s WHERE filename = $1;`, r.tables.Migrations)
+			args = []interface{}{issue.Filename}
+		default:
+			skipped = append(skipped, issue.Filename)
+			continue
+		}
+
+		if dryRun {
+			fmt.Printf("🔧 Would repair %s\n", issue)
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("repairing %s: %v", issue.Filename, err)
+		}
+		fmt.Printf("🔧 Repaired %s\n", issue)
+		repaired = append(repaired, issue.String())
+	}
+
+	if len(skipped) > 0 {
+		fmt.Println("⚠️  These applied migrations have no file and were left as they are:")
+		for _, f := range skipped {
+			fmt.Printf("   - %s\n", f)
+		}
+		fmt.Println("💡 Restore the files, or rerun with --drop-missing to forget them.")
+	}
+
+	if dryRun {
+		fmt.Printf("(Dry run only. %s was not changed.)\n", r.tables.Migrations)
+		return nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing repair: %v", err)
+	}
+	if len(repaired) > 0 {
+		r.logMigrationActivity(conn, ctx, "WARN", fmt.Sprintf("Repaired %d migration record(s)", len(repaired)), "", strings.Join(repaired, "; "))
+		fmt.Printf("✅ Repaired %d migration record(s)\n", len(repaired))
+	}
+	return nil
+}
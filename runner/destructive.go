@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DestructiveStatement is a statement of a pending migration that can lose data
+type DestructiveStatement struct {
+	Filename string
+	Line     int
+	SQL      string
+}
+
+func (s DestructiveStatement) String() string {
+	firstLine := strings.SplitN(s.SQL, "\n", 2)[0]
+	return fmt.Sprintf("%s:%d: %s", s.Filename, s.Line, firstLine)
+}
+
+// destructivePatterns match statements that drop or rewrite existing data
+var destructivePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?is)^DROP\s+(TABLE|SCHEMA|DATABASE)\b`),
+	regexp.MustCompile(`(?is)^TRUNCATE\b`),
+	regexp.MustCompile(`(?is)^DELETE\s+FROM\b`),
+	regexp.MustCompile(`(?is)\bDROP\s+COLUMN\b`),
+	regexp.MustCompile(`(?is)\bALTER\s+COLUMN\s+\S+\s+(SET\s+DATA\s+)?TYPE\b`),
+}
+
+// isDestructive reports whether a statement can lose data
+func isDestructive(sql string) bool {
+	// Leading comments would hide the statement from the anchored patterns
+	var lines []string
+	for _, line := range strings.Split(sql, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+	sql = strings.TrimSpace(strings.Join(lines, "\n"))
+
+	for _, pattern := range destructivePatterns {
+		if pattern.MatchString(sql) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfirmDestructive, when set, confirms the destructive statements of a
+// migrate run. The CLI sets it on protected environments.
+var ConfirmDestructive func(destructive []DestructiveStatement) (*Confirmation, error)
+
+// destructiveStatements lists the destructive statements of the pending files
+func (r *Runner) destructiveStatements(pending []string) ([]DestructiveStatement, error) {
+	var destructive []DestructiveStatement
+	for _, f := range pending {
+		sections, err := r.readMigration(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, stmt := range splitStatements(sections.Up, sections.UpLine) {
+			if isDestructive(stmt.SQL) {
+				destructive = append(destructive, DestructiveStatement{Filename: f, Line: stmt.Line, SQL: stmt.SQL})
+			}
+		}
+	}
+	return destructive, nil
+}
+
+// confirmDestructive asks Options.ConfirmDestructive to confirm the
+// destructive statements of the pending migrations and logs the
+// confirmation. It runs under the migration lock, so the migrations
+// confirmed are the ones applied.
+func (r *Runner) confirmDestructive(conn *sql.Conn, ctx context.Context, pending []string) error {
+	if r.opts.ConfirmDestructive == nil {
+		return nil
+	}
+	destructive, err := r.destructiveStatements(pending)
+	if err != nil {
+		return fmt.Errorf("check pending migrations: %v", err)
+	}
+	if len(destructive) == 0 {
+		return nil
+	}
+	confirmation, err := r.opts.ConfirmDestructive(destructive)
+	if err != nil || confirmation == nil {
+		return err
+	}
+	return r.recordConfirmation(conn, ctx, *confirmation)
+}
+
+// Confirmation is a confirmed action against a protected environment
+type Confirmation struct {
+	Env     string
+	Action  string
+	Details string // where the run comes from
+}
+
+// recordConfirmation writes a confirmation to the runner's migration_logs
+func (r *Runner) recordConfirmation(conn *sql.Conn, ctx context.Context, c Confirmation) error {
+	message := fmt.Sprintf("Confirmed %s on %s", c.Action, c.Env)
+	if err := r.logMigrationActivity(conn, ctx, "WARN", message, "", c.Details); err != nil {
+		return fmt.Errorf("record confirmation: %v", err)
+	}
+	return nil
+}
+
+// RecordConfirmation writes the confirmation of an action against a
+// protected environment to migration_logs
+func RecordConfirmation(ctx context.Context, env, action, details string) error {
+	r, err := cliRunner()
+	if err != nil {
+		return err
+	}
+	conn, err := r.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return r.recordConfirmation(conn, ctx, Confirmation{Env: env, Action: action, Details: details})
+}
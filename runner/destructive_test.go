@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestIsDestructive(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"DROP TABLE users;", true},
+		{"-- no longer needed\nDROP TABLE users;", true},
+		{"TRUNCATE sessions;", true},
+		{"DELETE FROM sessions WHERE expired;", true},
+		{"ALTER TABLE users DROP COLUMN age;", true},
+		{"ALTER TABLE users ALTER COLUMN age TYPE bigint;", true},
+		{"ALTER TABLE users ALTER COLUMN age SET DATA TYPE bigint;", true},
+		{"DROP INDEX idx_users_email;", false},
+		{"CREATE TABLE users (id INTEGER PRIMARY KEY);", false},
+		{"ALTER TABLE users ADD COLUMN age INTEGER;", false},
+		{"ALTER TABLE users ALTER COLUMN age SET NOT NULL;", false},
+	}
+	for _, tt := range tests {
+		if got := isDestructive(tt.sql); got != tt.want {
+			t.Errorf("isDestructive(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
+
+func TestConfirmDestructive(t *testing.T) {
+	refused := errors.New("confirmation refused")
+	files := mapFS(
+		"20240101000000_a.sql", migration("20240101000000", "a"),
+		"20240101000001_drop.sql", migrationSQL("20240101000001", "DROP TABLE a;", "CREATE TABLE a (id INTEGER PRIMARY KEY);"),
+	)
+	tests := []struct {
+		name       string
+		target     string
+		confirm    error // what the confirmation returns
+		wantAsked  []string
+		wantErr    error
+		wantTables []string
+	}{
+		{name: "nothing destructive pending", target: "20240101000000", wantTables: []string{"a"}},
+		{name: "confirmed", wantAsked: []string{"20240101000001_drop.sql:6: DROP TABLE a"}},
+		{name: "refused", confirm: refused, wantAsked: []string{"20240101000001_drop.sql:6: DROP TABLE a"}, wantErr: refused},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			var asked []string
+			r := testRunner(db, files, Options{ConfirmDestructive: func(destructive []DestructiveStatement) (*Confirmation, error) {
+				for _, stmt := range destructive {
+					asked = append(asked, stmt.String())
+				}
+				return &Confirmation{Env: "production", Action: "apply destructive statements"}, tt.confirm
+			}})
+
+			_, err := r.Migrate(context.Background(), tt.target)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("migrate error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(asked, tt.wantAsked) {
+				t.Errorf("asked to confirm %q, want %q", asked, tt.wantAsked)
+			}
+			// A refusal leaves the database as it was, a confirmation is logged
+			expectTables(t, db, tt.wantTables...)
+			var logged int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM migration_logs WHERE message = 'Confirmed apply destructive statements on production';`).Scan(&logged); err != nil {
+				t.Fatal(err)
+			}
+			if want := len(tt.wantAsked) > 0 && tt.wantErr == nil; (logged == 1) != want {
+				t.Errorf("%d confirmations logged", logged)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// directivePrefix marks header lines that change how a migration is run
+const directivePrefix = "-- migrato:"
+
+// noTransactionDirective runs a migration outside a transaction, for
+// statements such as CREATE INDEX CONCURRENTLY that cannot run inside one
+const noTransactionDirective = "no-transaction"
+
+// squashedFolder holds migrations that have been replaced by a squash baseline
+const squashedFolder = "squashed"
+
+// squashedDir returns the squashed folder of the CLI's migrations directory
+func squashedDir() string {
+	return filepath.Join(MigrationsDir, squashedFolder)
+}
+
+// migrationVersion returns the version prefix of a migration filename
+func migrationVersion(filename string) string {
+	name := strings.TrimSuffix(filename, ".sql")
+	if i := strings.Index(name, "_"); i != -1 {
+		return name[:i]
+	}
+	return name
+}
+
+// parseDirectives returns the "-- migrato:" lines found in the header of a
+// migration, i.e. before the up section, without the prefix
+func parseDirectives(content string) []string {
+	var directives []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "-- Up Migration") {
+			break
+		}
+		if strings.HasPrefix(line, directivePrefix) {
+			directives = append(directives, strings.TrimSpace(strings.TrimPrefix(line, directivePrefix)))
+		}
+	}
+	return directives
+}
+
+// directiveValues returns the arguments of every directive with the given name
+func directiveValues(directives []string, name string) []string {
+	var values []string
+	for _, directive := range directives {
+		fields := strings.Fields(directive)
+		if len(fields) > 1 && fields[0] == name {
+			values = append(values, strings.Join(fields[1:], " "))
+		}
+	}
+	return values
+}
+
+// hasDirective reports whether a directive with the given name is present
+func hasDirective(directives []string, name string) bool {
+	for _, directive := range directives {
+		if fields := strings.Fields(directive); len(fields) > 0 && fields[0] == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Formats export writes besides the tools import reads
+const (
+	ExportGoose         = ImportGoose
+	ExportGolangMigrate = ImportGolangMigrate
+	ExportFlyway        = ImportFlyway
+	ExportPlainSQL      = "plain-sql"
+)
+
+// ExportOptions configures ExportMigrations
+type ExportOptions struct {
+	Format string // ExportGoose, ExportGolangMigrate, ExportFlyway or ExportPlainSQL
+	Dir    string // directory the converted files are written to
+	Force  bool   // overwrite files already in Dir
+	DryRun bool
+}
+
+// exportedMigration is a migrato file split for export
+type exportedMigration struct {
+	Filename      string
+	Version       string
+	Name          string
+	Up, Down      string
+	NoTransaction bool
+	Squashes      []string
+}
+
+// exportFile is a file written by an export, relative to its directory
+type exportFile struct {
+	Path    string
+	Content string
+}
+
+// exporter converts one migration into the files of a format and returns
+// warnings about what the format cannot express
+type exporter func(m exportedMigration) ([]exportFile, []string)
+
+var exporters = map[string]exporter{
+	ExportGoose:         gooseExport,
+	ExportGolangMigrate: golangMigrateExport,
+	ExportFlyway:        flywayExport,
+	ExportPlainSQL:      plainSQLExport,
+}
+
+// ExportMigrations writes the CLI's migrations in the layout and naming of
+// another tool, so migrations authored with migrato can be delivered to
+// projects running that tool. Nothing is read from or written to a database.
+func ExportMigrations(opts ExportOptions) error {
+	export, ok := exporters[opts.Format]
+	if !ok {
+		return fmt.Errorf("unknown export format %q (supported: %s, %s, %s, %s)", opts.Format, ExportGolangMigrate, ExportGoose, ExportFlyway, ExportPlainSQL)
+	}
+
+	target, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %v", opts.Dir, err)
+	}
+	source, err := filepath.Abs(MigrationsDir)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %v", MigrationsDir, err)
+	}
+	if source == target {
+		return fmt.Errorf("%s is migrato's migrations directory; export to another directory", opts.Dir)
+	}
+
+	// Exporting only reads files, so no database or dialect is needed
+	r := New(nil, nil, os.DirFS(MigrationsDir), Options{Tables: TrackingTables})
+	filenames, err := r.migrationFiles()
+	if err != nil {
+		return err
+	}
+	if len(filenames) == 0 {
+		return fmt.Errorf("no migrations found in %s", MigrationsDir)
+	}
+
+	var files []exportFile
+	var warnings []string
+	for _, filename := range filenames {
+		m, err := r.exportedMigration(filename)
+		if err != nil {
+			return err
+		}
+		converted, notes := export(m)
+		files = append(files, converted...)
+		for _, note := range notes {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", filename, note))
+		}
+	}
+
+	if !opts.Force && !opts.DryRun {
+		for _, f := range files {
+			if _, err := os.Stat(filepath.Join(opts.Dir, f.Path)); err == nil {
+				return fmt.Errorf("%s already exists in %s; use --force to overwrite", f.Path, opts.Dir)
+			}
+		}
+	}
+
+	fmt.Printf("📤 Exporting %d migration(s) as %s to %s\n", len(filenames), opts.Format, opts.Dir)
+	for _, f := range files {
+		fmt.Printf("   %s\n", f.Path)
+	}
+	for _, warning := range warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+	if opts.DryRun {
+		fmt.Println("(Dry run only. No files were written.)")
+		return nil
+	}
+
+	for _, f := range files {
+		path := filepath.Join(opts.Dir, f.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(f.Content), 0644); err != nil {
+			return fmt.Errorf("write %s: %v", path, err)
+		}
+	}
+	fmt.Printf("✅ Wrote %d file(s) to %s\n", len(files), opts.Dir)
+	return nil
+}
+
+// exportedMigration reads and splits a migration file for export
+func (r *Runner) exportedMigration(filename string) (exportedMigration, error) {
+	sections, err := r.readMigration(filename)
+	if err != nil {
+		return exportedMigration{}, err
+	}
+	directives, err := r.readDirectives(filename)
+	if err != nil {
+		return exportedMigration{}, err
+	}
+
+	version := migrationVersion(filename)
+	name := strings.TrimPrefix(strings.TrimSuffix(filename, ".sql"), version)
+	name = strings.TrimPrefix(name, "_")
+	if name == "" {
+		name = "migration"
+	}
+
+	m := exportedMigration{
+		Filename:      filename,
+		Version:       version,
+		Name:          name,
+		Up:            sections.Up,
+		Down:          sections.Down,
+		NoTransaction: hasDirective(directives, noTransactionDirective),
+		Squashes:      directiveValues(directives, "squashes"),
+	}
+	return m, nil
+}
+
+// exportHeader names the migrato file a converted file comes from
+func exportHeader(m exportedMigration) string {
+	return fmt.Sprintf("-- Exported from migrato: %s\n", m.Filename)
+}
+
+// hasStatements reports whether SQL holds more than comments; down sections
+// written without rollback SQL hold only a note
+func hasStatements(sql string) bool {
+	return len(splitStatements(sql, 1)) > 0
+}
+
+// hasCopyData reports whether a section loads rows with COPY ... FROM stdin,
+// which only psql and Flyway can run from a file
+func hasCopyData(sql string) bool {
+	for _, stmt := range splitStatements(sql, 1) {
+		if stmt.CopyData != "" || copyFromStdin.MatchString(stmt.SQL) {
+			return true
+		}
+	}
+	return false
+}
+
+// exportWarnings returns the warnings every format shares
+func exportWarnings(m exportedMigration) []string {
+	var warnings []string
+	if len(m.Squashes) > 0 {
+		warnings = append(warnings, fmt.Sprintf("squash baseline of %d migration(s); databases that ran those will run it again", len(m.Squashes)))
+	}
+	if !hasStatements(m.Down) {
+		warnings = append(warnings, "no down SQL")
+	}
+	return warnings
+}
+
+// golangMigrateExport writes <version>_<name>.up.sql and .down.sql.
+// golang-migrate runs each file as one query, in no transaction of its own.
+func golangMigrateExport(m exportedMigration) ([]exportFile, []string) {
+	warnings := exportWarnings(m)
+	if m.NoTransaction && len(splitStatements(m.Up, 1)) > 1 {
+		warnings = append(warnings, "no-transaction migration with several statements; golang-migrate sends them as one query, which PostgreSQL runs in a transaction")
+	}
+	if hasCopyData(m.Up) || hasCopyData(m.Down) {
+		warnings = append(warnings, "COPY ... FROM stdin cannot be run by golang-migrate")
+	}
+
+	base := m.Version + "_" + m.Name
+	return []exportFile{
+		{Path: base + ".up.sql", Content: exportHeader(m) + "\n" + m.Up + "\n"},
+		{Path: base + ".down.sql", Content: exportHeader(m) + "\n" + m.Down + "\n"},
+	}, warnings
+}
+
+// gooseExport writes <version>_<name>.sql with -- +goose annotations. goose
+// splits on semicolons at the end of a line, so statements holding other
+// semicolons, such as function bodies, are wrapped in StatementBegin/End.
+func gooseExport(m exportedMigration) ([]exportFile, []string) {
+	warnings := exportWarnings(m)
+	if hasCopyData(m.Up) || hasCopyData(m.Down) {
+		warnings = append(warnings, "COPY ... FROM stdin cannot be run by goose")
+	}
+
+	var b strings.Builder
+	b.WriteString(exportHeader(m))
+	if m.NoTransaction {
+		b.WriteString("-- +goose NO TRANSACTION\n")
+	}
+	b.WriteString("\n-- +goose Up\n")
+	b.WriteString(gooseSection(m.Up))
+	b.WriteString("\n-- +goose Down\n")
+	b.WriteString(gooseSection(m.Down))
+
+	return []exportFile{{Path: m.Version + "_" + m.Name + ".sql", Content: b.String()}}, warnings
+}
+
+// gooseSection writes the SQL of a section as is, unless a statement needs
+// StatementBegin/End; the section is then rebuilt statement by statement
+func gooseSection(sql string) string {
+	statements := splitStatements(sql, 1)
+	wrap := false
+	for _, stmt := range statements {
+		if strings.Contains(stmt.SQL, ";") {
+			wrap = true
+		}
+	}
+	if !wrap {
+		return sql + "\n"
+	}
+
+	var b strings.Builder
+	for _, stmt := range statements {
+		if strings.Contains(stmt.SQL, ";") {
+			fmt.Fprintf(&b, "-- +goose StatementBegin\n%s;\n-- +goose StatementEnd\n", stmt.SQL)
+		} else {
+			fmt.Fprintf(&b, "%s;\n", stmt.SQL)
+		}
+	}
+	return b.String()
+}
+
+// flywayExport writes V<version>__<name>.sql, with the down SQL as the undo
+// migration U<version>__<name>.sql. No-transaction migrations get a script
+// configuration file turning off executeInTransaction.
+func flywayExport(m exportedMigration) ([]exportFile, []string) {
+	warnings := exportWarnings(m)
+
+	base := m.Version + "__" + m.Name + ".sql"
+	files := []exportFile{{Path: "V" + base, Content: exportHeader(m) + "\n" + m.Up + "\n"}}
+	if m.NoTransaction {
+		files = append(files, exportFile{Path: "V" + base + ".conf", Content: "executeInTransaction=false\n"})
+	}
+	if hasStatements(m.Down) {
+		files = append(files, exportFile{Path: "U" + base, Content: exportHeader(m) + "\n" + m.Down + "\n"})
+		if m.NoTransaction {
+			files = append(files, exportFile{Path: "U" + base + ".conf", Content: "executeInTransaction=false\n"})
+		}
+	}
+	return files, warnings
+}
+
+// plainSQLExport writes the up SQL to up/<version>_<name>.sql and the down
+// SQL to down/<version>_<name>.sql, for psql or any runner applying files
+// in name order
+func plainSQLExport(m exportedMigration) ([]exportFile, []string) {
+	warnings := exportWarnings(m)
+
+	base := m.Version + "_" + m.Name + ".sql"
+	up := exportHeader(m)
+	if m.NoTransaction {
+		up += "-- Runs outside a transaction; do not wrap this file in one\n"
+	}
+	return []exportFile{
+		{Path: filepath.Join("up", base), Content: up + "\n" + m.Up + "\n"},
+		{Path: filepath.Join("down", base), Content: exportHeader(m) + "\n" + m.Down + "\n"},
+	}, warnings
+}
@@ -0,0 +1,220 @@
+package runner
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Migrations the export tests convert
+var (
+	plainExport = exportedMigration{
+		Filename: "20240101000000_users.sql",
+		Version:  "20240101000000",
+		Name:     "users",
+		Up:       "CREATE TABLE users (id INT);",
+		Down:     "DROP TABLE users;",
+	}
+	noTransactionExport = exportedMigration{
+		Filename:      "20240101000001_indexes.sql",
+		Version:       "20240101000001",
+		Name:          "indexes",
+		Up:            "CREATE INDEX CONCURRENTLY a ON users (id);\nCREATE INDEX CONCURRENTLY b ON users (id);",
+		Down:          "DROP INDEX a;\nDROP INDEX b;",
+		NoTransaction: true,
+	}
+	functionExport = exportedMigration{
+		Filename: "20240101000002_touch.sql",
+		Version:  "20240101000002",
+		Name:     "touch",
+		Up:       "CREATE FUNCTION touch() RETURNS trigger AS $$\nBEGIN\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;\nSELECT 1;",
+		Down:     "-- Write the down SQL here",
+	}
+	copyExport = exportedMigration{
+		Filename: "20240101000003_baseline.sql",
+		Version:  "20240101000003",
+		Name:     "baseline",
+		Up:       "COPY users (id) FROM stdin;\n1\n\\.",
+		Down:     "DELETE FROM users;",
+		Squashes: []string{"20230101000000_a.sql", "20230101000001_b.sql"},
+	}
+)
+
+func TestExporters(t *testing.T) {
+	tests := []struct {
+		name     string
+		export   exporter
+		m        exportedMigration
+		want     []exportFile
+		warnings []string
+	}{
+		{
+			name:   "golang-migrate",
+			export: golangMigrateExport,
+			m:      plainExport,
+			want: []exportFile{
+				{Path: "20240101000000_users.up.sql", Content: "-- Exported from migrato: 20240101000000_users.sql\n\nCREATE TABLE users (id INT);\n"},
+				{Path: "20240101000000_users.down.sql", Content: "-- Exported from migrato: 20240101000000_users.sql\n\nDROP TABLE users;\n"},
+			},
+		},
+		{
+			name:   "golang-migrate no transaction",
+			export: golangMigrateExport,
+			m:      noTransactionExport,
+			want: []exportFile{
+				{Path: "20240101000001_indexes.up.sql", Content: "-- Exported from migrato: 20240101000001_indexes.sql\n\n" + noTransactionExport.Up + "\n"},
+				{Path: "20240101000001_indexes.down.sql", Content: "-- Exported from migrato: 20240101000001_indexes.sql\n\n" + noTransactionExport.Down + "\n"},
+			},
+			warnings: []string{"no-transaction migration with several statements; golang-migrate sends them as one query, which PostgreSQL runs in a transaction"},
+		},
+		{
+			name:   "golang-migrate copy and squash",
+			export: golangMigrateExport,
+			m:      copyExport,
+			want: []exportFile{
+				{Path: "20240101000003_baseline.up.sql", Content: "-- Exported from migrato: 20240101000003_baseline.sql\n\n" + copyExport.Up + "\n"},
+				{Path: "20240101000003_baseline.down.sql", Content: "-- Exported from migrato: 20240101000003_baseline.sql\n\nDELETE FROM users;\n"},
+			},
+			warnings: []string{
+				"squash baseline of 2 migration(s); databases that ran those will run it again",
+				"COPY ... FROM stdin cannot be run by golang-migrate",
+			},
+		},
+		{
+			name:   "goose",
+			export: gooseExport,
+			m:      plainExport,
+			want: []exportFile{
+				{Path: "20240101000000_users.sql", Content: "-- Exported from migrato: 20240101000000_users.sql\n\n-- +goose Up\nCREATE TABLE users (id INT);\n\n-- +goose Down\nDROP TABLE users;\n"},
+			},
+		},
+		{
+			name:   "goose no transaction",
+			export: gooseExport,
+			m:      noTransactionExport,
+			want: []exportFile{
+				{Path: "20240101000001_indexes.sql", Content: "-- Exported from migrato: 20240101000001_indexes.sql\n-- +goose NO TRANSACTION\n\n-- +goose Up\n" +
+					noTransactionExport.Up + "\n\n-- +goose Down\n" + noTransactionExport.Down + "\n"},
+			},
+		},
+		{
+			name:   "goose function body",
+			export: gooseExport,
+			m:      functionExport,
+			want: []exportFile{
+				{Path: "20240101000002_touch.sql", Content: "-- Exported from migrato: 20240101000002_touch.sql\n\n-- +goose Up\n" +
+					"-- +goose StatementBegin\nCREATE FUNCTION touch() RETURNS trigger AS $$\nBEGIN\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;\n-- +goose StatementEnd\nSELECT 1;\n" +
+					"\n-- +goose Down\n-- Write the down SQL here\n"},
+			},
+			warnings: []string{"no down SQL"},
+		},
+		{
+			name:   "goose copy",
+			export: gooseExport,
+			m:      copyExport,
+			want: []exportFile{
+				{Path: "20240101000003_baseline.sql", Content: "-- Exported from migrato: 20240101000003_baseline.sql\n\n-- +goose Up\n" + copyExport.Up + "\n\n-- +goose Down\nDELETE FROM users;\n"},
+			},
+			warnings: []string{
+				"squash baseline of 2 migration(s); databases that ran those will run it again",
+				"COPY ... FROM stdin cannot be run by goose",
+			},
+		},
+		{
+			name:   "flyway",
+			export: flywayExport,
+			m:      plainExport,
+			want: []exportFile{
+				{Path: "V20240101000000__users.sql", Content: "-- Exported from migrato: 20240101000000_users.sql\n\nCREATE TABLE users (id INT);\n"},
+				{Path: "U20240101000000__users.sql", Content: "-- Exported from migrato: 20240101000000_users.sql\n\nDROP TABLE users;\n"},
+			},
+		},
+		{
+			name:   "flyway no transaction",
+			export: flywayExport,
+			m:      noTransactionExport,
+			want: []exportFile{
+				{Path: "V20240101000001__indexes.sql", Content: "-- Exported from migrato: 20240101000001_indexes.sql\n\n" + noTransactionExport.Up + "\n"},
+				{Path: "V20240101000001__indexes.sql.conf", Content: "executeInTransaction=false\n"},
+				{Path: "U20240101000001__indexes.sql", Content: "-- Exported from migrato: 20240101000001_indexes.sql\n\n" + noTransactionExport.Down + "\n"},
+				{Path: "U20240101000001__indexes.sql.conf", Content: "executeInTransaction=false\n"},
+			},
+		},
+		{
+			name:   "flyway without down SQL",
+			export: flywayExport,
+			m:      functionExport,
+			want: []exportFile{
+				{Path: "V20240101000002__touch.sql", Content: "-- Exported from migrato: 20240101000002_touch.sql\n\n" + functionExport.Up + "\n"},
+			},
+			warnings: []string{"no down SQL"},
+		},
+		{
+			name:   "plain SQL no transaction",
+			export: plainSQLExport,
+			m:      noTransactionExport,
+			want: []exportFile{
+				{Path: filepath.Join("up", "20240101000001_indexes.sql"), Content: "-- Exported from migrato: 20240101000001_indexes.sql\n" +
+					"-- Runs outside a transaction; do not wrap this file in one\n\n" + noTransactionExport.Up + "\n"},
+				{Path: filepath.Join("down", "20240101000001_indexes.sql"), Content: "-- Exported from migrato: 20240101000001_indexes.sql\n\n" + noTransactionExport.Down + "\n"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, warnings := tt.export(tt.m)
+			if !reflect.DeepEqual(files, tt.want) {
+				t.Errorf("files =\n%q\nwant\n%q", files, tt.want)
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestExportedMigration(t *testing.T) {
+	r := testRunner(nil, mapFS(
+		"20240101000000_users.sql", migration("20240101000000", "users", "no-transaction"),
+		"20240101000001.sql", migration("20240101000001", "posts", "squashes 20230101000000_a.sql"),
+	), Options{})
+
+	tests := []struct {
+		filename string
+		want     exportedMigration
+	}{
+		{
+			filename: "20240101000000_users.sql",
+			want: exportedMigration{
+				Filename:      "20240101000000_users.sql",
+				Version:       "20240101000000",
+				Name:          "users",
+				Up:            "-- ============\nCREATE TABLE users (id INTEGER PRIMARY KEY);",
+				Down:          "DROP TABLE users;",
+				NoTransaction: true,
+			},
+		},
+		{
+			filename: "20240101000001.sql",
+			want: exportedMigration{
+				Filename: "20240101000001.sql",
+				Version:  "20240101000001",
+				Name:     "migration",
+				Up:       "-- ============\nCREATE TABLE posts (id INTEGER PRIMARY KEY);",
+				Down:     "DROP TABLE posts;",
+				Squashes: []string{"20230101000000_a.sql"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			got, err := r.exportedMigration(tt.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("exportedMigration =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ridoystarlord/migrato/dialect"
+)
+
+// testDB opens an empty SQLite database in a temporary directory
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open(dialect.SQLite.DriverName(), dialect.SQLite.DataSourceName("sqlite://"+path))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// migration renders a migration file creating and dropping one table
+func migration(version, table string, directives ...string) string {
+	return migrationSQL(version, fmt.Sprintf("CREATE TABLE %s (id INTEGER PRIMARY KEY);", table), fmt.Sprintf("DROP TABLE %s;", table), directives...)
+}
+
+// migrationSQL renders a migration file in the layout generate writes
+func migrationSQL(version, up, down string, directives ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Migration: %s\n-- Description: test\n", version)
+	for _, d := range directives {
+		fmt.Fprintf(&b, "%s%s\n", directivePrefix, d)
+	}
+	fmt.Fprintf(&b, "\n%s\n-- ============\n%s\n\n%s\n-- =======================\n%s\n", upMarker, up, downMarker, down)
+	return b.String()
+}
+
+// testRunner returns a runner applying files to db
+func testRunner(db *sql.DB, files fstest.MapFS, opts Options) *Runner {
+	return New(db, dialect.SQLite, files, opts)
+}
+
+// mapFS builds a file system from name and content pairs
+func mapFS(pairs ...string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		fsys[pairs[i]] = &fstest.MapFile{Data: []byte(pairs[i+1])}
+	}
+	return fsys
+}
+
+// userTables lists the tables of db that are not tracking tables
+func userTables(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.QueryContext(context.Background(), `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%';`)
+	if err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	defer rows.Close()
+
+	tracking := DefaultTables.WithDefaults()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("scan table: %v", err)
+		}
+		if !tracking.contains(name) {
+			tables = append(tables, name)
+		}
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// expectTables fails the test unless db holds exactly the given user tables
+func expectTables(t *testing.T, db *sql.DB, want ...string) {
+	t.Helper()
+	sort.Strings(want)
+	if got := userTables(t, db); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("tables = %v, want %v", got, want)
+	}
+}
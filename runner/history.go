@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ridoystarlord/migrato/dialect"
+	"github.com/ridoystarlord/migrato/utils"
+)
+
+// Event statuses of the migration history. Every apply and rollback appends
+// an event; schema_migrations only holds what is applied right now.
+const (
+	eventSuccess  = "success"
+	eventFailed   = "failed"
+	eventRecorded = "recorded" // recorded as applied without running, e.g. a baseline
+	eventResolved = "resolved" // a failed migration resolved by hand
+)
+
+// recordEvent appends an apply ("up") or rollback ("down") event to the history
+func (r *Runner) recordEvent(ex execer, ctx context.Context, filename, direction, status, checksum string, executionTime time.Duration, errorMessage string) error {
+	_, err := ex.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (filename, direction, status, execution_time, executed_by, checksum, error_message)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, r.tables.History), filename, direction, status, r.dialect.DurationValue(executionTime), utils.CurrentUser(), checksum, errorMessage)
+	if err != nil {
+		return fmt.Errorf("recording %s event for %s: %v", direction, filename, err)
+	}
+	return nil
+}
+
+// backfillHistorySQL seeds an empty history with the migrations applied
+// before it existed, so the timeline starts with them
+func (r *Runner) backfillHistorySQL() string {
+	return fmt.Sprintf(`INSERT INTO %[1]s (filename, direction, status, executed_at, execution_time, executed_by, checksum, error_message, table_affected)
+SELECT filename, 'up', CASE WHEN status = 'failed' THEN 'failed' ELSE 'success' END,
+       applied_at, execution_time, executed_by, checksum, error_message, table_affected
+FROM %[2]s
+WHERE NOT EXISTS (SELECT 1 FROM %[1]s)
+ORDER BY applied_at, id;`, r.tables.History, r.tables.Migrations)
+}
+
+// historyFilter narrows the events read from the history
+type historyFilter struct {
+	Limit     int    // newest events to read, all when zero
+	Table     string // substring of the affected table
+	Migration string // substring of the migration filename
+}
+
+// history reads the apply and rollback events, newest first
+func (r *Runner) history(q queryer, ctx context.Context, filter historyFilter) ([]MigrationRecord, error) {
+	query := fmt.Sprintf(`
+		SELECT id, filename, direction, executed_at, %s, COALESCE(executed_by, ''),
+		       status, COALESCE(error_message, ''), COALESCE(checksum, ''), COALESCE(table_affected, '')
+		FROM %s
+	`, r.dialect.DurationMillis("execution_time"), r.tables.History)
+
+	var where []string
+	var args []interface{}
+	if filter.Table != "" {
+		args = append(args, "%"+strings.ToLower(filter.Table)+"%")
+		where = append(where, fmt.Sprintf("LOWER(table_affected) LIKE $%d", len(args)))
+	}
+	if filter.Migration != "" {
+		args = append(args, "%"+filter.Migration+"%")
+		where = append(where, fmt.Sprintf("filename LIKE $%d", len(args)))
+	}
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	query += " ORDER BY id DESC"
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query migration history: %v", err)
+	}
+	defer rows.Close()
+
+	var records []MigrationRecord
+	for rows.Next() {
+		var record MigrationRecord
+		var executionMillis int64
+		err := rows.Scan(
+			&record.ID,
+			&record.MigrationName,
+			&record.Direction,
+			&record.ExecutedAt,
+			&executionMillis,
+			&record.ExecutedBy,
+			&record.Status,
+			&record.ErrorMessage,
+			&record.Checksum,
+			&record.TableAffected,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan migration record: %v", err)
+		}
+		record.ExecutionTime = time.Duration(executionMillis) * time.Millisecond
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// queryer is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// GetMigrationHistory returns the apply and rollback events recorded in db,
+// newest first, optionally limited and filtered by affected table and
+// migration filename
+func GetMigrationHistory(ctx context.Context, db *sql.DB, d dialect.Dialect, limit int, tableFilter, migrationFilter string) ([]MigrationRecord, error) {
+	r := New(db, d, nil, Options{Tables: TrackingTables})
+	conn, err := r.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return r.history(conn, ctx, historyFilter{Limit: limit, Table: tableFilter, Migration: migrationFilter})
+}
+
+// History returns the apply and rollback events, newest first. limit caps
+// the number of events, zero returns all of them.
+func (r *Runner) History(ctx context.Context, limit int) ([]MigrationRecord, error) {
+	conn, err := r.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return r.history(conn, ctx, historyFilter{Limit: limit})
+}
@@ -0,0 +1,366 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ridoystarlord/migrato/generator"
+	"github.com/ridoystarlord/migrato/utils"
+)
+
+// ImportOptions configures ImportMigrations
+type ImportOptions struct {
+	From   string // ImportGoose, ImportGolangMigrate, ImportFlyway or ImportAtlas
+	Dir    string // the tool's migrations directory
+	Table  string // the tool's version table, its default when empty
+	DryRun bool
+}
+
+// eventImported marks history events translated from another tool's version table
+const eventImported = "imported"
+
+// importFilenameChars are replaced in the names of imported files
+var importFilenameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// importTableName guards the version table name interpolated into queries
+var importTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ImportMigrations converts the migrations of another tool into migrato
+// files and records the versions its version table marks as applied, with
+// their original time, user and duration where the tool keeps them. Failed
+// or dirty versions are recorded as failed migrations to resolve.
+func ImportMigrations(ctx context.Context, opts ImportOptions) error {
+	tool, ok := importers[opts.From]
+	if !ok {
+		return fmt.Errorf("unknown migration tool %q (supported: %s, %s, %s, %s)", opts.From, ImportGoose, ImportGolangMigrate, ImportFlyway, ImportAtlas)
+	}
+	table := opts.Table
+	if table == "" {
+		table = tool.Table
+	}
+	if !importTableName.MatchString(table) {
+		return fmt.Errorf("invalid version table name %q", table)
+	}
+
+	source, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %v", opts.Dir, err)
+	}
+	target, err := filepath.Abs(MigrationsDir)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %v", MigrationsDir, err)
+	}
+	if source == target {
+		return fmt.Errorf("%s is migrato's migrations directory; move the %s files elsewhere and import from there", opts.Dir, opts.From)
+	}
+
+	migrations, warnings, err := tool.Files(opts.Dir)
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		return fmt.Errorf("no %s migrations found in %s", opts.From, opts.Dir)
+	}
+	filenames, err := importFilenames(migrations)
+	if err != nil {
+		return err
+	}
+	for _, f := range filenames {
+		if _, err := os.Stat(filepath.Join(MigrationsDir, f)); err == nil {
+			return fmt.Errorf("%s already exists in %s; were these migrations imported before?", f, MigrationsDir)
+		}
+	}
+
+	r, err := cliRunner()
+	if err != nil {
+		return err
+	}
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	unlock, err := r.acquireLock(conn, ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// golang-migrate's default table has the name of migrato's own; it is
+	// read, then kept under another name before migrato creates its table
+	renamed := ""
+	if table == r.tables.Migrations {
+		renamed = table + "_" + strings.ReplaceAll(opts.From, "-", "_")
+		if exists, err := r.tableExists(conn, ctx, renamed); err != nil {
+			return err
+		} else if exists {
+			table, renamed = renamed, ""
+		}
+	}
+
+	var exists bool
+	if opts.From == ImportAtlas && r.dialect.Name() == "postgres" {
+		// Atlas keeps its table in a schema of its own on PostgreSQL
+		err = conn.QueryRowContext(ctx, `SELECT COUNT(*) > 0 FROM information_schema.tables WHERE table_schema = $1 AND table_name = $1;`, table).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("look up table %s: %v", table, err)
+		}
+	} else if exists, err = r.tableExists(conn, ctx, table); err != nil {
+		return err
+	}
+	applied := map[string]sourceRecord{}
+	if exists {
+		applied, err = tool.Applied(r, conn, ctx, table, migrations)
+		if err != nil {
+			return err
+		}
+	} else {
+		warnings = append(warnings, fmt.Sprintf("the database has no %s table; nothing is recorded as applied", table))
+	}
+
+	// Applied versions without a file cannot be recorded
+	known := map[string]bool{}
+	for _, m := range migrations {
+		known[normalizeVersion(m.Version)] = true
+	}
+	for _, version := range sortedVersions(applied) {
+		if !known[version] {
+			warnings = append(warnings, fmt.Sprintf("version %s is applied in %s but has no migration file", version, table))
+		}
+	}
+
+	fmt.Printf("📥 Importing %d %s migration(s) from %s\n", len(migrations), opts.From, opts.Dir)
+	for i, m := range migrations {
+		state := "pending"
+		if record, ok := applied[normalizeVersion(m.Version)]; ok {
+			state = "applied"
+			if record.Failed != "" {
+				state = "failed: " + record.Failed
+			}
+		}
+		fmt.Printf("   %s -> %s (%s)\n", m.Source, filenames[i], state)
+	}
+	for _, warning := range warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+	if opts.DryRun {
+		fmt.Println("(Dry run only. No files were written and nothing was recorded.)")
+		return nil
+	}
+
+	// The files are written only after the versions are recorded, so a
+	// failed import leaves no files behind that would block running it again
+	files := make([]generator.MigrationFile, len(migrations))
+	upSQL := make([]string, len(migrations))
+	for i, m := range migrations {
+		var directives []string
+		if m.NoTransaction {
+			directives = append(directives, noTransactionDirective)
+		}
+		down := m.Down
+		if down == "" {
+			down = fmt.Sprintf("-- %s has no down SQL; write it before rolling back", m.Source)
+		}
+		files[i] = generator.MigrationFile{
+			Version:     migrationVersion(filenames[i]),
+			Name:        strings.TrimSuffix(filenames[i][strings.Index(filenames[i], "_")+1:], ".sql"),
+			Description: fmt.Sprintf("Imported from %s %s", opts.From, m.Source),
+			Directives:  directives,
+			Up:          []string{m.Up},
+			Down:        []string{down},
+		}
+		_, content := generator.RenderMigration(files[i])
+		sections, err := parseMigration(filenames[i], content)
+		if err != nil {
+			return err
+		}
+		upSQL[i] = sections.Up
+	}
+	// Check the sum file now rather than after recording
+	if err := generator.VerifySum(MigrationsDir); err != nil && err != generator.ErrNoSum {
+		return err
+	}
+
+	// The rename and the recorded versions outlive a failed import; running
+	// it again reads the kept table and skips the versions already recorded
+	if renamed != "" {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s RENAME TO %s;`, table, renamed)); err != nil {
+			return fmt.Errorf("rename %s to %s: %v", table, renamed, err)
+		}
+		fmt.Printf("📦 Kept %s's %s table as %s\n", opts.From, table, renamed)
+	}
+	if err := r.ensureMigrationsTable(conn, ctx); err != nil {
+		return fmt.Errorf("ensure migrations table: %v", err)
+	}
+	alreadyRecorded, err := r.recordedMigrations(conn, ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	recorded, failed := 0, 0
+	for i, m := range migrations {
+		record, ok := applied[normalizeVersion(m.Version)]
+		if !ok || alreadyRecorded[filenames[i]] {
+			continue
+		}
+		if err := r.recordImported(tx, ctx, filenames[i], upSQL[i], record); err != nil {
+			return err
+		}
+		if record.Failed != "" {
+			failed++
+		} else {
+			recorded++
+		}
+	}
+
+	details := fmt.Sprintf("%d applied, %d failed, from %s in %s", recorded, failed, table, opts.Dir)
+	if err := r.logMigrationActivity(tx, ctx, "INFO", fmt.Sprintf("Imported %d migration(s) from %s", len(migrations), opts.From), "", details); err != nil {
+		return fmt.Errorf("log import: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit import: %v", err)
+	}
+
+	fmt.Printf("✅ Recorded %d applied migration(s) from %s\n", recorded, table)
+	if failed > 0 {
+		fmt.Printf("⚠️  Recorded %d failed migration(s); settle them with 'migrato resolve'\n", failed)
+	}
+
+	if err := writeImported(files); err != nil {
+		return fmt.Errorf("%v; the versions are recorded, run the import again to write the files", err)
+	}
+	fmt.Printf("✅ Wrote %d migration(s) to %s\n", len(files), MigrationsDir)
+	return nil
+}
+
+// writeImported writes the imported migration files. When one fails, the
+// files already written are removed again.
+func writeImported(files []generator.MigrationFile) error {
+	var written []string
+	for _, f := range files {
+		path, err := generator.WriteMigration(f)
+		if err != nil {
+			for _, w := range written {
+				os.Remove(w)
+			}
+			if len(written) > 0 {
+				generator.WriteSum(MigrationsDir)
+			}
+			return err
+		}
+		written = append(written, path)
+	}
+	return nil
+}
+
+// recordedMigrations returns the filenames of every tracking row, applied or failed
+func (r *Runner) recordedMigrations(conn *sql.Conn, ctx context.Context) (map[string]bool, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT filename FROM %s;`, r.tables.Migrations))
+	if err != nil {
+		return nil, fmt.Errorf("query recorded migrations: %v", err)
+	}
+	defer rows.Close()
+
+	recorded := map[string]bool{}
+	for rows.Next() {
+		var filename string
+		if err := rows.Scan(&filename); err != nil {
+			return nil, fmt.Errorf("scan filename: %v", err)
+		}
+		recorded[filename] = true
+	}
+	return recorded, rows.Err()
+}
+
+// importFilenames names the imported migrations. Numeric versions are padded
+// to the width of migrato's timestamps, which keeps them in order and before
+// migrations generated later; dotted versions are numbered in order.
+func importFilenames(migrations []importedMigration) ([]string, error) {
+	numeric := true
+	for _, m := range migrations {
+		if strings.ContainsAny(m.Version, "._") || len(m.Version) > len(VersionLayout) {
+			numeric = false
+		}
+	}
+
+	filenames := make([]string, len(migrations))
+	seen := map[string]string{}
+	for i, m := range migrations {
+		version := fmt.Sprintf("%0*d", len(VersionLayout), i+1)
+		if numeric {
+			version = strings.Repeat("0", len(VersionLayout)-len(m.Version)) + m.Version
+		}
+		if previous, ok := seen[version]; ok {
+			return nil, fmt.Errorf("%s and %s have the same version", previous, m.Source)
+		}
+		seen[version] = m.Source
+
+		name := strings.Trim(importFilenameChars.ReplaceAllString(strings.ToLower(m.Name), "_"), "_")
+		if name == "" {
+			name = "migration"
+		}
+		filenames[i] = version + "_" + name + ".sql"
+	}
+	return filenames, nil
+}
+
+// recordImported inserts the tracking row and the history event of an
+// imported migration, keeping the time, user and duration of the source
+func (r *Runner) recordImported(ex execer, ctx context.Context, filename, upSQL string, record sourceRecord) error {
+	appliedAt := record.AppliedAt
+	if appliedAt.IsZero() {
+		appliedAt = time.Now()
+	}
+	appliedAt = appliedAt.UTC()
+	appliedBy := record.AppliedBy
+	if appliedBy == "" {
+		appliedBy = utils.CurrentUser()
+	}
+	status, event := "success", eventImported
+	if record.Failed != "" {
+		status, event = "failed", eventFailed
+	}
+	checksum := calculateChecksum(upSQL)
+
+	_, err := ex.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (filename, applied_at, execution_time, executed_by, status, error_message, checksum)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, r.tables.Migrations), filename, appliedAt, r.dialect.DurationValue(record.ExecutionTime), appliedBy, status, record.Failed, checksum)
+	if err != nil {
+		return fmt.Errorf("recording migration %s: %v", filename, err)
+	}
+	_, err = ex.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (filename, direction, status, executed_at, execution_time, executed_by, checksum, error_message)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, r.tables.History), filename, "up", event, appliedAt, r.dialect.DurationValue(record.ExecutionTime), appliedBy, checksum, record.Failed)
+	if err != nil {
+		return fmt.Errorf("recording up event for %s: %v", filename, err)
+	}
+	return nil
+}
+
+// sortedVersions returns the keys of a version map in version order
+func sortedVersions(applied map[string]sourceRecord) []string {
+	var versions []string
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
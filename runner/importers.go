@@ -0,0 +1,456 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Migration tools import reads from
+const (
+	ImportGoose         = "goose"
+	ImportGolangMigrate = "golang-migrate"
+	ImportFlyway        = "flyway"
+	ImportAtlas         = "atlas"
+)
+
+// importer reads the migration files and the version table of one tool
+type importer struct {
+	// Table is the tool's default version table
+	Table string
+	// Files parses the migration files of dir, sorted by version
+	Files func(dir string) ([]importedMigration, []string, error)
+	// Applied reads the version table and returns the state of every
+	// version it knows, keyed by normalizeVersion
+	Applied func(r *Runner, q queryer, ctx context.Context, table string, migrations []importedMigration) (map[string]sourceRecord, error)
+}
+
+var importers = map[string]importer{
+	ImportGoose:         {Table: "goose_db_version", Files: gooseFiles, Applied: gooseApplied},
+	ImportGolangMigrate: {Table: "schema_migrations", Files: golangMigrateFiles, Applied: golangMigrateApplied},
+	ImportFlyway:        {Table: "flyway_schema_history", Files: flywayFiles, Applied: flywayApplied},
+	ImportAtlas:         {Table: "atlas_schema_revisions", Files: atlasFiles, Applied: atlasApplied},
+}
+
+// importedMigration is a migration of another tool converted to up and down SQL
+type importedMigration struct {
+	Source        string // file name, or the up file for tools with one file per direction
+	Version       string // version as the tool writes it
+	Name          string
+	Up, Down      string
+	NoTransaction bool
+}
+
+// sourceRecord is the state a tool's version table gives a version
+type sourceRecord struct {
+	AppliedAt     time.Time
+	AppliedBy     string
+	ExecutionTime time.Duration
+	Failed        string // why the version is failed or dirty, empty when applied
+}
+
+// normalizeVersion makes versions comparable across file names and version
+// tables: Flyway's 1_1 is 1.1, and leading zeros are dropped from each part
+func normalizeVersion(version string) string {
+	parts := strings.Split(strings.ReplaceAll(version, "_", "."), ".")
+	for i, part := range parts {
+		part = strings.TrimLeft(part, "0")
+		if part == "" {
+			part = "0"
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, ".")
+}
+
+// compareVersions orders dotted numeric versions of any length
+func compareVersions(a, b string) int {
+	pa := strings.Split(normalizeVersion(a), ".")
+	pb := strings.Split(normalizeVersion(b), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		x, y := "0", "0"
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if len(x) != len(y) {
+			if len(x) < len(y) {
+				return -1
+			}
+			return 1
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func sortImported(migrations []importedMigration) {
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return compareVersions(migrations[i].Version, migrations[j].Version) < 0
+	})
+}
+
+var gooseFile = regexp.MustCompile(`^(\d+)_(.+)\.(sql|go)$`)
+
+// gooseFiles reads goose SQL migrations with their -- +goose Up/Down annotations
+func gooseFiles(dir string) ([]importedMigration, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %v", dir, err)
+	}
+
+	var migrations []importedMigration
+	var warnings []string
+	for _, entry := range entries {
+		m := gooseFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		if m[3] == "go" {
+			warnings = append(warnings, fmt.Sprintf("%s is a Go migration and cannot be converted; port it to SQL by hand", entry.Name()))
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("read %s: %v", entry.Name(), err)
+		}
+
+		migration := importedMigration{Source: entry.Name(), Version: m[1], Name: m[2]}
+		var up, down []string
+		section := ""
+		for _, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(strings.TrimSpace(line))
+			if len(fields) >= 2 && fields[0] == "--" && strings.HasPrefix(fields[1], "+goose") {
+				switch strings.ToLower(strings.Join(fields[2:], " ")) {
+				case "up":
+					section = "up"
+				case "down":
+					section = "down"
+				case "no transaction":
+					migration.NoTransaction = true
+				}
+				// StatementBegin/End and ENVSUB only guide goose's own parser
+				continue
+			}
+			switch section {
+			case "up":
+				up = append(up, line)
+			case "down":
+				down = append(down, line)
+			}
+		}
+		if section == "" {
+			return nil, nil, fmt.Errorf("%s has no -- +goose Up annotation", entry.Name())
+		}
+		migration.Up = strings.TrimSpace(strings.Join(up, "\n"))
+		migration.Down = strings.TrimSpace(strings.Join(down, "\n"))
+		migrations = append(migrations, migration)
+	}
+	sortImported(migrations)
+	return migrations, warnings, nil
+}
+
+// gooseApplied reads goose_db_version, where the latest row of a version
+// says whether it is applied
+func gooseApplied(r *Runner, q queryer, ctx context.Context, table string, migrations []importedMigration) (map[string]sourceRecord, error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`SELECT version_id, is_applied, tstamp FROM %s ORDER BY id;`, table))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	applied := map[string]sourceRecord{}
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		var tstamp sql.NullTime
+		if err := rows.Scan(&version, &isApplied, &tstamp); err != nil {
+			return nil, fmt.Errorf("read %s: %v", table, err)
+		}
+		key := normalizeVersion(fmt.Sprint(version))
+		if version == 0 {
+			// goose's initial row, not a migration
+			continue
+		}
+		if isApplied {
+			applied[key] = sourceRecord{AppliedAt: tstamp.Time}
+		} else {
+			delete(applied, key)
+		}
+	}
+	return applied, rows.Err()
+}
+
+var golangMigrateFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// golangMigrateFiles pairs golang-migrate's <version>_<name>.up.sql and .down.sql files
+func golangMigrateFiles(dir string) ([]importedMigration, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %v", dir, err)
+	}
+
+	byVersion := map[string]*importedMigration{}
+	var warnings []string
+	for _, entry := range entries {
+		m := golangMigrateFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("read %s: %v", entry.Name(), err)
+		}
+		migration, ok := byVersion[m[1]]
+		if !ok {
+			migration = &importedMigration{Version: m[1], Name: m[2]}
+			byVersion[m[1]] = migration
+		}
+		if m[3] == "up" {
+			migration.Source = entry.Name()
+			migration.Up = strings.TrimSpace(string(content))
+		} else {
+			migration.Down = strings.TrimSpace(string(content))
+		}
+	}
+
+	var migrations []importedMigration
+	for _, migration := range byVersion {
+		if migration.Source == "" {
+			warnings = append(warnings, fmt.Sprintf("version %s has a down file but no up file and was skipped", migration.Version))
+			continue
+		}
+		migrations = append(migrations, *migration)
+	}
+	sortImported(migrations)
+	return migrations, warnings, nil
+}
+
+// golangMigrateApplied reads golang-migrate's single row: every version up
+// to it is applied, and a dirty version failed halfway
+func golangMigrateApplied(r *Runner, q queryer, ctx context.Context, table string, migrations []importedMigration) (map[string]sourceRecord, error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`SELECT version, dirty FROM %s;`, table))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	applied := map[string]sourceRecord{}
+	if !rows.Next() {
+		return applied, rows.Err()
+	}
+	var current int64
+	var dirty bool
+	if err := rows.Scan(&current, &dirty); err != nil {
+		return nil, fmt.Errorf("read %s: %v", table, err)
+	}
+
+	for _, m := range migrations {
+		switch c := compareVersions(m.Version, fmt.Sprint(current)); {
+		case c < 0, c == 0 && !dirty:
+			applied[normalizeVersion(m.Version)] = sourceRecord{}
+		case c == 0:
+			applied[normalizeVersion(m.Version)] = sourceRecord{Failed: "marked dirty by golang-migrate: the migration failed partway"}
+		}
+	}
+	if _, ok := applied[normalizeVersion(fmt.Sprint(current))]; !ok && current > 0 {
+		// Keep the version visible as orphaned when no file has it
+		applied[normalizeVersion(fmt.Sprint(current))] = sourceRecord{}
+	}
+	return applied, rows.Err()
+}
+
+var (
+	flywayVersioned  = regexp.MustCompile(`^V([0-9._]+)__(.+)\.sql$`)
+	flywayUndo       = regexp.MustCompile(`^U([0-9._]+)__(.+)\.sql$`)
+	flywayRepeatable = regexp.MustCompile(`^R__(.+)\.sql$`)
+)
+
+// flywayFiles reads Flyway's V<version>__<description>.sql files with their
+// U<version> undo files as the down SQL
+func flywayFiles(dir string) ([]importedMigration, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %v", dir, err)
+	}
+
+	undo := map[string]string{}
+	var migrations []importedMigration
+	var warnings []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		versioned, undoFile := flywayVersioned.FindStringSubmatch(name), flywayUndo.FindStringSubmatch(name)
+		if versioned == nil && undoFile == nil {
+			if flywayRepeatable.MatchString(name) {
+				warnings = append(warnings, fmt.Sprintf("%s is a repeatable migration and was skipped; add its SQL to a regular migration", name))
+			}
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, nil, fmt.Errorf("read %s: %v", name, err)
+		}
+		if undoFile != nil {
+			undo[normalizeVersion(undoFile[1])] = strings.TrimSpace(string(content))
+			continue
+		}
+		migrations = append(migrations, importedMigration{
+			Source:  name,
+			Version: strings.ReplaceAll(versioned[1], "_", "."),
+			Name:    versioned[2],
+			Up:      strings.TrimSpace(string(content)),
+		})
+	}
+	for i := range migrations {
+		migrations[i].Down = undo[normalizeVersion(migrations[i].Version)]
+	}
+	sortImported(migrations)
+	return migrations, warnings, nil
+}
+
+// flywayApplied replays flyway_schema_history in rank order: successful
+// migrations are applied, undos remove them and a baseline covers every
+// version up to its own
+func flywayApplied(r *Runner, q queryer, ctx context.Context, table string, migrations []importedMigration) (map[string]sourceRecord, error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`
+		SELECT COALESCE(version, ''), type, COALESCE(installed_by, ''), installed_on, COALESCE(execution_time, 0), success
+		FROM %s
+		ORDER BY installed_rank;
+	`, table))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	applied := map[string]sourceRecord{}
+	for rows.Next() {
+		var version, kind, installedBy string
+		var installedOn sql.NullTime
+		var executionMillis int64
+		var success bool
+		if err := rows.Scan(&version, &kind, &installedBy, &installedOn, &executionMillis, &success); err != nil {
+			return nil, fmt.Errorf("read %s: %v", table, err)
+		}
+		if version == "" {
+			// Repeatable migrations and schema creation have no version
+			continue
+		}
+		record := sourceRecord{AppliedAt: installedOn.Time, AppliedBy: installedBy, ExecutionTime: time.Duration(executionMillis) * time.Millisecond}
+		switch {
+		case kind == "BASELINE":
+			for _, m := range migrations {
+				if compareVersions(m.Version, version) <= 0 {
+					applied[normalizeVersion(m.Version)] = record
+				}
+			}
+		case strings.HasPrefix(kind, "UNDO"), kind == "DELETE":
+			if success {
+				delete(applied, normalizeVersion(version))
+			}
+		case !success:
+			record.Failed = "failed in Flyway"
+			applied[normalizeVersion(version)] = record
+		default:
+			applied[normalizeVersion(version)] = record
+		}
+	}
+	return applied, rows.Err()
+}
+
+var atlasFile = regexp.MustCompile(`^(\d+)(?:_(.+))?\.sql$`)
+
+// atlasFiles reads Atlas' <version>_<name>.sql files, which have no down SQL
+func atlasFiles(dir string) ([]importedMigration, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %v", dir, err)
+	}
+
+	var migrations []importedMigration
+	for _, entry := range entries {
+		m := atlasFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("read %s: %v", entry.Name(), err)
+		}
+		name := m[2]
+		if name == "" {
+			name = "atlas"
+		}
+		migrations = append(migrations, importedMigration{Source: entry.Name(), Version: m[1], Name: name, Up: strings.TrimSpace(string(content))})
+	}
+	sortImported(migrations)
+
+	var warnings []string
+	if len(migrations) > 0 {
+		warnings = append(warnings, "Atlas migrations have no down SQL; write the down sections before rolling them back")
+	}
+	return migrations, warnings, nil
+}
+
+// atlasApplied reads atlas_schema_revisions, which PostgreSQL keeps in a
+// schema of the same name. A revision is applied once all its statements ran.
+func atlasApplied(r *Runner, q queryer, ctx context.Context, table string, migrations []importedMigration) (map[string]sourceRecord, error) {
+	if r.dialect.Name() == "postgres" && !strings.Contains(table, ".") {
+		table = table + "." + table
+	}
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`
+		SELECT version, type, applied, total, executed_at, execution_time, COALESCE(error, '')
+		FROM %s
+		ORDER BY version;
+	`, table))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	// Revision types are bit flags; 2 marks a baseline
+	const atlasBaseline = 2
+
+	applied := map[string]sourceRecord{}
+	for rows.Next() {
+		var version string
+		var kind, done, total int
+		var executedAt sql.NullTime
+		var executionNanos int64
+		var errorMessage string
+		if err := rows.Scan(&version, &kind, &done, &total, &executedAt, &executionNanos, &errorMessage); err != nil {
+			return nil, fmt.Errorf("read %s: %v", table, err)
+		}
+		record := sourceRecord{AppliedAt: executedAt.Time, ExecutionTime: time.Duration(executionNanos)}
+		switch {
+		case kind&atlasBaseline != 0:
+			for _, m := range migrations {
+				if compareVersions(m.Version, version) <= 0 {
+					applied[normalizeVersion(m.Version)] = record
+				}
+			}
+			continue
+		case errorMessage != "":
+			record.Failed = "failed in Atlas: " + errorMessage
+		case done < total:
+			record.Failed = fmt.Sprintf("partially applied by Atlas (%d of %d statements)", done, total)
+		}
+		applied[normalizeVersion(version)] = record
+	}
+	return applied, rows.Err()
+}
@@ -0,0 +1,225 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeVersion(t *testing.T) {
+	tests := []struct {
+		version, want string
+	}{
+		{"1", "1"},
+		{"0001", "1"},
+		{"0", "0"},
+		{"000", "0"},
+		{"20240101000000", "20240101000000"},
+		{"1.1", "1.1"},
+		{"1_1", "1.1"},
+		{"01.002.0", "1.2.0"},
+		{"2_0_10", "2.0.10"},
+	}
+	for _, tt := range tests {
+		if got := normalizeVersion(tt.version); got != tt.want {
+			t.Errorf("normalizeVersion(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1", "1", 0},
+		{"1", "2", -1},
+		{"2", "1", 1},
+		{"9", "10", -1},
+		{"001", "1", 0},
+		{"1.1", "1_1", 0},
+		{"1.1", "1.10", -1},
+		{"1.2", "1.10", -1},
+		{"1", "1.0", 0},
+		{"1", "1.0.1", -1},
+		{"2", "1.9.9", 1},
+		{"20240101000000", "20231231235959", 1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestImportFilenames(t *testing.T) {
+	tests := []struct {
+		name       string
+		migrations []importedMigration
+		want       []string
+		wantErr    string
+	}{
+		{
+			name: "numeric versions are padded",
+			migrations: []importedMigration{
+				{Source: "1_users.sql", Version: "1", Name: "users"},
+				{Source: "20240101000000_posts.sql", Version: "20240101000000", Name: "posts"},
+			},
+			want: []string{"00000000000001_users.sql", "20240101000000_posts.sql"},
+		},
+		{
+			name: "dotted versions are numbered",
+			migrations: []importedMigration{
+				{Source: "V1__init.sql", Version: "1", Name: "init"},
+				{Source: "V1.1__add_posts.sql", Version: "1.1", Name: "add_posts"},
+				{Source: "V2__seed.sql", Version: "2", Name: "seed"},
+			},
+			want: []string{"00000000000001_init.sql", "00000000000002_add_posts.sql", "00000000000003_seed.sql"},
+		},
+		{
+			name: "names are cleaned",
+			migrations: []importedMigration{
+				{Source: "1_x.sql", Version: "1", Name: "Add Users-Table!"},
+				{Source: "2.sql", Version: "2", Name: "--"},
+			},
+			want: []string{"00000000000001_add_users_table.sql", "00000000000002_migration.sql"},
+		},
+		{
+			name: "versions equal once padded",
+			migrations: []importedMigration{
+				{Source: "1_a.sql", Version: "1", Name: "a"},
+				{Source: "001_b.sql", Version: "001", Name: "b"},
+			},
+			wantErr: "1_a.sql and 001_b.sql have the same version",
+		},
+		{
+			name: "same version",
+			migrations: []importedMigration{
+				{Source: "1_a.sql", Version: "1", Name: "a"},
+				{Source: "01_b.sql", Version: "1", Name: "b"},
+			},
+			wantErr: "1_a.sql and 01_b.sql have the same version",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := importFilenames(tt.migrations)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("importFilenames error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("importFilenames = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImporterFiles(t *testing.T) {
+	tests := []struct {
+		tool     string
+		files    map[string]string
+		want     []importedMigration
+		warnings []string
+		wantErr  string
+	}{
+		{
+			tool: ImportGoose,
+			files: map[string]string{
+				"00002_posts.sql": "-- +goose NO TRANSACTION\n-- +goose Up\nCREATE INDEX CONCURRENTLY i ON posts (id);\n-- +goose Down\nDROP INDEX i;\n",
+				"00001_users.sql": "-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE users (id INT);\n-- +goose StatementEnd\n\n-- +goose Down\nDROP TABLE users;\n",
+				"00003_data.go":   "package migrations\n",
+				"README.md":       "not a migration",
+			},
+			want: []importedMigration{
+				{Source: "00001_users.sql", Version: "00001", Name: "users", Up: "CREATE TABLE users (id INT);", Down: "DROP TABLE users;"},
+				{Source: "00002_posts.sql", Version: "00002", Name: "posts", Up: "CREATE INDEX CONCURRENTLY i ON posts (id);", Down: "DROP INDEX i;", NoTransaction: true},
+			},
+			warnings: []string{"00003_data.go is a Go migration and cannot be converted; port it to SQL by hand"},
+		},
+		{
+			tool:    ImportGoose,
+			files:   map[string]string{"00001_users.sql": "CREATE TABLE users (id INT);\n"},
+			wantErr: "00001_users.sql has no -- +goose Up annotation",
+		},
+		{
+			tool: ImportGolangMigrate,
+			files: map[string]string{
+				"10_posts.up.sql":   "CREATE TABLE posts (id INT);\n",
+				"10_posts.down.sql": "DROP TABLE posts;\n",
+				"9_users.up.sql":    "CREATE TABLE users (id INT);\n",
+				"11_gone.down.sql":  "DROP TABLE gone;\n",
+			},
+			want: []importedMigration{
+				{Source: "9_users.up.sql", Version: "9", Name: "users", Up: "CREATE TABLE users (id INT);"},
+				{Source: "10_posts.up.sql", Version: "10", Name: "posts", Up: "CREATE TABLE posts (id INT);", Down: "DROP TABLE posts;"},
+			},
+			warnings: []string{"version 11 has a down file but no up file and was skipped"},
+		},
+		{
+			tool: ImportFlyway,
+			files: map[string]string{
+				"V1__init.sql":     "CREATE TABLE users (id INT);",
+				"V1_10__later.sql": "CREATE TABLE later (id INT);",
+				"V1.2__posts.sql":  "CREATE TABLE posts (id INT);",
+				"U1.2__posts.sql":  "DROP TABLE posts;",
+				"R__views.sql":     "CREATE VIEW v AS SELECT 1;",
+				"V2__a.sql.conf":   "executeInTransaction=false",
+				"afterMigrate.sql": "SELECT 1;",
+			},
+			want: []importedMigration{
+				{Source: "V1__init.sql", Version: "1", Name: "init", Up: "CREATE TABLE users (id INT);"},
+				{Source: "V1.2__posts.sql", Version: "1.2", Name: "posts", Up: "CREATE TABLE posts (id INT);", Down: "DROP TABLE posts;"},
+				{Source: "V1_10__later.sql", Version: "1.10", Name: "later", Up: "CREATE TABLE later (id INT);"},
+			},
+			warnings: []string{"R__views.sql is a repeatable migration and was skipped; add its SQL to a regular migration"},
+		},
+		{
+			tool: ImportAtlas,
+			files: map[string]string{
+				"20240102000000_posts.sql": "CREATE TABLE posts (id INT);",
+				"20240101000000.sql":       "CREATE TABLE users (id INT);",
+				"atlas.sum":                "h1:abc",
+			},
+			want: []importedMigration{
+				{Source: "20240101000000.sql", Version: "20240101000000", Name: "atlas", Up: "CREATE TABLE users (id INT);"},
+				{Source: "20240102000000_posts.sql", Version: "20240102000000", Name: "posts", Up: "CREATE TABLE posts (id INT);"},
+			},
+			warnings: []string{"Atlas migrations have no down SQL; write the down sections before rolling them back"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, warnings, err := importers[tt.tool].Files(dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Files error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Files =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultLockKey is the advisory lock key used when none is configured
+const DefaultLockKey int64 = 7_262_839_153
+
+// LockKey is the PostgreSQL advisory lock key serializing migrate and
+// rollback runs. Projects sharing a database should use different keys.
+var LockKey = DefaultLockKey
+
+// LockWait is how long to wait for another process to release the lock
+var LockWait = time.Minute
+
+// lockPollInterval is how often a held lock is retried
+const lockPollInterval = time.Second
+
+// acquireLock takes the session-level advisory lock on conn, waiting up to
+// Options.LockWait while another process holds it. The returned function
+// releases the lock and must run before conn is returned to the pool.
+// Dialects without advisory locks get a no-op.
+func (r *Runner) acquireLock(conn *sql.Conn, ctx context.Context) (func(), error) {
+	if !r.dialect.SupportsAdvisoryLocks() {
+		return func() {}, nil
+	}
+
+	key, wait := r.opts.LockKey, r.opts.LockWait
+	deadline := time.Now().Add(wait)
+	waiting := false
+	for {
+		var locked bool
+		if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1);`, key).Scan(&locked); err != nil {
+			return nil, fmt.Errorf("acquire migration lock: %v", err)
+		}
+		if locked {
+			if waiting {
+				r.println("🔓 Migration lock acquired")
+			}
+			return func() {
+				conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, key)
+			}, nil
+		}
+
+		holder := lockHolder(conn, ctx, key)
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("migration lock %d is held by %s (waited %v)", key, holder, wait)
+		}
+		if !waiting {
+			r.printf("⏳ Migration lock %d is held by %s, waiting up to %v...\n", key, holder, wait)
+			waiting = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// lockHolder describes the session holding the advisory lock, from pg_locks and pg_stat_activity
+func lockHolder(conn *sql.Conn, ctx context.Context, key int64) string {
+	// A bigint advisory key is split into classid (high 32 bits) and objid (low 32 bits)
+	classID := uint32(uint64(key) >> 32)
+	objID := uint32(uint64(key))
+
+	var pid int
+	var userName, application, client, state string
+	var since sql.NullTime
+	err := conn.QueryRowContext(ctx, `
+		SELECT a.pid, COALESCE(a.usename, ''), COALESCE(a.application_name, ''),
+		       COALESCE(host(a.client_addr), ''), COALESCE(a.state, ''), a.backend_start
+		FROM pg_locks l
+		JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.granted
+		  AND l.classid::bigint = $1 AND l.objid::bigint = $2 AND l.objsubid = 1
+		LIMIT 1;
+	`, int64(classID), int64(objID)).Scan(&pid, &userName, &application, &client, &state, &since)
+	if err != nil {
+		return "another session"
+	}
+
+	details := []string{}
+	if userName != "" {
+		details = append(details, "user "+userName)
+	}
+	if application != "" {
+		details = append(details, "application "+application)
+	}
+	if client != "" {
+		details = append(details, "client "+client)
+	}
+	if since.Valid {
+		details = append(details, "connected since "+since.Time.Format(time.RFC3339))
+	}
+	if state != "" {
+		details = append(details, "state "+state)
+	}
+	if len(details) == 0 {
+		return fmt.Sprintf("pid %d", pid)
+	}
+	return fmt.Sprintf("pid %d (%s)", pid, strings.Join(details, ", "))
+}
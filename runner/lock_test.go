@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ridoystarlord/migrato/dialect"
+	"modernc.org/sqlite"
+)
+
+// advisoryLocks stands in for PostgreSQL's advisory locks. The runner's
+// session takes and releases keys through pg_try_advisory_lock and
+// pg_advisory_unlock, registered for SQLite below; like PostgreSQL's, its
+// locks are re-entrant. Keys in other are held by another session.
+var advisoryLocks = struct {
+	sync.Mutex
+	taken map[int64]int
+	other map[int64]bool
+}{taken: map[int64]int{}, other: map[int64]bool{}}
+
+func init() {
+	sqlite.MustRegisterScalarFunction("pg_try_advisory_lock", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		advisoryLocks.Lock()
+		defer advisoryLocks.Unlock()
+		key := args[0].(int64)
+		if advisoryLocks.other[key] {
+			return int64(0), nil
+		}
+		advisoryLocks.taken[key]++
+		return int64(1), nil
+	})
+	sqlite.MustRegisterScalarFunction("pg_advisory_unlock", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		advisoryLocks.Lock()
+		defer advisoryLocks.Unlock()
+		key := args[0].(int64)
+		if advisoryLocks.taken[key] == 0 {
+			return int64(0), nil
+		}
+		advisoryLocks.taken[key]--
+		return int64(1), nil
+	})
+}
+
+// lockingSQLite is SQLite with the advisory locks above
+type lockingSQLite struct{ dialect.Dialect }
+
+func (lockingSQLite) SupportsAdvisoryLocks() bool { return true }
+
+// lockRunner returns a runner on a new database taking advisory locks
+func lockRunner(t *testing.T, files fstest.MapFS, opts Options) *Runner {
+	return New(testDB(t), lockingSQLite{dialect.SQLite}, files, opts)
+}
+
+// lockTaken reports whether the runner's session still holds key
+func lockTaken(key int64) bool {
+	advisoryLocks.Lock()
+	defer advisoryLocks.Unlock()
+	return advisoryLocks.taken[key] > 0
+}
+
+// holdLock lets another session hold key until the test ends or release is
+// called
+func holdLock(t *testing.T, key int64) (release func()) {
+	advisoryLocks.Lock()
+	advisoryLocks.other[key] = true
+	advisoryLocks.Unlock()
+	release = func() {
+		advisoryLocks.Lock()
+		delete(advisoryLocks.other, key)
+		advisoryLocks.Unlock()
+	}
+	t.Cleanup(release)
+	return release
+}
+
+func TestLockTimesOut(t *testing.T) {
+	const key = 101
+	holdLock(t, key)
+
+	r := lockRunner(t, mapFS("20240101000000_a.sql", migration("20240101000000", "a")), Options{LockKey: key, LockWait: 10 * time.Millisecond})
+	_, err := r.Migrate(context.Background(), "")
+	if err == nil || !strings.Contains(err.Error(), "migration lock 101 is held by another session") {
+		t.Fatalf("migrate error = %v, want the lock to be reported as held", err)
+	}
+	expectTables(t, r.db)
+}
+
+func TestLockWaitsForHolder(t *testing.T) {
+	const key = 102
+	release := holdLock(t, key)
+	time.AfterFunc(100*time.Millisecond, release)
+
+	r := lockRunner(t, mapFS("20240101000000_a.sql", migration("20240101000000", "a")), Options{LockKey: key, LockWait: 10 * time.Second})
+	if _, err := r.Migrate(context.Background(), ""); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	expectTables(t, r.db, "a")
+	if lockTaken(key) {
+		t.Error("migrate kept the lock after finishing")
+	}
+}
+
+func TestLockWaitCancelled(t *testing.T) {
+	const key = 103
+	holdLock(t, key)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	r := lockRunner(t, mapFS(), Options{LockKey: key, LockWait: time.Minute})
+	conn, err := r.conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := r.acquireLock(conn, ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("acquireLock error = %v, want context.Canceled", err)
+	}
+}
+
+func TestLockReleasedAfterFailure(t *testing.T) {
+	const key = 104
+	r := lockRunner(t, mapFS("20240101000000_bad.sql", migrationSQL("20240101000000", "CREATE TABLE;", "")), Options{LockKey: key})
+	if _, err := r.Migrate(context.Background(), ""); err == nil {
+		t.Fatal("migrate succeeded with invalid SQL")
+	}
+	if lockTaken(key) {
+		t.Error("the lock is still held after the failed migrate")
+	}
+}
+
+func TestLockKeysAreIndependent(t *testing.T) {
+	holdLock(t, 105)
+
+	r := lockRunner(t, mapFS("20240101000000_a.sql", migration("20240101000000", "a")), Options{LockKey: 106, LockWait: 10 * time.Millisecond})
+	if _, err := r.Migrate(context.Background(), ""); err != nil {
+		t.Fatalf("migrate under another key: %v", err)
+	}
+	expectTables(t, r.db, "a")
+}
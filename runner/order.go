@@ -0,0 +1,214 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ridoystarlord/migrato/generator"
+)
+
+// AllowOutOfOrder lets migrate apply pending migrations that sort before the
+// latest applied one, e.g. after merging a branch with older timestamps
+var AllowOutOfOrder bool
+
+// VersionLayout is the timestamp layout of generated migration versions
+const VersionLayout = "20060102150405"
+
+// OutOfOrderMigrations returns the pending migrations whose version sorts
+// before the latest applied migration
+func OutOfOrderMigrations(applied, pending []string) []string {
+	latest := latestVersion(applied)
+	var outOfOrder []string
+	for _, f := range pending {
+		if migrationVersion(f) < latest {
+			outOfOrder = append(outOfOrder, f)
+		}
+	}
+	return outOfOrder
+}
+
+func latestVersion(files []string) string {
+	latest := ""
+	for _, f := range files {
+		if version := migrationVersion(f); version > latest {
+			latest = version
+		}
+	}
+	return latest
+}
+
+// orderedPending returns the pending migrations that take part in ordering.
+// A squash baseline of migrations the database already applied is recorded
+// in their place rather than after them, so it is never out of order.
+func (r *Runner) orderedPending(pending []string, applied map[string]bool) ([]string, error) {
+	var ordered []string
+	for _, f := range pending {
+		squashes, err := r.squashedFiles(f)
+		if err != nil {
+			return nil, err
+		}
+		if len(squashes) > 0 && r.anyApplied(squashes, applied) {
+			continue
+		}
+		ordered = append(ordered, f)
+	}
+	return ordered, nil
+}
+
+// OutOfOrderError is returned when pending migrations sort before the latest
+// applied one and out-of-order migrations are not allowed
+type OutOfOrderError struct {
+	Files  []string
+	Latest string // version of the latest applied migration
+}
+
+func (e *OutOfOrderError) Error() string {
+	return fmt.Sprintf("%d migration(s) out of order", len(e.Files))
+}
+
+// printOutOfOrder reports pending migrations that sort before the latest applied one
+func (r *Runner) printOutOfOrder(outOfOrder []string, applied []string) {
+	r.printf("❌ These pending migrations sort before the latest applied migration (%s):\n", latestVersion(applied))
+	for _, f := range outOfOrder {
+		r.printf("   - %s\n", f)
+	}
+	r.println("💡 Run 'migrato rebase' to renumber them after it, or migrate with --allow-out-of-order.")
+}
+
+// nextVersion returns the first version after v that is not taken. Timestamp
+// versions advance by one second, other numeric versions by one.
+func nextVersion(v string, taken map[string]bool) (string, error) {
+	if t, err := time.Parse(VersionLayout, v); err == nil && len(v) == len(VersionLayout) {
+		for {
+			t = t.Add(time.Second)
+			if next := t.Format(VersionLayout); !taken[next] {
+				return next, nil
+			}
+		}
+	}
+
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("cannot renumber after version %s: not a timestamp or number", v)
+	}
+	for {
+		n++
+		next := fmt.Sprintf("%0*d", len(v), n)
+		if !taken[next] {
+			return next, nil
+		}
+	}
+}
+
+var migrationHeader = regexp.MustCompile(`(?m)^-- Migration: .*$`)
+
+// RebaseMigrations renames pending migrations that sort before the latest
+// applied one so that they follow it, keeping their relative order. Only
+// migrations that were not deployed anywhere else should be rebased.
+func RebaseMigrations(ctx context.Context, dryRun bool) error {
+	r, err := cliRunner()
+	if err != nil {
+		return err
+	}
+	conn, err := r.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	appliedMap, err := r.getAppliedMigrations(conn, ctx)
+	if err != nil {
+		return err
+	}
+	var applied []string
+	for f := range appliedMap {
+		applied = append(applied, f)
+	}
+
+	files, err := r.migrationFiles()
+	if err != nil {
+		return err
+	}
+	var pending []string
+	taken := map[string]bool{}
+	for _, f := range files {
+		taken[migrationVersion(f)] = true
+		if !appliedMap[f] {
+			pending = append(pending, f)
+		}
+	}
+	for _, f := range applied {
+		taken[migrationVersion(f)] = true
+	}
+
+	ordered, err := r.orderedPending(pending, appliedMap)
+	if err != nil {
+		return err
+	}
+	outOfOrder := OutOfOrderMigrations(applied, ordered)
+	if len(outOfOrder) == 0 {
+		fmt.Println("✅ No pending migrations sort before the latest applied one. Nothing to rebase.")
+		return nil
+	}
+	sort.Strings(outOfOrder)
+
+	version := latestVersion(applied)
+	renames := map[string]string{}
+	var order []string
+	for _, f := range outOfOrder {
+		next, err := nextVersion(version, taken)
+		if err != nil {
+			return err
+		}
+		taken[next] = true
+		version = next
+
+		renamed := next + strings.TrimPrefix(f, migrationVersion(f))
+		if _, err := os.Stat(filepath.Join(MigrationsDir, renamed)); err == nil {
+			return fmt.Errorf("cannot rename %s: %s already exists", f, renamed)
+		}
+		renames[f] = renamed
+		order = append(order, f)
+	}
+
+	for _, f := range order {
+		if dryRun {
+			fmt.Printf("🔀 Would rename %s -> %s\n", f, renames[f])
+			continue
+		}
+
+		path := filepath.Join(MigrationsDir, f)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read file %s: %v", f, err)
+		}
+		version := migrationVersion(renames[f])
+		content = migrationHeader.ReplaceAll(content, []byte("-- Migration: "+version))
+
+		target := filepath.Join(MigrationsDir, renames[f])
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return fmt.Errorf("write file %s: %v", renames[f], err)
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("remove file %s: %v", f, err)
+		}
+		fmt.Printf("🔀 Renamed %s -> %s\n", f, renames[f])
+	}
+
+	if dryRun {
+		fmt.Println("(Dry run only. No files were renamed.)")
+		return nil
+	}
+	if err := generator.WriteSum(MigrationsDir); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Rebased %d migration(s) after %s\n", len(order), latestVersion(applied))
+	return nil
+}
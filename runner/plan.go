@@ -0,0 +1,285 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ridoystarlord/migrato/introspect"
+	"github.com/ridoystarlord/migrato/utils"
+)
+
+// planFormat is the version of the plan file layout
+const planFormat = 1
+
+// Plan is a reviewed set of pending migrations. It is saved by plan and
+// executed by apply, which refuses to run when the migrations, the applied
+// set or the schema changed in between.
+type Plan struct {
+	Format            int                `json:"format"`
+	CreatedAt         time.Time          `json:"created_at"`
+	CreatedBy         string             `json:"created_by"`
+	Target            string             `json:"target,omitempty"` // version of migrate --to, empty for all
+	Applied           []string           `json:"applied"`
+	Pending           []PlannedMigration `json:"pending"`
+	SchemaFingerprint string             `json:"schema_fingerprint"`
+}
+
+// PlannedMigration is a pending migration file of a plan
+type PlannedMigration struct {
+	Filename string `json:"filename"`
+	Checksum string `json:"checksum"` // sha256 of the whole file
+}
+
+// PlanMismatchError is returned by apply when the plan no longer matches
+type PlanMismatchError struct {
+	Problems []string
+}
+
+func (e *PlanMismatchError) Error() string {
+	return fmt.Sprintf("plan is stale: %d change(s) since it was created", len(e.Problems))
+}
+
+// WritePlan saves a plan as JSON
+func WritePlan(plan *Plan, path string) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode plan: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("write plan %s: %v", path, err)
+	}
+	return nil
+}
+
+// ReadPlan loads a plan saved by WritePlan
+func ReadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read plan: %v", err)
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("parse plan %s: %v", path, err)
+	}
+	if plan.Format != planFormat {
+		return nil, fmt.Errorf("plan %s has format %d, this version of migrato reads format %d", path, plan.Format, planFormat)
+	}
+	return &plan, nil
+}
+
+// CreatePlan plans the pending migrations of the CLI's database up to target
+func CreatePlan(ctx context.Context, target string) (*Plan, error) {
+	r, err := cliRunner()
+	if err != nil {
+		return nil, err
+	}
+	return r.Plan(ctx, target)
+}
+
+// ApplyPlan applies a plan to the CLI's database
+func ApplyPlan(ctx context.Context, plan *Plan) error {
+	r, err := cliRunner()
+	if err != nil {
+		return err
+	}
+	_, err = r.ApplyPlan(ctx, plan)
+	return err
+}
+
+// Plan records the pending migrations up to target, or all of them when it
+// is empty, together with the applied set and a fingerprint of the schema
+func (r *Runner) Plan(ctx context.Context, target string) (*Plan, error) {
+	conn, err := r.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	failed, err := r.getFailedMigrations(conn, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("check failed migrations: %v", err)
+	}
+	if len(failed) > 0 {
+		r.println("❌ Found failed migrations that need to be resolved:")
+		for _, migration := range failed {
+			r.printf("   - %s: %s\n", migration.MigrationName, migration.ErrorMessage)
+		}
+		return nil, &FailedMigrationsError{Migrations: failed}
+	}
+	issues, err := r.verifyChecksums(conn, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("verify checksums: %v", err)
+	}
+	if len(issues) > 0 {
+		printChecksumIssues(r.out, issues)
+		return nil, &ChecksumError{Issues: issues}
+	}
+
+	applied, err := r.getAppliedMigrations(conn, ctx)
+	if err != nil {
+		return nil, err
+	}
+	files, err := r.migrationFiles()
+	if err != nil {
+		return nil, err
+	}
+	pending, err := pendingMigrations(files, applied, target)
+	if err != nil {
+		return nil, err
+	}
+
+	appliedFiles := sortedKeys(applied)
+	ordered, err := r.orderedPending(pending, applied)
+	if err != nil {
+		return nil, err
+	}
+	if outOfOrder := OutOfOrderMigrations(appliedFiles, ordered); len(outOfOrder) > 0 && !r.opts.AllowOutOfOrder {
+		r.printOutOfOrder(outOfOrder, appliedFiles)
+		return nil, &OutOfOrderError{Files: outOfOrder, Latest: latestVersion(appliedFiles)}
+	}
+
+	planned, err := r.plannedMigrations(pending)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := r.schemaFingerprint(conn, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Plan{
+		Format:            planFormat,
+		CreatedAt:         time.Now().UTC(),
+		CreatedBy:         utils.CurrentUser(),
+		Target:            target,
+		Applied:           appliedFiles,
+		Pending:           planned,
+		SchemaFingerprint: fingerprint,
+	}, nil
+}
+
+// ApplyPlan applies the migrations of a plan. Under the migration lock it
+// first checks that the applied set, the pending files and the schema are
+// exactly as planned, and returns a PlanMismatchError otherwise.
+func (r *Runner) ApplyPlan(ctx context.Context, plan *Plan) ([]Result, error) {
+	return r.migrate(ctx, plan.Target, func(conn *sql.Conn, applied map[string]bool, pending []string) error {
+		problems, err := r.comparePlan(conn, ctx, plan, applied, pending)
+		if err != nil {
+			return err
+		}
+		if len(problems) > 0 {
+			r.println("❌ The database or the migrations changed since the plan was created:")
+			for _, problem := range problems {
+				r.printf("   - %s\n", problem)
+			}
+			r.println("💡 Create a new plan and review it again.")
+			return &PlanMismatchError{Problems: problems}
+		}
+		r.println("✅ Plan matches the database and the migration files")
+		return nil
+	})
+}
+
+// comparePlan lists how the current state differs from a plan
+func (r *Runner) comparePlan(conn *sql.Conn, ctx context.Context, plan *Plan, applied map[string]bool, pending []string) ([]string, error) {
+	var problems []string
+
+	planApplied := map[string]bool{}
+	for _, f := range plan.Applied {
+		planApplied[f] = true
+		if !applied[f] {
+			problems = append(problems, fmt.Sprintf("%s is no longer applied", f))
+		}
+	}
+	for _, f := range sortedKeys(applied) {
+		if !planApplied[f] {
+			problems = append(problems, fmt.Sprintf("%s was applied after the plan", f))
+		}
+	}
+
+	current, err := r.plannedMigrations(pending)
+	if err != nil {
+		return nil, err
+	}
+	currentChecksums := map[string]string{}
+	for _, m := range current {
+		currentChecksums[m.Filename] = m.Checksum
+	}
+	planned := map[string]bool{}
+	for _, m := range plan.Pending {
+		planned[m.Filename] = true
+		checksum, ok := currentChecksums[m.Filename]
+		switch {
+		case !ok && !r.migrationFileExists(m.Filename):
+			problems = append(problems, fmt.Sprintf("%s was removed", m.Filename))
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s is no longer pending", m.Filename))
+		case checksum != m.Checksum:
+			problems = append(problems, fmt.Sprintf("%s was edited", m.Filename))
+		}
+	}
+	for _, m := range current {
+		if !planned[m.Filename] {
+			problems = append(problems, fmt.Sprintf("%s is a new migration", m.Filename))
+		}
+	}
+
+	fingerprint, err := r.schemaFingerprint(conn, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if fingerprint != plan.SchemaFingerprint {
+		problems = append(problems, "the database schema was changed outside the plan")
+	}
+	return problems, nil
+}
+
+// plannedMigrations checksums the given migration files
+func (r *Runner) plannedMigrations(files []string) ([]PlannedMigration, error) {
+	planned := []PlannedMigration{}
+	for _, f := range files {
+		content, err := r.readFile(f)
+		if err != nil {
+			return nil, err
+		}
+		planned = append(planned, PlannedMigration{Filename: f, Checksum: calculateChecksum(content)})
+	}
+	return planned, nil
+}
+
+// schemaFingerprint hashes the introspected schema, leaving out the tables
+// migrato keeps its own records in
+func (r *Runner) schemaFingerprint(conn *sql.Conn, ctx context.Context) (string, error) {
+	tables, err := introspect.Introspect(ctx, conn, r.dialect)
+	if err != nil {
+		return "", fmt.Errorf("introspect schema: %v", err)
+	}
+
+	var schema []introspect.ExistingTable
+	for _, t := range tables {
+		if introspect.IsIgnoredTable(t.TableName) || r.tables.contains(t.TableName) {
+			continue
+		}
+		schema = append(schema, t)
+	}
+
+	data, err := json.Marshal(normalizeSchema(schema))
+	if err != nil {
+		return "", fmt.Errorf("encode schema: %v", err)
+	}
+	return calculateChecksum(string(data)), nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := []string{}
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
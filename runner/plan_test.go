@@ -0,0 +1,176 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestApplyPlan(t *testing.T) {
+	tests := []struct {
+		name string
+		// change alters the database or the files after the plan is created
+		change func(t *testing.T, r *Runner, db *sql.DB, files fstest.MapFS)
+		want   []string // problems; none means the plan applies
+	}{
+		{
+			name:   "unchanged",
+			change: func(t *testing.T, r *Runner, db *sql.DB, files fstest.MapFS) {},
+		},
+		{
+			name: "pending migration edited",
+			change: func(t *testing.T, r *Runner, db *sql.DB, files fstest.MapFS) {
+				files["20240101000001_b.sql"] = &fstest.MapFile{Data: []byte(migration("20240101000001", "b_renamed"))}
+			},
+			want: []string{"20240101000001_b.sql was edited"},
+		},
+		{
+			name: "pending migration removed",
+			change: func(t *testing.T, r *Runner, db *sql.DB, files fstest.MapFS) {
+				delete(files, "20240101000002_c.sql")
+			},
+			want: []string{"20240101000002_c.sql was removed"},
+		},
+		{
+			name: "new migration",
+			change: func(t *testing.T, r *Runner, db *sql.DB, files fstest.MapFS) {
+				files["20240101000003_d.sql"] = &fstest.MapFile{Data: []byte(migration("20240101000003", "d"))}
+			},
+			want: []string{"20240101000003_d.sql is a new migration"},
+		},
+		{
+			name: "migration applied after the plan",
+			change: func(t *testing.T, r *Runner, db *sql.DB, files fstest.MapFS) {
+				if _, err := r.Migrate(context.Background(), "20240101000001"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{
+				"20240101000001_b.sql was applied after the plan",
+				"20240101000001_b.sql is no longer pending",
+				"the database schema was changed outside the plan",
+			},
+		},
+		{
+			name: "applied migration rolled back",
+			change: func(t *testing.T, r *Runner, db *sql.DB, files fstest.MapFS) {
+				if _, err := r.Rollback(context.Background(), 1); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{
+				"20240101000000_a.sql is no longer applied",
+				"20240101000000_a.sql is a new migration",
+				"the database schema was changed outside the plan",
+			},
+		},
+		{
+			name: "schema changed outside migrato",
+			change: func(t *testing.T, r *Runner, db *sql.DB, files fstest.MapFS) {
+				if _, err := db.Exec(`CREATE TABLE stray (id INTEGER);`); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []string{"the database schema was changed outside the plan"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := testDB(t)
+			files := mapFS(
+				"20240101000000_a.sql", migration("20240101000000", "a"),
+				"20240101000001_b.sql", migration("20240101000001", "b"),
+				"20240101000002_c.sql", migration("20240101000002", "c"),
+			)
+			r := testRunner(db, files, Options{AllowOutOfOrder: true})
+			if _, err := r.Migrate(ctx, "20240101000000"); err != nil {
+				t.Fatal(err)
+			}
+
+			plan, err := r.Plan(ctx, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var pending []string
+			for _, m := range plan.Pending {
+				pending = append(pending, m.Filename)
+			}
+			if want := []string{"20240101000001_b.sql", "20240101000002_c.sql"}; !reflect.DeepEqual(pending, want) {
+				t.Fatalf("plan pending = %q, want %q", pending, want)
+			}
+
+			tt.change(t, r, db, files)
+			_, err = r.ApplyPlan(ctx, plan)
+
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("apply plan: %v", err)
+				}
+				expectTables(t, db, "a", "b", "c")
+				return
+			}
+			var mismatch *PlanMismatchError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("apply plan error = %v, want a PlanMismatchError", err)
+			}
+			if !reflect.DeepEqual(mismatch.Problems, tt.want) {
+				t.Errorf("problems = %q, want %q", mismatch.Problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanTarget(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	r := testRunner(db, mapFS(
+		"20240101000000_a.sql", migration("20240101000000", "a"),
+		"20240101000001_b.sql", migration("20240101000001", "b"),
+	), Options{})
+
+	plan, err := r.Plan(ctx, "20240101000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Pending) != 1 || plan.Pending[0].Filename != "20240101000000_a.sql" {
+		t.Fatalf("plan pending = %+v, want only a", plan.Pending)
+	}
+	if _, err := r.ApplyPlan(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+	expectTables(t, db, "a")
+}
+
+func TestPlanFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	plan := &Plan{
+		Format:            planFormat,
+		Target:            "20240101000001",
+		Applied:           []string{"20240101000000_a.sql"},
+		Pending:           []PlannedMigration{{Filename: "20240101000001_b.sql", Checksum: "abc"}},
+		SchemaFingerprint: "def",
+	}
+	if err := WritePlan(plan, path); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadPlan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, plan) {
+		t.Errorf("ReadPlan = %+v, want %+v", read, plan)
+	}
+
+	plan.Format = planFormat + 1
+	if err := WritePlan(plan, path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPlan(path); err == nil {
+		t.Error("ReadPlan accepted a plan of another format")
+	}
+}
@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+)
+
+// RedoMigrations rolls back and re-applies the last steps migrations of the
+// CLI's database
+func RedoMigrations(ctx context.Context, steps int) error {
+	r, err := cliRunner()
+	if err != nil {
+		return err
+	}
+	_, err = r.Redo(ctx, steps)
+	return err
+}
+
+// Redo rolls back the last steps applied migrations and applies them again,
+// in one run under the migration lock, so that their down SQL is exercised
+func (r *Runner) Redo(ctx context.Context, steps int) ([]Result, error) {
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	unlock, err := r.acquireLock(conn, ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := r.ensureMigrationsTable(conn, ctx); err != nil {
+		return nil, fmt.Errorf("ensure migrations table: %v", err)
+	}
+
+	// Most recent first
+	applied, err := r.getAppliedMigrationsOrdered(conn, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 {
+		r.println("✅ No migrations to redo.")
+		return nil, nil
+	}
+	if steps > len(applied) {
+		r.printf("⚠️  Only %d migrations available, redoing all.\n", len(applied))
+		steps = len(applied)
+	}
+	files := applied[:steps]
+	for _, f := range files {
+		if !r.migrationFileExists(f) {
+			return nil, fmt.Errorf("migration file %s not found", f)
+		}
+	}
+
+	r.printf("Redoing %d migration(s)...\n", len(files))
+	results, err := r.rollbackAll(conn, ctx, files)
+	if err != nil {
+		return results, err
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("interrupted before re-applying %s: %w", f, err)
+		}
+		r.printf("Applying: %s\n", f)
+		result, err := r.applyMigration(conn, ctx, f)
+		results = append(results, result)
+		if err != nil {
+			return results, err
+		}
+	}
+
+	r.printf("✅ Redid %d migration(s).\n", len(files))
+	return results, nil
+}
@@ -0,0 +1,176 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ridoystarlord/migrato/utils"
+)
+
+// Resolution says how a failed migration was settled
+type Resolution string
+
+const (
+	// ResolveApplied records the migration as applied; its changes were completed by hand
+	ResolveApplied Resolution = "applied"
+	// ResolveRolledBack clears the failure and leaves the migration pending
+	ResolveRolledBack Resolution = "rolled-back"
+	// ResolveRetry clears the failure and applies the migration again
+	ResolveRetry Resolution = "retry"
+)
+
+// ResolveFailedMigration settles a failed migration of the CLI's database
+func ResolveFailedMigration(ctx context.Context, name string, resolution Resolution, runDown bool) error {
+	r, err := cliRunner()
+	if err != nil {
+		return err
+	}
+	return r.Resolve(ctx, name, resolution, runDown)
+}
+
+// Resolve settles a failed migration, named by file or version, so migrate
+// can continue. The failed row is turned into a successful one or removed;
+// the failure itself stays in migration_logs, next to the resolution and the
+// operator's name. With runDown, the down SQL runs first to clean up a
+// partial apply.
+func (r *Runner) Resolve(ctx context.Context, name string, resolution Resolution, runDown bool) error {
+	switch resolution {
+	case ResolveApplied, ResolveRolledBack, ResolveRetry:
+	default:
+		return fmt.Errorf("unknown resolution '%s'", resolution)
+	}
+	if runDown && resolution == ResolveApplied {
+		return fmt.Errorf("running the down SQL contradicts resolving as applied")
+	}
+
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	unlock, err := r.acquireLock(conn, ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := r.ensureMigrationsTable(conn, ctx); err != nil {
+		return fmt.Errorf("ensure migrations table: %v", err)
+	}
+
+	failed, err := r.getFailedMigrations(conn, ctx)
+	if err != nil {
+		return err
+	}
+	var filename, errorMessage string
+	for _, record := range failed {
+		if record.MigrationName == name || migrationVersion(record.MigrationName) == name {
+			filename = record.MigrationName
+			errorMessage = record.ErrorMessage
+		}
+	}
+	if filename == "" {
+		var names []string
+		for _, record := range failed {
+			names = append(names, record.MigrationName)
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("%s has no failed run to resolve; there are no failed migrations", name)
+		}
+		return fmt.Errorf("%s has no failed run to resolve; failed migrations: %s", name, strings.Join(names, ", "))
+	}
+	if !r.migrationFileExists(filename) && (runDown || resolution != ResolveRolledBack) {
+		return fmt.Errorf("migration file %s not found", filename)
+	}
+
+	r.printf("🔧 Resolving %s as %s\n", filename, resolution)
+	r.printf("   Last error: %s\n", errorMessage)
+
+	if runDown {
+		r.printf("Running down SQL: %s\n", filename)
+		if err := r.runDownSQL(conn, ctx, filename); err != nil {
+			r.logMigrationActivity(conn, ctx, "ERROR", fmt.Sprintf("Cleanup failed: %s", filename), filename, err.Error())
+			return err
+		}
+	}
+
+	var query, checksum string
+	args := []interface{}{filename}
+	if resolution == ResolveApplied {
+		checksum, err = r.fileChecksum(filename)
+		if err != nil {
+			return err
+		}
+		query = fmt.Sprintf(`UPDATE %s SET status = 'success', checksum = $2 WHERE filename = $1 AND status = 'failed';`, r.tables.Migrations)
+		args = append(args, checksum)
+	} else {
+		query = fmt.Sprintf(`DELETE FROM %s WHERE filename = $1 AND status = 'failed';`, r.tables.Migrations)
+	}
+	if _, err := conn.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("resolving %s: %v", filename, err)
+	}
+
+	// A retry records its own events when it applies the migration again
+	switch resolution {
+	case ResolveApplied:
+		err = r.recordEvent(conn, ctx, filename, "up", eventResolved, checksum, 0, "")
+	case ResolveRolledBack:
+		err = r.recordEvent(conn, ctx, filename, "down", eventResolved, "", 0, "")
+	}
+	if err != nil {
+		return err
+	}
+
+	details := fmt.Sprintf("Resolved as %s by %s", resolution, utils.CurrentUser())
+	if runDown {
+		details += " after running the down SQL"
+	}
+	r.logMigrationActivity(conn, ctx, "WARN", fmt.Sprintf("Failed migration resolved: %s", filename), filename, details)
+
+	switch resolution {
+	case ResolveApplied:
+		r.printf("✅ %s recorded as applied\n", filename)
+	case ResolveRolledBack:
+		r.printf("✅ %s is pending again\n", filename)
+	case ResolveRetry:
+		r.printf("Applying: %s\n", filename)
+		if _, err := r.applyMigration(conn, ctx, filename); err != nil {
+			return err
+		}
+		r.printf("✅ %s applied\n", filename)
+	}
+	return nil
+}
+
+// runDownSQL executes a migration's down section without touching
+// schema_migrations, in a transaction unless the file opts out
+func (r *Runner) runDownSQL(conn *sql.Conn, ctx context.Context, filename string) error {
+	sections, err := r.readMigration(filename)
+	if err != nil {
+		return fmt.Errorf("parse migration file %s: %v", filename, err)
+	}
+	directives, err := r.readDirectives(filename)
+	if err != nil {
+		return err
+	}
+
+	if hasDirective(directives, noTransactionDirective) {
+		if err := execScript(conn, conn, ctx, filename, sections.Down, sections.DownLine); err != nil {
+			return fmt.Errorf("executing down SQL: %w", err)
+		}
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction for %s: %v", filename, err)
+	}
+	defer tx.Rollback()
+	if err := execScript(conn, tx, ctx, filename, sections.Down, sections.DownLine); err != nil {
+		return fmt.Errorf("executing down SQL (rolled back): %w", err)
+	}
+	return tx.Commit()
+}
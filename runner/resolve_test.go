@@ -0,0 +1,179 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// partialUp creates table a and then fails; without a transaction, a stays
+const partialUp = "CREATE TABLE a (id INTEGER PRIMARY KEY);\nCREATE TABLE;"
+
+func TestResolve(t *testing.T) {
+	const failing = "20240101000001_a.sql"
+	tests := []struct {
+		name       string
+		resolution Resolution
+		runDown    bool
+		// fix changes the database or the file after the failure
+		fix        func(t *testing.T, r *Runner, files fstest.MapFS)
+		wantErr    string
+		wantTables []string
+		wantStatus string // status of the row left for the failed file, "" when there is none
+		pending    bool   // migrate applies it afterwards
+	}{
+		{
+			name:       "applied after finishing by hand",
+			resolution: ResolveApplied,
+			fix: func(t *testing.T, r *Runner, files fstest.MapFS) {
+				if _, err := r.db.Exec(`CREATE TABLE b (id INTEGER);`); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantTables: []string{"base", "a", "b"},
+			wantStatus: "success",
+		},
+		{
+			name:       "rolled back and fixed",
+			resolution: ResolveRolledBack,
+			runDown:    true,
+			fix: func(t *testing.T, r *Runner, files fstest.MapFS) {
+				files[failing] = &fstest.MapFile{Data: []byte(migration("20240101000001", "a", "no-transaction"))}
+			},
+			wantTables: []string{"base"},
+			pending:    true,
+		},
+		{
+			name:       "retry after fixing the file",
+			resolution: ResolveRetry,
+			runDown:    true,
+			fix: func(t *testing.T, r *Runner, files fstest.MapFS) {
+				files[failing] = &fstest.MapFile{Data: []byte(migration("20240101000001", "a", "no-transaction"))}
+			},
+			wantTables: []string{"base", "a"},
+			wantStatus: "success",
+		},
+		{
+			name:       "retry over a partial apply",
+			resolution: ResolveRetry,
+			fix: func(t *testing.T, r *Runner, files fstest.MapFS) {
+				files[failing] = &fstest.MapFile{Data: []byte(migration("20240101000001", "a", "no-transaction"))}
+			},
+			wantErr:    "table a already exists",
+			wantTables: []string{"base", "a"},
+			wantStatus: "failed",
+		},
+		{
+			name:       "down SQL contradicts applied",
+			resolution: ResolveApplied,
+			runDown:    true,
+			wantErr:    "contradicts resolving as applied",
+			wantTables: []string{"base", "a"},
+			wantStatus: "failed",
+		},
+		{
+			name:       "unknown resolution",
+			resolution: "ignored",
+			wantErr:    "unknown resolution 'ignored'",
+			wantTables: []string{"base", "a"},
+			wantStatus: "failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := testDB(t)
+			files := mapFS(
+				"20240101000000_base.sql", migration("20240101000000", "base"),
+				failing, migrationSQL("20240101000001", partialUp, "DROP TABLE IF EXISTS a;", "no-transaction"),
+			)
+			r := testRunner(db, files, Options{})
+			if _, err := r.Migrate(ctx, ""); err == nil {
+				t.Fatal("migrate succeeded with invalid SQL")
+			}
+			if tt.fix != nil {
+				tt.fix(t, r, files)
+			}
+
+			// The failed migration is named by its version
+			err := r.Resolve(ctx, "20240101000001", tt.resolution, tt.runDown)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolve error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+			expectTables(t, db, tt.wantTables...)
+
+			var status string
+			row := db.QueryRow(`SELECT status FROM schema_migrations WHERE filename = $1;`, failing)
+			if err := row.Scan(&status); err != nil && tt.wantStatus != "" {
+				t.Fatalf("read status: %v", err)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", status, tt.wantStatus)
+			}
+
+			if tt.pending {
+				results, err := r.Migrate(ctx, "")
+				if err != nil {
+					t.Fatalf("migrate after resolving: %v", err)
+				}
+				if len(results) != 1 || results[0].Filename != failing {
+					t.Errorf("migrate after resolving applied %+v, want %s", results, failing)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveWithoutFailure(t *testing.T) {
+	ctx := context.Background()
+	r := testRunner(testDB(t), mapFS("20240101000000_base.sql", migration("20240101000000", "base")), Options{})
+	if _, err := r.Migrate(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	err := r.Resolve(ctx, "20240101000000", ResolveRetry, false)
+	if err == nil || !strings.Contains(err.Error(), "there are no failed migrations") {
+		t.Fatalf("resolve error = %v, want no failed migrations", err)
+	}
+}
+
+func TestResolveRecordsEvent(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	r := testRunner(db, mapFS("20240101000000_a.sql", migrationSQL("20240101000000", partialUp, "DROP TABLE IF EXISTS a;", "no-transaction")), Options{})
+	if _, err := r.Migrate(ctx, ""); err == nil {
+		t.Fatal("migrate succeeded with invalid SQL")
+	}
+	if err := r.Resolve(ctx, "20240101000000_a.sql", ResolveApplied, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var statuses []string
+	rows, err := db.Query(`SELECT status FROM schema_migration_history ORDER BY id;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		if err := rows.Scan(&status); err != nil {
+			t.Fatal(err)
+		}
+		statuses = append(statuses, status)
+	}
+	if got := strings.Join(statuses, ","); got != "failed,resolved" {
+		t.Errorf("history statuses = %s, want failed,resolved", got)
+	}
+
+	var details string
+	if err := db.QueryRow(`SELECT details FROM migration_logs WHERE message = 'Failed migration resolved: 20240101000000_a.sql';`).Scan(&details); err != nil {
+		t.Fatalf("read resolution log: %v", err)
+	}
+	if !strings.HasPrefix(details, "Resolved as applied by ") {
+		t.Errorf("log details = %q", details)
+	}
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Retries is how many times a transactional migration failing with a
+// transient error is run again before giving up
+var Retries = 3
+
+// retryableCodes are the SQLSTATEs of transient failures: lock_not_available,
+// raised by lock_timeout, serialization_failure and deadlock_detected
+var retryableCodes = map[string]bool{
+	"55P03": true,
+	"40001": true,
+	"40P01": true,
+}
+
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+)
+
+// retryableCode returns the SQLSTATE of err and whether it is transient
+func retryableCode(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return "", false
+	}
+	return pgErr.Code, retryableCodes[pgErr.Code]
+}
+
+// retryDelay doubles the wait with every attempt and picks a random point in
+// its upper half, so that concurrent deploys do not retry in lockstep
+func retryDelay(attempt int) time.Duration {
+	backoff := retryMaxDelay
+	if attempt < 16 {
+		backoff = min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
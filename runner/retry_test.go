@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRetryableCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  string
+		retryable bool
+	}{
+		{name: "lock timeout", err: &pgconn.PgError{Code: "55P03"}, wantCode: "55P03", retryable: true},
+		{name: "serialization failure", err: &pgconn.PgError{Code: "40001"}, wantCode: "40001", retryable: true},
+		{name: "deadlock", err: &pgconn.PgError{Code: "40P01"}, wantCode: "40P01", retryable: true},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}, wantCode: "23505"},
+		{name: "not a PostgreSQL error", err: errors.New("table a already exists")},
+		{name: "wrapped", err: fmt.Errorf("apply: %w", &pgconn.PgError{Code: "40P01"}), wantCode: "40P01", retryable: true},
+		{name: "statement error", err: &StatementError{File: "a.sql", Line: 1, Column: 1, Err: &pgconn.PgError{Code: "55P03"}}, wantCode: "55P03", retryable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, retryable := retryableCode(tt.err)
+			if code != tt.wantCode || retryable != tt.retryable {
+				t.Errorf("retryableCode = %q, %v, want %q, %v", code, retryable, tt.wantCode, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		backoff time.Duration
+	}{
+		{1, 500 * time.Millisecond},
+		{2, time.Second},
+		{3, 2 * time.Second},
+		{7, 30 * time.Second},
+		{16, 30 * time.Second},
+		{100, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.attempt), func(t *testing.T) {
+			// The delay is random, so sample it
+			for i := 0; i < 100; i++ {
+				if d := retryDelay(tt.attempt); d < tt.backoff/2 || d > tt.backoff {
+					t.Fatalf("retryDelay(%d) = %v, want between %v and %v", tt.attempt, d, tt.backoff/2, tt.backoff)
+				}
+			}
+		})
+	}
+}
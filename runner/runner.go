@@ -3,23 +3,26 @@ package runner
 import (
 	"context"
 	"crypto/sha256"
+	"database/sql"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"io/fs"
 	"os"
-	"os/user"
-	"path/filepath"
+	"path"
 	"sort"
 	"strings"
 	"time"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/ridoystarlord/migrato/database"
+	"github.com/ridoystarlord/migrato/dialect"
+	"github.com/ridoystarlord/migrato/utils"
 )
 
 // MigrationRecord represents a migration execution record
 type MigrationRecord struct {
 	ID             int
 	MigrationName  string
+	Direction      string // "up" or "down", for events of the history
 	ExecutedAt     time.Time
 	ExecutionTime  time.Duration
 	ExecutedBy     string
@@ -40,63 +43,153 @@ type MigrationLog struct {
 	MigrationName string
 }
 
-func getConn() (*pgx.Conn, context.Context, error) {
-	ctx := context.Background()
-	conn, err := database.GetConnection(ctx)
+// MigrationsDir is the directory the CLI reads migrations from
+var MigrationsDir = "migrations"
+
+// Options configures a Runner
+type Options struct {
+	LockKey          int64         // PostgreSQL advisory lock key, DefaultLockKey when zero
+	LockWait         time.Duration // how long to wait for the lock, a minute when zero
+	AllowOutOfOrder  bool          // apply pending migrations sorting before the latest applied one
+	LockTimeout      time.Duration // PostgreSQL lock_timeout per migration; zero waits indefinitely
+	StatementTimeout time.Duration // PostgreSQL statement_timeout per migration; zero disables it
+	Retries          int           // retries of a transactional migration failing transiently
+	Tables           Tables        // tracking table names, DefaultTables when unset
+	Output           io.Writer     // progress messages; nil discards them
+
+	// ConfirmDestructive confirms pending destructive statements under the
+	// lock before migrate applies them; nil applies them without asking
+	ConfirmDestructive func(destructive []DestructiveStatement) (*Confirmation, error)
+}
+
+// Runner applies the migrations of a file system to a database. The files
+// sit at the root of the file system, squashed ones in squashed/.
+type Runner struct {
+	db      *sql.DB
+	dialect dialect.Dialect
+	fsys    fs.FS
+	opts    Options
+	tables  Tables
+	out     io.Writer
+}
+
+// New returns a Runner applying the migrations in fsys to db
+func New(db *sql.DB, d dialect.Dialect, fsys fs.FS, opts Options) *Runner {
+	if opts.LockKey == 0 {
+		opts.LockKey = DefaultLockKey
+	}
+	if opts.LockWait == 0 {
+		opts.LockWait = time.Minute
+	}
+	out := opts.Output
+	if out == nil {
+		out = io.Discard
+	}
+	return &Runner{db: db, dialect: d, fsys: fsys, opts: opts, tables: opts.Tables.WithDefaults(), out: out}
+}
+
+// Result describes one migration handled by a run
+type Result struct {
+	Filename  string
+	Direction string // "up" or "down"
+	Status    string // ResultApplied, ResultRecorded, ResultRolledBack or ResultFailed
+	Duration  time.Duration
+	Err       error // set for ResultFailed
+}
+
+const (
+	// ResultApplied means the up SQL ran and the migration was recorded
+	ResultApplied = "applied"
+	// ResultRecorded means a squash baseline was recorded without running it
+	ResultRecorded = "recorded"
+	// ResultRolledBack means the down SQL ran and the record was removed
+	ResultRolledBack = "rolled back"
+	// ResultFailed means the migration failed; see Err
+	ResultFailed = "failed"
+)
+
+// StatusReport summarizes the migrations of a Runner against its database
+type StatusReport struct {
+	Applied        []string
+	Pending        []string
+	Failed         []MigrationRecord
+	OutOfOrder     []string // pending migrations sorting before the latest applied one
+	ChecksumIssues []ChecksumIssue
+	History        []MigrationRecord // apply and rollback events, newest first
+}
+
+// FailedMigrationsError is returned when failed migrations must be resolved
+// before migrating
+type FailedMigrationsError struct {
+	Migrations []MigrationRecord
+}
+
+func (e *FailedMigrationsError) Error() string {
+	return "failed migrations detected"
+}
+
+// cliRunner runs the migrations directory against DATABASE_URL with the
+// flag-bound package settings, printing to stdout
+func cliRunner() (*Runner, error) {
+	db, d, err := database.GetDB()
 	if err != nil {
-		return nil, nil, fmt.Errorf("get connection: %v", err)
+		return nil, fmt.Errorf("get connection: %v", err)
 	}
-	return conn, ctx, nil
+	return New(db, d, os.DirFS(MigrationsDir), cliOptions()), nil
 }
 
-func ensureMigrationsTable(conn *pgx.Conn, ctx context.Context) error {
-	fmt.Println("🔧 Ensuring migration tables exist...")
-	
-	// Create enhanced migrations table with history tracking
-	_, err := conn.Exec(ctx, `
-	CREATE TABLE IF NOT EXISTS schema_migrations (
-		id SERIAL PRIMARY KEY,
-		filename TEXT NOT NULL UNIQUE,
-		applied_at TIMESTAMP DEFAULT now(),
-		execution_time INTERVAL,
-		executed_by TEXT,
-		status TEXT DEFAULT 'success',
-		error_message TEXT,
-		checksum TEXT,
-		table_affected TEXT
-	);
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create schema_migrations table: %v", err)
-	}
-	fmt.Println("✅ schema_migrations table ensured")
-
-	// Create migration logs table
-	_, err = conn.Exec(ctx, `
-	CREATE TABLE IF NOT EXISTS migration_logs (
-		id SERIAL PRIMARY KEY,
-		timestamp TIMESTAMP DEFAULT now(),
-		level TEXT NOT NULL,
-		message TEXT NOT NULL,
-		user_name TEXT,
-		details TEXT,
-		migration_name TEXT
-	);
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create migration_logs table: %v", err)
-	}
-	fmt.Println("✅ migration_logs table ensured")
-	
-	return nil
+// cliOptions returns the runner options set by the CLI's flags and configuration
+func cliOptions() Options {
+	return Options{
+		LockKey:          LockKey,
+		LockWait:         LockWait,
+		AllowOutOfOrder:  AllowOutOfOrder,
+		LockTimeout:      LockTimeout,
+		StatementTimeout: StatementTimeout,
+		Retries:          Retries,
+		Tables:           TrackingTables,
+		Output:           os.Stdout,
+
+		ConfirmDestructive: ConfirmDestructive,
+	}
+}
+
+func (r *Runner) printf(format string, args ...interface{}) {
+	fmt.Fprintf(r.out, format, args...)
 }
 
-func getCurrentUser() string {
-	currentUser, err := user.Current()
+func (r *Runner) println(args ...interface{}) {
+	fmt.Fprintln(r.out, args...)
+}
+
+// conn reserves a connection, so that session state such as advisory locks
+// stays with the run
+func (r *Runner) conn(ctx context.Context) (*sql.Conn, error) {
+	conn, err := r.db.Conn(ctx)
 	if err != nil {
-		return "unknown"
+		return nil, fmt.Errorf("get connection: %v", err)
 	}
-	return currentUser.Username
+	return conn, nil
+}
+
+func (r *Runner) ensureMigrationsTable(conn *sql.Conn, ctx context.Context) error {
+	r.println("🔧 Ensuring migration tables exist...")
+
+	// Create or upgrade the migrations, logs and history tables to this release's layout
+	return r.upgradeMetadata(conn, ctx)
+}
+
+// open reserves a connection and ensures the tracking tables exist
+func (r *Runner) open(ctx context.Context) (*sql.Conn, error) {
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ensureMigrationsTable(conn, ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ensure migrations table: %w", err)
+	}
+	return conn, nil
 }
 
 func calculateChecksum(content string) string {
@@ -104,17 +197,17 @@ func calculateChecksum(content string) string {
 	return fmt.Sprintf("%x", hash)
 }
 
-func logMigrationActivity(conn *pgx.Conn, ctx context.Context, level, message, migrationName, details string) error {
-	userName := getCurrentUser()
-	_, err := conn.Exec(ctx, `
-		INSERT INTO migration_logs (level, message, user_name, migration_name, details)
+func (r *Runner) logMigrationActivity(ex execer, ctx context.Context, level, message, migrationName, details string) error {
+	userName := utils.CurrentUser()
+	_, err := ex.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (level, message, user_name, migration_name, details)
 		VALUES ($1, $2, $3, $4, $5)
-	`, level, message, userName, migrationName, details)
+	`, r.tables.Logs), level, message, userName, migrationName, details)
 	return err
 }
 
-func getAppliedMigrations(conn *pgx.Conn, ctx context.Context) (map[string]bool, error) {
-	rows, err := conn.Query(ctx, `SELECT filename FROM schema_migrations WHERE status = 'success';`)
+func (r *Runner) getAppliedMigrations(conn *sql.Conn, ctx context.Context) (map[string]bool, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT filename FROM %s WHERE status = 'success';`, r.tables.Migrations))
 	if err != nil {
 		return nil, fmt.Errorf("query applied migrations: %v", err)
 	}
@@ -131,8 +224,11 @@ func getAppliedMigrations(conn *pgx.Conn, ctx context.Context) (map[string]bool,
 	return applied, nil
 }
 
-func getAppliedMigrationsOrdered(conn *pgx.Conn, ctx context.Context) ([]string, error) {
-	rows, err := conn.Query(ctx, `SELECT filename FROM schema_migrations WHERE status = 'success' ORDER BY applied_at DESC;`)
+func (r *Runner) getAppliedMigrationsOrdered(conn *sql.Conn, ctx context.Context) ([]string, error) {
+	// Migrations applied within the same timestamp, which SQLite stores to the
+	// second, are ordered by version: a squash baseline shares the apply time
+	// of the files it squashes but is recorded after newer migrations
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT filename FROM %s WHERE status = 'success' ORDER BY applied_at DESC, filename DESC, id DESC;`, r.tables.Migrations))
 	if err != nil {
 		return nil, fmt.Errorf("query applied migrations: %v", err)
 	}
@@ -149,8 +245,8 @@ func getAppliedMigrationsOrdered(conn *pgx.Conn, ctx context.Context) ([]string,
 	return applied, nil
 }
 
-func getFailedMigrations(conn *pgx.Conn, ctx context.Context) ([]MigrationRecord, error) {
-	rows, err := conn.Query(ctx, `SELECT filename, error_message FROM schema_migrations WHERE status = 'failed';`)
+func (r *Runner) getFailedMigrations(conn *sql.Conn, ctx context.Context) ([]MigrationRecord, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT filename, COALESCE(error_message, '') FROM %s WHERE status = 'failed';`, r.tables.Migrations))
 	if err != nil {
 		return nil, fmt.Errorf("query failed migrations: %v", err)
 	}
@@ -167,8 +263,9 @@ func getFailedMigrations(conn *pgx.Conn, ctx context.Context) ([]MigrationRecord
 	return failed, nil
 }
 
-func getMigrationFiles() ([]string, error) {
-	files, err := ioutil.ReadDir("migrations")
+// migrationFiles lists the migration files at the root of the file system
+func (r *Runner) migrationFiles() ([]string, error) {
+	files, err := fs.ReadDir(r.fsys, ".")
 	if err != nil {
 		return nil, fmt.Errorf("read migrations dir: %v", err)
 	}
@@ -183,354 +280,786 @@ func getMigrationFiles() ([]string, error) {
 	return filenames, nil
 }
 
-func parseMigrationFile(filename string) (string, string, error) {
-	content, err := os.ReadFile(filepath.Join("migrations", filename))
-	if err != nil {
-		return "", "", fmt.Errorf("read file %s: %v", filename, err)
+// migrationPath locates a migration file, falling back to the squashed folder
+func (r *Runner) migrationPath(filename string) string {
+	if _, err := fs.Stat(r.fsys, filename); err != nil {
+		squashed := path.Join(squashedFolder, filename)
+		if _, err := fs.Stat(r.fsys, squashed); err == nil {
+			return squashed
+		}
 	}
+	return filename
+}
 
-	contentStr := string(content)
-	
-	// Split content into up and down sections
-	parts := strings.Split(contentStr, "-- Down Migration (Rollback)")
-	if len(parts) < 2 {
-		return "", "", fmt.Errorf("migration file %s does not contain rollback section", filename)
+// migrationFileExists reports whether a migration is at the root or in squashed/
+func (r *Runner) migrationFileExists(filename string) bool {
+	_, err := fs.Stat(r.fsys, r.migrationPath(filename))
+	return err == nil
+}
+
+func (r *Runner) readFile(filename string) (string, error) {
+	content, err := fs.ReadFile(r.fsys, r.migrationPath(filename))
+	if err != nil {
+		return "", fmt.Errorf("read file %s: %v", filename, err)
 	}
+	return string(content), nil
+}
 
-	upSection := parts[0]
-	downSection := parts[1]
+func (r *Runner) readMigration(filename string) (migrationSections, error) {
+	content, err := r.readFile(filename)
+	if err != nil {
+		return migrationSections{}, err
+	}
+	return parseMigration(filename, content)
+}
 
-	// Extract SQL from up section (after "-- Up Migration")
-	upParts := strings.Split(upSection, "-- Up Migration")
-	if len(upParts) < 2 {
-		return "", "", fmt.Errorf("migration file %s does not contain up migration section", filename)
+// readDirectives reads the header directives of a migration file
+func (r *Runner) readDirectives(filename string) ([]string, error) {
+	content, err := r.readFile(filename)
+	if err != nil {
+		return nil, err
 	}
+	return parseDirectives(content), nil
+}
 
-	// Extract SQL from down section (after "-- =======================")
-	downParts := strings.Split(downSection, "-- =======================")
-	if len(downParts) < 2 {
-		return "", "", fmt.Errorf("migration file %s does not contain valid rollback section", filename)
+func (r *Runner) parseMigrationFile(filename string) (string, string, error) {
+	sections, err := r.readMigration(filename)
+	if err != nil {
+		return "", "", err
 	}
+	return sections.Up, sections.Down, nil
+}
 
-	upSQL := strings.TrimSpace(upParts[1])
-	downSQL := strings.TrimSpace(downParts[1])
+// execer is implemented by both *sql.Conn and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
 
-	return upSQL, downSQL, nil
+// recordMigration inserts a successful schema_migrations row and appends
+// an up event with the given status to the history
+func (r *Runner) recordMigration(ex execer, ctx context.Context, filename, upSQL string, executionTime time.Duration, event string) error {
+	checksum := calculateChecksum(upSQL)
+	_, err := ex.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (filename, execution_time, executed_by, status, checksum)
+		VALUES ($1, $2, $3, $4, $5)
+	`, r.tables.Migrations), filename, r.dialect.DurationValue(executionTime), utils.CurrentUser(), "success", checksum)
+	if err != nil {
+		return fmt.Errorf("recording migration %s: %v", filename, err)
+	}
+	return r.recordEvent(ex, ctx, filename, "up", event, checksum, executionTime, "")
 }
 
-func applyMigration(conn *pgx.Conn, ctx context.Context, filename string) error {
+// applyMigration runs a migration and records it in one transaction, so a
+// failure leaves neither a half-applied schema nor a tracking row behind.
+// Migrations with the no-transaction directive run statement by statement as
+// written and record a failed row when they break.
+func (r *Runner) applyMigration(conn *sql.Conn, ctx context.Context, filename string) (Result, error) {
 	startTime := time.Now()
-	upSQL, _, err := parseMigrationFile(filename)
+	result := Result{Filename: filename, Direction: "up", Status: ResultFailed}
+	fail := func(err error) (Result, error) {
+		result.Duration = time.Since(startTime)
+		result.Err = err
+		return result, err
+	}
+
+	sections, err := r.readMigration(filename)
 	if err != nil {
-		return fmt.Errorf("parse migration file %s: %v", filename, err)
+		return fail(fmt.Errorf("parse migration file %s: %v", filename, err))
+	}
+	directives, err := r.readDirectives(filename)
+	if err != nil {
+		return fail(err)
 	}
 
 	// Log migration start
-	logMigrationActivity(conn, ctx, "INFO", fmt.Sprintf("Starting migration: %s", filename), filename, "Migration execution started")
+	r.logMigrationActivity(conn, ctx, "INFO", fmt.Sprintf("Starting migration: %s", filename), filename, "Migration execution started")
+
+	if hasDirective(directives, noTransactionDirective) {
+		if err := r.applyWithoutTransaction(conn, ctx, filename, sections, startTime); err != nil {
+			return fail(err)
+		}
+		result.Status = ResultApplied
+		result.Duration = time.Since(startTime)
+		return result, nil
+	}
+
+	// Transient failures such as lock timeouts and deadlocks are retried
+	var executionTime time.Duration
+	for attempt := 1; ; attempt++ {
+		executionTime, err = r.applyInTransaction(conn, ctx, filename, sections)
+		if err == nil {
+			break
+		}
+
+		code, retryable := retryableCode(err)
+		if retryable && attempt <= r.opts.Retries && ctx.Err() == nil {
+			delay := retryDelay(attempt)
+			r.logMigrationActivity(conn, ctx, "WARN", fmt.Sprintf("Retrying migration: %s", filename), filename,
+				fmt.Sprintf("Attempt %d of %d failed with SQLSTATE %s, retrying in %v: %v", attempt, r.opts.Retries+1, code, delay, err))
+			r.printf("⏳ %s failed with SQLSTATE %s (attempt %d of %d), retrying in %v...\n", filename, code, attempt, r.opts.Retries+1, delay.Round(time.Millisecond))
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+				continue
+			}
+		}
+
+		rec, recCtx, done := r.recorder(conn, ctx)
+		defer done()
+		details := err.Error()
+		if attempt > 1 {
+			details = fmt.Sprintf("Attempt %d: %s", attempt, details)
+		}
+		r.logMigrationActivity(rec, recCtx, "ERROR", failureMessage(ctx, "Migration", filename), filename, details)
+		r.recordEvent(rec, recCtx, filename, "up", eventFailed, calculateChecksum(sections.Up), time.Since(startTime), err.Error())
+		return fail(err)
+	}
+
+	// Log success
+	r.logMigrationActivity(conn, ctx, "SUCCESS", fmt.Sprintf("Migration completed: %s", filename), filename, fmt.Sprintf("Execution time: %v", executionTime))
+	result.Status = ResultApplied
+	result.Duration = executionTime
+	return result, nil
+}
+
+// applyInTransaction runs one attempt of a migration and records it in the
+// same transaction. Nothing is left behind when it fails.
+func (r *Runner) applyInTransaction(conn *sql.Conn, ctx context.Context, filename string, sections migrationSections) (time.Duration, error) {
+	startTime := time.Now()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction for %s: %v", filename, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := r.setTimeouts(tx, ctx, true); err != nil {
+		return 0, err
+	}
 
 	// Execute migration
-	_, err = conn.Exec(ctx, upSQL)
+	if err := execScript(conn, tx, ctx, filename, sections.Up, sections.UpLine); err != nil {
+		return 0, fmt.Errorf("executing migration (rolled back): %w", err)
+	}
 	executionTime := time.Since(startTime)
-	
+
+	if err := r.recordMigration(tx, ctx, filename, sections.Up, executionTime, eventSuccess); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing migration %s: %w", filename, err)
+	}
+	return executionTime, nil
+}
+
+func (r *Runner) applyWithoutTransaction(conn *sql.Conn, ctx context.Context, filename string, sections migrationSections, startTime time.Time) error {
+	upSQL := sections.Up
+
+	resetTimeouts, err := r.setTimeouts(conn, ctx, false)
 	if err != nil {
-		// Log failure
-		logMigrationActivity(conn, ctx, "ERROR", fmt.Sprintf("Migration failed: %s", filename), filename, err.Error())
-		
-		// Record failed migration
+		return err
+	}
+
+	// Execute migration; statements that succeeded before a failure stay applied
+	err = execScript(conn, conn, ctx, filename, upSQL, sections.UpLine)
+	resetTimeouts()
+	executionTime := time.Since(startTime)
+
+	if err != nil {
+		// Log failure, even when interrupted
+		rec, recCtx, done := r.recorder(conn, ctx)
+		defer done()
+		r.logMigrationActivity(rec, recCtx, "ERROR", failureMessage(ctx, "Migration", filename), filename, err.Error())
+
+		// Record failed migration; the schema may be partially changed
 		checksum := calculateChecksum(upSQL)
-		userName := getCurrentUser()
-		_, insertErr := conn.Exec(ctx, `
-			INSERT INTO schema_migrations (filename, execution_time, executed_by, status, error_message, checksum)
+		userName := utils.CurrentUser()
+		_, insertErr := rec.ExecContext(recCtx, fmt.Sprintf(`
+			INSERT INTO %s (filename, execution_time, executed_by, status, error_message, checksum)
 			VALUES ($1, $2, $3, $4, $5, $6)
-		`, filename, executionTime, userName, "failed", err.Error(), checksum)
-		
+		`, r.tables.Migrations), filename, r.dialect.DurationValue(executionTime), userName, "failed", err.Error(), checksum)
+
 		if insertErr != nil {
 			return fmt.Errorf("recording failed migration %s: %v", filename, insertErr)
 		}
-		
-		return fmt.Errorf("executing migration %s: %v", filename, err)
+		if err := r.recordEvent(rec, recCtx, filename, "up", eventFailed, checksum, executionTime, err.Error()); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("executing migration: %w", err)
 	}
 
 	// Log success
-	logMigrationActivity(conn, ctx, "SUCCESS", fmt.Sprintf("Migration completed: %s", filename), filename, fmt.Sprintf("Execution time: %v", executionTime))
+	r.logMigrationActivity(conn, ctx, "SUCCESS", fmt.Sprintf("Migration completed: %s", filename), filename, fmt.Sprintf("Execution time: %v", executionTime))
 
 	// Record successful migration
-	checksum := calculateChecksum(upSQL)
-	userName := getCurrentUser()
-	_, err = conn.Exec(ctx, `
-		INSERT INTO schema_migrations (filename, execution_time, executed_by, status, checksum)
-		VALUES ($1, $2, $3, $4, $5)
-	`, filename, executionTime, userName, "success", checksum)
-	
+	return r.recordMigration(conn, ctx, filename, upSQL, executionTime, eventSuccess)
+}
+
+// recordWithoutExecuting inserts a successful schema_migrations row for a
+// migration whose changes are already present in the database
+func (r *Runner) recordWithoutExecuting(ex execer, ctx context.Context, filename string) error {
+	upSQL, _, err := r.parseMigrationFile(filename)
 	if err != nil {
-		return fmt.Errorf("recording migration %s: %v", filename, err)
+		return fmt.Errorf("parse migration file %s: %v", filename, err)
+	}
+	return r.recordMigration(ex, ctx, filename, upSQL, 0, eventRecorded)
+}
+
+// AppliedMigrations returns the filenames of all successfully applied migrations
+func AppliedMigrations(ctx context.Context) ([]string, error) {
+	r, err := cliRunner()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := r.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return r.getAppliedMigrationsOrdered(conn, ctx)
+}
+
+// MarkApplied records a migration as applied without executing it, for
+// migrations describing a schema the database already has
+func MarkApplied(ctx context.Context, filename string) error {
+	r, err := cliRunner()
+	if err != nil {
+		return err
+	}
+	conn, err := r.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	applied, err := r.getAppliedMigrations(conn, ctx)
+	if err != nil {
+		return err
+	}
+	if applied[filename] {
+		return fmt.Errorf("migration %s is already applied", filename)
+	}
+
+	if err := r.recordWithoutExecuting(conn, ctx, filename); err != nil {
+		return err
 	}
 
+	r.logMigrationActivity(conn, ctx, "SUCCESS", fmt.Sprintf("Migration marked as applied: %s", filename), filename, "Recorded without execution")
 	return nil
 }
 
-func rollbackMigration(conn *pgx.Conn, ctx context.Context, filename string) error {
+// rollbackMigration runs the down migration and removes the tracking row in
+// one transaction, unless the migration has the no-transaction directive
+func (r *Runner) rollbackMigration(conn *sql.Conn, ctx context.Context, filename string) (Result, error) {
 	startTime := time.Now()
-	_, downSQL, err := parseMigrationFile(filename)
+	result := Result{Filename: filename, Direction: "down", Status: ResultFailed}
+	fail := func(err error) (Result, error) {
+		result.Duration = time.Since(startTime)
+		result.Err = err
+		return result, err
+	}
+
+	sections, err := r.readMigration(filename)
 	if err != nil {
-		return fmt.Errorf("parse migration file %s: %v", filename, err)
+		return fail(fmt.Errorf("parse migration file %s: %v", filename, err))
+	}
+	directives, err := r.readDirectives(filename)
+	if err != nil {
+		return fail(err)
 	}
 
 	// Log rollback start
-	logMigrationActivity(conn, ctx, "INFO", fmt.Sprintf("Starting rollback: %s", filename), filename, "Rollback execution started")
+	r.logMigrationActivity(conn, ctx, "INFO", fmt.Sprintf("Starting rollback: %s", filename), filename, "Rollback execution started")
+
+	var ex execer = conn
+	var tx *sql.Tx
+	if !hasDirective(directives, noTransactionDirective) {
+		tx, err = conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fail(fmt.Errorf("begin transaction for %s: %v", filename, err))
+		}
+		ex = tx
+	}
+	resetTimeouts, err := r.setTimeouts(ex, ctx, tx != nil)
+	if err != nil {
+		if tx != nil {
+			tx.Rollback()
+		}
+		return fail(err)
+	}
 
 	// Execute rollback
-	_, err = conn.Exec(ctx, downSQL)
+	err = execScript(conn, ex, ctx, filename, sections.Down, sections.DownLine)
+	resetTimeouts()
 	executionTime := time.Since(startTime)
-	
+
 	if err != nil {
-		// Log failure
-		logMigrationActivity(conn, ctx, "ERROR", fmt.Sprintf("Rollback failed: %s", filename), filename, err.Error())
-		return fmt.Errorf("executing rollback for %s: %v", filename, err)
+		if tx != nil {
+			tx.Rollback()
+		}
+		// Log failure, even when interrupted
+		rec, recCtx, done := r.recorder(conn, ctx)
+		defer done()
+		r.logMigrationActivity(rec, recCtx, "ERROR", failureMessage(ctx, "Rollback", filename), filename, err.Error())
+		r.recordEvent(rec, recCtx, filename, "down", eventFailed, calculateChecksum(sections.Up), executionTime, err.Error())
+		return fail(fmt.Errorf("executing rollback: %w", err))
 	}
 
-	// Log success
-	logMigrationActivity(conn, ctx, "SUCCESS", fmt.Sprintf("Rollback completed: %s", filename), filename, fmt.Sprintf("Execution time: %v", executionTime))
-
 	// Remove migration record
-	_, err = conn.Exec(ctx, `DELETE FROM schema_migrations WHERE filename = $1;`, filename)
+	_, err = ex.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE filename = $1;`, r.tables.Migrations), filename)
 	if err != nil {
-		return fmt.Errorf("removing migration record for %s: %v", filename, err)
+		if tx != nil {
+			tx.Rollback()
+		}
+		return fail(fmt.Errorf("removing migration record for %s: %v", filename, err))
+	}
+	if err := r.recordEvent(ex, ctx, filename, "down", eventSuccess, calculateChecksum(sections.Up), executionTime, ""); err != nil {
+		if tx != nil {
+			tx.Rollback()
+		}
+		return fail(err)
 	}
 
-	return nil
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return fail(fmt.Errorf("committing rollback for %s: %v", filename, err))
+		}
+	}
+
+	// Log success
+	r.logMigrationActivity(conn, ctx, "SUCCESS", fmt.Sprintf("Rollback completed: %s", filename), filename, fmt.Sprintf("Execution time: %v", executionTime))
+
+	result.Status = ResultRolledBack
+	result.Duration = executionTime
+	return result, nil
 }
 
-func ApplyMigrations() error {
-	conn, ctx, err := getConn()
+// ApplyMigrations applies all pending migrations
+func ApplyMigrations(ctx context.Context) error {
+	return ApplyMigrationsTo(ctx, "")
+}
+
+// ApplyMigrationsTo applies the pending migrations up to and including the
+// target version. An empty target applies all of them.
+func ApplyMigrationsTo(ctx context.Context, target string) error {
+	r, err := cliRunner()
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	_, err = r.Migrate(ctx, target)
+	return err
+}
+
+// Migrate applies the pending migrations up to and including the target
+// version, or all of them when target is empty. The results list every
+// migration handled, including the one that failed.
+func (r *Runner) Migrate(ctx context.Context, target string) ([]Result, error) {
+	return r.migrate(ctx, target, nil)
+}
+
+// migrate applies the pending migrations up to target. check, when set,
+// vets the pending migrations under the lock before any of them runs.
+func (r *Runner) migrate(ctx context.Context, target string, check func(conn *sql.Conn, applied map[string]bool, pending []string) error) ([]Result, error) {
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Serialize concurrent runs, e.g. several pods migrating at startup
+	unlock, err := r.acquireLock(conn, ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	// Ensure tracking table exists
-	if err := ensureMigrationsTable(conn, ctx); err != nil {
-		return fmt.Errorf("ensure migrations table: %v", err)
+	if err := r.ensureMigrationsTable(conn, ctx); err != nil {
+		return nil, fmt.Errorf("ensure migrations table: %w", err)
 	}
 
 	// Check for failed migrations first
-	failedMigrations, err := getFailedMigrations(conn, ctx)
+	failedMigrations, err := r.getFailedMigrations(conn, ctx)
 	if err != nil {
-		return fmt.Errorf("check failed migrations: %v", err)
+		return nil, fmt.Errorf("check failed migrations: %v", err)
 	}
-	
+
 	if len(failedMigrations) > 0 {
-		fmt.Println("❌ Found failed migrations that need to be resolved:")
+		r.println("❌ Found failed migrations that need to be resolved:")
 		for _, migration := range failedMigrations {
-			fmt.Printf("   - %s: %s\n", migration.MigrationName, migration.ErrorMessage)
+			r.printf("   - %s: %s\n", migration.MigrationName, migration.ErrorMessage)
 		}
-		fmt.Println("💡 Please fix the issues and run 'migrato migrate' again.")
-		return fmt.Errorf("failed migrations detected")
+		r.println("💡 Fix the database, then run 'migrato resolve <migration> --applied|--rolled-back|--retry'.")
+		return nil, &FailedMigrationsError{Migrations: failedMigrations}
+	}
+
+	// Applied migrations must still match their files
+	issues, err := r.verifyChecksums(conn, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("verify checksums: %v", err)
+	}
+	if len(issues) > 0 {
+		printChecksumIssues(r.out, issues)
+		return nil, &ChecksumError{Issues: issues}
 	}
 
 	// Get applied migrations
-	applied, err := getAppliedMigrations(conn, ctx)
+	applied, err := r.getAppliedMigrations(conn, ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Get all migration files
-	files, err := getMigrationFiles()
+	files, err := r.migrationFiles()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var pending []string
-	for _, f := range files {
-		if !applied[f] {
-			pending = append(pending, f)
+	pending, err := pendingMigrations(files, applied, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if check != nil {
+		if err := check(conn, applied, pending); err != nil {
+			return nil, err
 		}
 	}
+	if err := r.confirmDestructive(conn, ctx, pending); err != nil {
+		return nil, err
+	}
 
 	if len(pending) == 0 {
-		fmt.Println("✅ No pending migrations.")
-		return nil
+		if target != "" {
+			r.printf("✅ Already at version %s.\n", target)
+			return nil, nil
+		}
+		r.println("✅ No pending migrations.")
+		return nil, nil
+	}
+
+	// Migrations merged from another branch can sort before ones already applied
+	var appliedFiles []string
+	for f := range applied {
+		appliedFiles = append(appliedFiles, f)
+	}
+	ordered, err := r.orderedPending(pending, applied)
+	if err != nil {
+		return nil, err
+	}
+	if outOfOrder := OutOfOrderMigrations(appliedFiles, ordered); len(outOfOrder) > 0 {
+		if !r.opts.AllowOutOfOrder {
+			r.printOutOfOrder(outOfOrder, appliedFiles)
+			return nil, &OutOfOrderError{Files: outOfOrder, Latest: latestVersion(appliedFiles)}
+		}
+		r.printf("⚠️  Applying %d migration(s) out of order\n", len(outOfOrder))
+	}
+
+	if target != "" {
+		r.printPlan(fmt.Sprintf("Migration Plan: up to %s", target), pending)
 	}
 
-	fmt.Printf("Applying %d migration(s)...\n", len(pending))
+	r.warnUnsupportedTimeouts()
+	r.printf("Applying %d migration(s)...\n", len(pending))
+	var results []Result
 	for _, f := range pending {
-		fmt.Printf("Applying: %s\n", f)
-		if err := applyMigration(conn, ctx, f); err != nil {
-			return err
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("interrupted before %s: %w", f, err)
+		}
+		squashes, err := r.squashedFiles(f)
+		if err != nil {
+			return results, err
+		}
+		if len(squashes) > 0 {
+			squashResults, err := r.applySquash(conn, ctx, f, squashes, applied)
+			results = append(results, squashResults...)
+			if err != nil {
+				return results, err
+			}
+			continue
+		}
+
+		r.printf("Applying: %s\n", f)
+		result, err := r.applyMigration(conn, ctx, f)
+		results = append(results, result)
+		if err != nil {
+			return results, err
 		}
 	}
 
-	fmt.Println("✅ All migrations applied.")
-	return nil
+	if target != "" {
+		r.printf("✅ Migrated to version %s.\n", target)
+		return results, nil
+	}
+	r.println("✅ All migrations applied.")
+	return results, nil
 }
 
-func RollbackMigrations(steps int) error {
-	conn, ctx, err := getConn()
+func RollbackMigrations(ctx context.Context, steps int) error {
+	r, err := cliRunner()
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
+	_, err = r.Rollback(ctx, steps)
+	return err
+}
+
+// Rollback rolls back the given number of most recently applied migrations
+func (r *Runner) Rollback(ctx context.Context, steps int) ([]Result, error) {
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Serialize concurrent runs, e.g. several pods migrating at startup
+	unlock, err := r.acquireLock(conn, ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	// Ensure tracking table exists
-	if err := ensureMigrationsTable(conn, ctx); err != nil {
-		return fmt.Errorf("ensure migrations table: %v", err)
+	if err := r.ensureMigrationsTable(conn, ctx); err != nil {
+		return nil, fmt.Errorf("ensure migrations table: %w", err)
 	}
 
 	// Get applied migrations in reverse order (most recent first)
-	applied, err := getAppliedMigrationsOrdered(conn, ctx)
+	applied, err := r.getAppliedMigrationsOrdered(conn, ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(applied) == 0 {
-		fmt.Println("✅ No migrations to rollback.")
-		return nil
+		r.println("✅ No migrations to rollback.")
+		return nil, nil
 	}
 
 	// Determine how many migrations to rollback
 	toRollback := steps
 	if toRollback > len(applied) {
 		toRollback = len(applied)
-		fmt.Printf("⚠️  Only %d migrations available, rolling back all.\n", len(applied))
+		r.printf("⚠️  Only %d migrations available, rolling back all.\n", len(applied))
 	}
 
 	// Get the migrations to rollback (most recent first)
 	migrationsToRollback := applied[:toRollback]
 
-	fmt.Printf("Rolling back %d migration(s)...\n", toRollback)
-	for _, f := range migrationsToRollback {
-		fmt.Printf("Rolling back: %s\n", f)
-		if err := rollbackMigration(conn, ctx, f); err != nil {
-			return err
-		}
+	r.printf("Rolling back %d migration(s)...\n", toRollback)
+	results, err := r.rollbackAll(conn, ctx, migrationsToRollback)
+	if err != nil {
+		return results, err
 	}
 
-	fmt.Println("✅ Rollback completed.")
-	return nil
+	r.println("✅ Rollback completed.")
+	return results, nil
 }
 
-func Status() ([]string, []string, []MigrationRecord, error) {
-	conn, ctx, err := getConn()
+// RollbackTo rolls back every applied migration newer than the target
+// version, most recent first. A target of "0" rolls back all migrations.
+func RollbackTo(ctx context.Context, target string) error {
+	r, err := cliRunner()
 	if err != nil {
-		return nil, nil, nil, err
+		return err
 	}
-	defer conn.Close(ctx)
+	_, err = r.RollbackTo(ctx, target)
+	return err
+}
 
-	if err := ensureMigrationsTable(conn, ctx); err != nil {
-		return nil, nil, nil, err
+// RollbackTo rolls back every applied migration newer than the target
+// version, most recent first. A target of "0" rolls back all migrations.
+func (r *Runner) RollbackTo(ctx context.Context, target string) ([]Result, error) {
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
 	}
+	defer conn.Close()
 
-	appliedMap, err := getAppliedMigrations(conn, ctx)
+	// Serialize concurrent runs, e.g. several pods migrating at startup
+	unlock, err := r.acquireLock(conn, ctx)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
+	defer unlock()
 
-	var applied []string
-	for k := range appliedMap {
-		applied = append(applied, k)
+	// Ensure tracking table exists
+	if err := r.ensureMigrationsTable(conn, ctx); err != nil {
+		return nil, fmt.Errorf("ensure migrations table: %w", err)
 	}
 
-	files, err := getMigrationFiles()
+	applied, err := r.getAppliedMigrationsOrdered(conn, ctx)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 
+	found := target == "0"
+	var toRollback []string
+	for _, f := range applied {
+		version := migrationVersion(f)
+		if version == target {
+			found = true
+		}
+		if version > target {
+			toRollback = append(toRollback, f)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("version %s is not applied (use 0 to roll back everything)", target)
+	}
+
+	if len(toRollback) == 0 {
+		r.printf("✅ Already at version %s.\n", target)
+		return nil, nil
+	}
+
+	r.printPlan(fmt.Sprintf("Rollback Plan: back to %s", target), toRollback)
+
+	r.printf("Rolling back %d migration(s)...\n", len(toRollback))
+	results, err := r.rollbackAll(conn, ctx, toRollback)
+	if err != nil {
+		return results, err
+	}
+
+	r.printf("✅ Rolled back to version %s.\n", target)
+	return results, nil
+}
+
+// rollbackAll rolls back the files in order, stopping at the first failure
+func (r *Runner) rollbackAll(conn *sql.Conn, ctx context.Context, files []string) ([]Result, error) {
+	r.warnUnsupportedTimeouts()
+	var results []Result
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return results, fmt.Errorf("interrupted before %s: %w", f, err)
+		}
+		r.printf("Rolling back: %s\n", f)
+		result, err := r.rollbackMigration(conn, ctx, f)
+		results = append(results, result)
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// pendingMigrations returns the unapplied files, limited to versions up to
+// and including target when one is given
+func pendingMigrations(files []string, applied map[string]bool, target string) ([]string, error) {
+	found := target == ""
 	var pending []string
 	for _, f := range files {
-		if !appliedMap[f] {
-			pending = append(pending, f)
+		version := migrationVersion(f)
+		if version == target {
+			found = true
+		}
+		if applied[f] || (target != "" && version > target) {
+			continue
 		}
+		pending = append(pending, f)
+	}
+	if !found {
+		return nil, fmt.Errorf("no migration with version %s", target)
+	}
+	return pending, nil
+}
+
+// printPlan lists the files a run is about to apply or roll back
+func (r *Runner) printPlan(title string, files []string) {
+	r.printf("\n================ %s ================\n", title)
+	for i, f := range files {
+		r.printf("  %d. %s\n", i+1, f)
 	}
+	r.println("============================================================")
+}
 
-	// Get failed migrations
-	failed, err := getFailedMigrations(conn, ctx)
+// Status reports the migrations of the CLI's database
+func Status(ctx context.Context) (*StatusReport, error) {
+	r, err := cliRunner()
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
-
-	return applied, pending, failed, nil
+	return r.Status(ctx)
 }
 
-// GetMigrationHistory retrieves migration history with optional filtering
-func GetMigrationHistory(conn *pgx.Conn, limit int, tableFilter string) ([]MigrationRecord, error) {
-	ctx := context.Background()
-	
-	query := `
-		SELECT id, filename, applied_at, execution_time, executed_by, 
-		       status, error_message, checksum, table_affected
-		FROM schema_migrations
-	`
-	
-	var args []interface{}
-	argCount := 0
-	
-	if tableFilter != "" {
-		argCount++
-		query += fmt.Sprintf(" WHERE table_affected ILIKE $%d", argCount)
-		args = append(args, "%"+tableFilter+"%")
+// Status reports the applied, pending and failed migrations together with
+// out-of-order files, checksum mismatches and the history of events
+func (r *Runner) Status(ctx context.Context) (*StatusReport, error) {
+	conn, err := r.open(ctx)
+	if err != nil {
+		return nil, err
 	}
-	
-	query += " ORDER BY applied_at DESC"
-	
-	if limit > 0 {
-		argCount++
-		query += fmt.Sprintf(" LIMIT $%d", argCount)
-		args = append(args, limit)
+	defer conn.Close()
+
+	appliedMap, err := r.getAppliedMigrations(conn, ctx)
+	if err != nil {
+		return nil, err
 	}
-	
-	rows, err := conn.Query(ctx, query, args...)
+
+	report := &StatusReport{}
+	for k := range appliedMap {
+		report.Applied = append(report.Applied, k)
+	}
+	sort.Strings(report.Applied)
+
+	files, err := r.migrationFiles()
 	if err != nil {
-		return nil, fmt.Errorf("query migration history: %v", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	var records []MigrationRecord
-	for rows.Next() {
-		var record MigrationRecord
-		var executionTime *time.Duration
-		
-		err := rows.Scan(
-			&record.ID,
-			&record.MigrationName,
-			&record.ExecutedAt,
-			&executionTime,
-			&record.ExecutedBy,
-			&record.Status,
-			&record.ErrorMessage,
-			&record.Checksum,
-			&record.TableAffected,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("scan migration record: %v", err)
-		}
-		
-		if executionTime != nil {
-			record.ExecutionTime = *executionTime
+	for _, f := range files {
+		if !appliedMap[f] {
+			report.Pending = append(report.Pending, f)
 		}
-		
-		records = append(records, record)
 	}
-	
-	return records, nil
+	ordered, err := r.orderedPending(report.Pending, appliedMap)
+	if err != nil {
+		return nil, err
+	}
+	report.OutOfOrder = OutOfOrderMigrations(report.Applied, ordered)
+
+	// Get failed migrations
+	report.Failed, err = r.getFailedMigrations(conn, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report.ChecksumIssues, err = r.verifyChecksums(conn, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("verify checksums: %v", err)
+	}
+
+	report.History, err = r.history(conn, ctx, historyFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
 }
 
-// GetMigrationLogs retrieves migration logs with optional limit
-func GetMigrationLogs(conn *pgx.Conn, limit int) ([]MigrationLog, error) {
-	ctx := context.Background()
-	
-	query := `
-		SELECT id, timestamp, level, message, user_name, details, migration_name
-		FROM migration_logs
-		ORDER BY timestamp DESC
-	`
+// GetMigrationLogs retrieves migration logs with optional limit, optionally
+// only those of migrations whose filename contains migrationFilter
+func GetMigrationLogs(ctx context.Context, db *sql.DB, limit int, migrationFilter string) ([]MigrationLog, error) {
+	query := fmt.Sprintf(`
+		SELECT id, timestamp, level, message, COALESCE(user_name, ''), COALESCE(details, ''), COALESCE(migration_name, '')
+		FROM %s
+	`, TrackingTables.Logs)
 	
 	var args []interface{}
+	if migrationFilter != "" {
+		query += " WHERE migration_name LIKE $1"
+		args = append(args, "%"+migrationFilter+"%")
+	}
+	
+	query += " ORDER BY timestamp DESC, id DESC"
+	
 	if limit > 0 {
-		query += " LIMIT $1"
+		query += fmt.Sprintf(" LIMIT $%d", len(args)+1)
 		args = append(args, limit)
 	}
 	
-	rows, err := conn.Query(ctx, query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query migration logs: %v", err)
 	}
@@ -559,33 +1088,36 @@ func GetMigrationLogs(conn *pgx.Conn, limit int) ([]MigrationLog, error) {
 	return logs, nil
 }
 
-// PreviewMigrations prints the SQL of all pending migrations without applying them.
-func PreviewMigrations() error {
-	conn, ctx, err := getConn()
+// PreviewMigrations prints the SQL of all pending migrations without applying them
+func PreviewMigrations(ctx context.Context) error {
+	return PreviewMigrationsTo(ctx, "")
+}
+
+// PreviewMigrationsTo prints the SQL of the pending migrations up to the target version
+func PreviewMigrationsTo(ctx context.Context, target string) error {
+	r, err := cliRunner()
 	if err != nil {
 		return err
 	}
-	defer conn.Close(ctx)
-
-	if err := ensureMigrationsTable(conn, ctx); err != nil {
-		return fmt.Errorf("ensure migrations table: %v", err)
+	conn, err := r.open(ctx)
+	if err != nil {
+		return err
 	}
+	defer conn.Close()
 
-	applied, err := getAppliedMigrations(conn, ctx)
+	applied, err := r.getAppliedMigrations(conn, ctx)
 	if err != nil {
 		return err
 	}
 
-	files, err := getMigrationFiles()
+	files, err := r.migrationFiles()
 	if err != nil {
 		return err
 	}
 
-	var pending []string
-	for _, f := range files {
-		if !applied[f] {
-			pending = append(pending, f)
-		}
+	pending, err := pendingMigrations(files, applied, target)
+	if err != nil {
+		return err
 	}
 
 	if len(pending) == 0 {
@@ -596,7 +1128,7 @@ func PreviewMigrations() error {
 	fmt.Println("\n================ DRY RUN: Migration Preview ================")
 	for _, f := range pending {
 		fmt.Printf("\n-- Migration: %s --\n", f)
-		upSQL, downSQL, err := parseMigrationFile(f)
+		upSQL, downSQL, err := r.parseMigrationFile(f)
 		if err != nil {
 			return fmt.Errorf("parse migration file %s: %v", f, err)
 		}
@@ -609,4 +1141,3 @@ func PreviewMigrations() error {
 	fmt.Println("(Dry run only. No migrations were applied.)")
 	return nil
 }
-
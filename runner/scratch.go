@@ -0,0 +1,211 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ridoystarlord/migrato/database"
+	"github.com/ridoystarlord/migrato/dialect"
+	"github.com/ridoystarlord/migrato/introspect"
+)
+
+// scratchSchema is an empty schema that migrations can be replayed into
+// without touching the real tables. On PostgreSQL it is a temporary schema
+// that is the only schema on the search_path of a dedicated connection, so
+// unqualified names never resolve to real tables; statements naming another
+// schema or changing the search_path are refused. On SQLite it is a separate
+// in-memory database.
+type scratchSchema struct {
+	conn    *sql.Conn
+	dialect dialect.Dialect
+	name    string
+	// schemas holds the other schemas of the database, which replayed
+	// statements must not name
+	schemas map[string]bool
+	cleanup func()
+}
+
+func openScratchSchema(ctx context.Context, d dialect.Dialect) (*scratchSchema, error) {
+	switch d {
+	case dialect.Postgres:
+		db, _, err := database.GetDB()
+		if err != nil {
+			return nil, fmt.Errorf("get connection: %v", err)
+		}
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get connection: %v", err)
+		}
+
+		name := fmt.Sprintf("migrato_scratch_%d", time.Now().UnixNano())
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf(`CREATE SCHEMA %q`, name)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("create scratch schema: %v", err)
+		}
+		drop := func() {
+			conn.ExecContext(ctx, fmt.Sprintf(`DROP SCHEMA %q CASCADE`, name))
+			conn.Close()
+		}
+		// public is left off the path: a statement whose table is not in the
+		// scratch schema, such as DROP TABLE IF EXISTS, must not fall through
+		// to the real one. pg_catalog is always searched.
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf(`SET search_path TO %q`, name)); err != nil {
+			drop()
+			return nil, fmt.Errorf("set search_path: %v", err)
+		}
+		schemas, err := otherSchemas(ctx, conn, name)
+		if err != nil {
+			drop()
+			return nil, err
+		}
+
+		return &scratchSchema{
+			conn:    conn,
+			dialect: d,
+			name:    name,
+			schemas: schemas,
+			cleanup: func() {
+				conn.ExecContext(ctx, `SET search_path TO DEFAULT`)
+				conn.ExecContext(ctx, fmt.Sprintf(`DROP SCHEMA IF EXISTS %q CASCADE`, name))
+				conn.Close()
+			},
+		}, nil
+
+	case dialect.SQLite:
+		db, err := sql.Open(d.DriverName(), ":memory:")
+		if err != nil {
+			return nil, fmt.Errorf("open scratch database: %v", err)
+		}
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("open scratch database: %v", err)
+		}
+
+		return &scratchSchema{
+			conn:    conn,
+			dialect: d,
+			cleanup: func() {
+				conn.Close()
+				db.Close()
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("scratch schemas are not supported for %s", d.Name())
+}
+
+// otherSchemas lists the schemas of the database besides the scratch schema
+// and the system catalogs
+func otherSchemas(ctx context.Context, conn *sql.Conn, scratch string) (map[string]bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT nspname FROM pg_namespace WHERE nspname <> $1 AND nspname <> 'information_schema' AND nspname NOT LIKE 'pg\_%'`, scratch)
+	if err != nil {
+		return nil, fmt.Errorf("list schemas: %v", err)
+	}
+	defer rows.Close()
+
+	schemas := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("list schemas: %v", err)
+		}
+		schemas[name] = true
+	}
+	return schemas, rows.Err()
+}
+
+// exec runs a migration script in the scratch schema, statement by statement.
+// Every statement is checked before the first one runs, so a refused script
+// leaves nothing half replayed.
+func (s *scratchSchema) exec(ctx context.Context, filename, script string, firstLine int) error {
+	for _, stmt := range splitStatements(script, firstLine) {
+		if reason := escapesScratch(stmt.SQL, s.schemas); reason != "" {
+			return newStatementError(filename, stmt, fmt.Errorf("cannot be replayed in a scratch schema: %s", reason))
+		}
+	}
+	return execScript(s.conn, s.conn, ctx, filename, script, firstLine)
+}
+
+// escapesScratch reports why a statement could reach outside the scratch
+// schema: a name qualified with one of schemas, or a change of the
+// search_path. String literals and comments are not searched for names.
+func escapesScratch(sql string, schemas map[string]bool) string {
+	// set_config('search_path', ...) names it in a string, so the whole
+	// statement is searched
+	if strings.Contains(strings.ToLower(sql), "search_path") {
+		return "it changes the search_path"
+	}
+
+	i := 0
+	for i < len(sql) {
+		c := sql[i]
+		switch {
+		case strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end == -1 {
+				return ""
+			}
+			i += end
+
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				return ""
+			}
+			i += 2 + end + 2
+
+		case c == '\'':
+			j := i + 1
+			for j < len(sql) {
+				if sql[j] == '\'' {
+					if j+1 < len(sql) && sql[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			i = j + 1
+
+		case c == '"' || (isIdentChar(c) && c != '$' && (c < '0' || c > '9') && (i == 0 || !isIdentChar(sql[i-1]))):
+			var name string
+			if c == '"' {
+				end := strings.IndexByte(sql[i+1:], '"')
+				if end == -1 {
+					return ""
+				}
+				name = sql[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				j := i
+				for j < len(sql) && isIdentChar(sql[j]) {
+					j++
+				}
+				name = strings.ToLower(sql[i:j])
+				i = j
+			}
+			rest := strings.TrimLeft(sql[i:], " \t\r\n")
+			if schemas[name] && strings.HasPrefix(rest, ".") {
+				return fmt.Sprintf("it names schema %s; use unqualified names", name)
+			}
+
+		default:
+			i++
+		}
+	}
+	return ""
+}
+
+// introspect reads the tables the replayed migrations left behind
+func (s *scratchSchema) introspect(ctx context.Context) ([]introspect.ExistingTable, error) {
+	return introspect.IntrospectSchema(ctx, s.conn, s.dialect, s.name)
+}
+
+func (s *scratchSchema) close() {
+	s.cleanup()
+}
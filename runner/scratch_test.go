@@ -0,0 +1,38 @@
+package runner
+
+import "testing"
+
+func TestEscapesScratch(t *testing.T) {
+	schemas := map[string]bool{"public": true, "Audit": true}
+	tests := []struct {
+		name   string
+		sql    string
+		escape bool
+	}{
+		{"unqualified", "DROP TABLE IF EXISTS legacy", false},
+		{"column references", "UPDATE users SET name = u.name FROM users AS u WHERE users.id = u.id", false},
+		{"qualified table", "DROP TABLE public.legacy", true},
+		{"qualified in upper case", "ALTER TABLE PUBLIC.users ADD COLUMN x INT", true},
+		{"quoted schema", `DELETE FROM "public"."users"`, true},
+		{"quoted schema is case sensitive", `CREATE TABLE "Audit".log (id INT)`, true},
+		{"unquoted name folds to lower case", `CREATE TABLE Audit.log (id INT)`, false},
+		{"space before the dot", "DROP TABLE public .legacy", true},
+		{"qualified function", "CREATE TABLE t (id UUID DEFAULT public.uuid_generate_v4())", true},
+		{"inside a DO block", "DO $$ BEGIN DROP TABLE public.legacy; END $$", true},
+		{"in a string", "INSERT INTO notes VALUES ('see public.users')", false},
+		{"in a comment", "SELECT 1 -- was public.users\n", false},
+		{"in a block comment", "SELECT /* public.users */ 1", false},
+		{"schema name as a column", "SELECT public FROM flags", false},
+		{"catalog", "SELECT pg_catalog.now()", false},
+		{"set search_path", "SET search_path TO public", true},
+		{"set_config", "SELECT set_config('search_path', 'public', false)", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := escapesScratch(tt.sql, schemas)
+			if (reason != "") != tt.escape {
+				t.Errorf("escapesScratch(%q) = %q, want escape %v", tt.sql, reason, tt.escape)
+			}
+		})
+	}
+}
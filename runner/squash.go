@@ -0,0 +1,331 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/ridoystarlord/migrato/database"
+	"github.com/ridoystarlord/migrato/dialect"
+	"github.com/ridoystarlord/migrato/diff"
+	"github.com/ridoystarlord/migrato/generator"
+	"github.com/ridoystarlord/migrato/introspect"
+	"github.com/ridoystarlord/migrato/schema"
+)
+
+// nonSchemaStatement matches statements whose effect is not captured by
+// introspecting tables, columns, indexes and foreign keys
+var nonSchemaStatement = regexp.MustCompile(`(?im)^\s*(INSERT|UPDATE|DELETE|COPY|CREATE\s+(OR\s+REPLACE\s+)?(VIEW|MATERIALIZED\s+VIEW|FUNCTION|PROCEDURE|TRIGGER|TYPE|SEQUENCE|DOMAIN|RULE|POLICY))\b`)
+
+// SquashMigrations replaces every migration up to and including the given
+// version with one baseline migration. The baseline is built by replaying the
+// migrations into a scratch schema and introspecting the result. The squashed
+// files are moved to migrations/squashed and listed in the baseline's header,
+// so databases that already applied them record the baseline without running it.
+func SquashMigrations(ctx context.Context, upTo string, dryRun bool) error {
+	d, err := database.GetDialect()
+	if err != nil {
+		return err
+	}
+	// Squashing only reads and writes files; the scratch schema opens its own connection
+	r := New(nil, d, os.DirFS(MigrationsDir), Options{Tables: TrackingTables, Output: os.Stdout})
+
+	files, err := r.migrationFiles()
+	if err != nil {
+		return err
+	}
+
+	end := -1
+	for i, f := range files {
+		if f == upTo || migrationVersion(f) == upTo {
+			end = i
+		}
+	}
+	if end == -1 {
+		return fmt.Errorf("no migration with version %s", upTo)
+	}
+	toSquash := files[:end+1]
+	if len(toSquash) < 2 {
+		return fmt.Errorf("nothing to squash: %s is the first migration", toSquash[0])
+	}
+
+	version := migrationVersion(files[end])
+	baselineName := version + "_squash.sql"
+	for _, f := range toSquash {
+		if f == baselineName {
+			return fmt.Errorf("migrations are already squashed up to %s", version)
+		}
+	}
+
+	fmt.Printf("🔄 Replaying %d migration(s) into a scratch schema...\n", len(toSquash))
+	var scripts []migrationSections
+	var unsupportedFiles []string
+	for _, f := range toSquash {
+		sections, err := r.readMigration(f)
+		if err != nil {
+			return err
+		}
+		if nonSchemaStatement.MatchString(sections.Up) {
+			unsupportedFiles = append(unsupportedFiles, f)
+		}
+		scripts = append(scripts, sections)
+	}
+
+	tables, err := replayMigrations(ctx, d, toSquash, scripts)
+	if err != nil {
+		return err
+	}
+	models := introspect.ToModels(tables)
+	if len(models) == 0 {
+		return fmt.Errorf("the migrations up to %s leave no tables to squash", version)
+	}
+
+	ops := diff.CreateOperations(models, d.SupportsConstraints())
+	upStatements, err := generator.GenerateSQL(d, ops)
+	if err != nil {
+		return fmt.Errorf("generate baseline: %v", err)
+	}
+	downStatements, err := generator.GenerateRollbackSQL(d, ops)
+	if err != nil {
+		return fmt.Errorf("generate baseline rollback: %v", err)
+	}
+
+	// Replay the baseline itself and check it rebuilds the same schema
+	fmt.Println("🔍 Verifying baseline...")
+	baseline := migrationSections{Up: strings.Join(upStatements, "\n"), UpLine: 1}
+	rebuilt, err := replayMigrations(ctx, d, []string{baselineName}, []migrationSections{baseline})
+	if err != nil {
+		return err
+	}
+	differences := compareModels(models, introspect.ToModels(rebuilt))
+
+	var directives []string
+	for _, f := range toSquash {
+		directives = append(directives, "squashes "+f)
+	}
+
+	if len(unsupportedFiles) > 0 {
+		fmt.Println("⚠️  These migrations contain statements a baseline cannot reproduce (data, views, functions, triggers, types):")
+		for _, f := range unsupportedFiles {
+			fmt.Printf("   - %s\n", f)
+		}
+		fmt.Println("💡 Add the missing statements to the baseline by hand before committing it.")
+	}
+	if len(differences) > 0 {
+		fmt.Println("⚠️  The baseline does not rebuild the replayed schema exactly:")
+		for _, difference := range differences {
+			fmt.Printf("   - %s\n", difference)
+		}
+	}
+
+	if dryRun {
+		fmt.Println("\n================ DRY RUN: Squash Preview ================")
+		fmt.Printf("\n-- Baseline: %s --\n", baselineName)
+		for _, directive := range directives {
+			fmt.Println(directivePrefix + directive)
+		}
+		fmt.Println("-- Up Migration SQL --")
+		fmt.Println(strings.Join(upStatements, "\n"))
+		fmt.Println("\n-- Down Migration (Rollback) SQL --")
+		fmt.Println(strings.Join(downStatements, "\n"))
+		fmt.Println("=========================================================")
+		fmt.Println("(Dry run only. No files were written or moved.)")
+		return nil
+	}
+
+	path, err := generator.WriteMigration(generator.MigrationFile{
+		Version:     version,
+		Name:        "squash",
+		Description: fmt.Sprintf("Baseline squashing %d migrations up to %s", len(toSquash), version),
+		Directives:  directives,
+		Up:          upStatements,
+		Down:        downStatements,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(squashedDir(), 0755); err != nil {
+		return fmt.Errorf("creating %s folder: %v", squashedDir(), err)
+	}
+	for _, f := range toSquash {
+		if err := os.Rename(filepath.Join(MigrationsDir, f), filepath.Join(squashedDir(), f)); err != nil {
+			return fmt.Errorf("moving %s to %s: %v", f, squashedDir(), err)
+		}
+	}
+	if err := generator.WriteSum(MigrationsDir); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Squashed %d migration(s) into %s\n", len(toSquash), path)
+	fmt.Printf("📦 Squashed files moved to %s\n", squashedDir())
+	return nil
+}
+
+// replayMigrations runs the given scripts in a scratch schema and returns the resulting tables
+func replayMigrations(ctx context.Context, d dialect.Dialect, names []string, scripts []migrationSections) ([]introspect.ExistingTable, error) {
+	scratch, err := openScratchSchema(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	defer scratch.close()
+
+	for i, script := range scripts {
+		if err := scratch.exec(ctx, names[i], script.Up, script.UpLine); err != nil {
+			return nil, fmt.Errorf("replaying migrations: %v", err)
+		}
+	}
+
+	tables, err := scratch.introspect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("introspecting scratch schema: %v", err)
+	}
+	return tables, nil
+}
+
+// compareModels describes how the rebuilt models differ from the expected ones
+func compareModels(expected, actual []schema.Model) []string {
+	actualByName := map[string]schema.Model{}
+	for _, m := range actual {
+		actualByName[m.TableName] = m
+	}
+
+	var differences []string
+	for _, m := range expected {
+		rebuilt, ok := actualByName[m.TableName]
+		if !ok {
+			differences = append(differences, fmt.Sprintf("table %s is missing", m.TableName))
+			continue
+		}
+		if !reflect.DeepEqual(m.Columns, rebuilt.Columns) {
+			differences = append(differences, fmt.Sprintf("columns of table %s differ", m.TableName))
+		}
+		if !reflect.DeepEqual(m.Indexes, rebuilt.Indexes) {
+			differences = append(differences, fmt.Sprintf("indexes of table %s differ", m.TableName))
+		}
+		delete(actualByName, m.TableName)
+	}
+	for name := range actualByName {
+		differences = append(differences, fmt.Sprintf("unexpected table %s", name))
+	}
+	return differences
+}
+
+// squashedFiles returns the migrations a squash baseline replaces, if any
+func (r *Runner) squashedFiles(filename string) ([]string, error) {
+	directives, err := r.readDirectives(filename)
+	if err != nil {
+		return nil, err
+	}
+	return directiveValues(directives, "squashes"), nil
+}
+
+// anyApplied reports whether the database applied any of the files, looking
+// through nested squash baselines
+func (r *Runner) anyApplied(files []string, applied map[string]bool) bool {
+	for _, f := range files {
+		if applied[f] {
+			return true
+		}
+		nested, err := r.squashedFiles(f)
+		if err == nil && r.anyApplied(nested, applied) {
+			return true
+		}
+	}
+	return false
+}
+
+// applySquash applies a squash baseline. A database that never saw the
+// squashed migrations runs the baseline; one that applied some of them first
+// catches up on the rest and then records the baseline without running it.
+func (r *Runner) applySquash(conn *sql.Conn, ctx context.Context, filename string, squashes []string, applied map[string]bool) ([]Result, error) {
+	if !r.anyApplied(squashes, applied) {
+		r.printf("Applying: %s\n", filename)
+		result, err := r.applyMigration(conn, ctx, filename)
+		return []Result{result}, err
+	}
+
+	var results []Result
+	for _, f := range squashes {
+		if applied[f] {
+			continue
+		}
+		nested, err := r.squashedFiles(f)
+		if err != nil {
+			return results, err
+		}
+		if len(nested) > 0 {
+			nestedResults, err := r.applySquash(conn, ctx, f, nested, applied)
+			results = append(results, nestedResults...)
+			if err != nil {
+				return results, err
+			}
+		} else {
+			r.printf("Applying squashed migration: %s\n", f)
+			result, err := r.applyMigration(conn, ctx, f)
+			results = append(results, result)
+			if err != nil {
+				return results, err
+			}
+		}
+		applied[f] = true
+	}
+
+	r.printf("Recording baseline: %s (already applied as %d migration(s))\n", filename, len(squashes))
+	result := Result{Filename: filename, Direction: "up", Status: ResultRecorded}
+	if err := r.recordSquash(conn, ctx, filename, squashes); err != nil {
+		result.Status = ResultFailed
+		result.Err = err
+		return append(results, result), err
+	}
+	return append(results, result), nil
+}
+
+// recordSquash marks a baseline as applied and the migrations it replaces as
+// superseded, in one transaction
+func (r *Runner) recordSquash(conn *sql.Conn, ctx context.Context, filename string, squashes []string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction for %s: %v", filename, err)
+	}
+	defer tx.Rollback()
+
+	if err := r.recordWithoutExecuting(tx, ctx, filename); err != nil {
+		return err
+	}
+
+	// The baseline takes the place of the squashed migrations, so it keeps
+	// their latest apply time; recording it now would make rollback treat it
+	// as newer than the migrations applied after them
+	placeholders := make([]string, len(squashes))
+	args := []interface{}{filename}
+	for i, f := range squashes {
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+		args = append(args, f)
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %[1]s SET applied_at = COALESCE(
+		(SELECT MAX(applied_at) FROM %[1]s WHERE filename IN (%[2]s) AND status = 'success'), applied_at)
+		WHERE filename = $1;`, r.tables.Migrations, strings.Join(placeholders, ", ")), args...)
+	if err != nil {
+		return fmt.Errorf("dating baseline %s: %v", filename, err)
+	}
+
+	for _, f := range squashes {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status = 'superseded' WHERE filename = $1 AND status = 'success';`, r.tables.Migrations), f)
+		if err != nil {
+			return fmt.Errorf("marking %s as superseded: %v", f, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing baseline %s: %v", filename, err)
+	}
+
+	r.logMigrationActivity(conn, ctx, "SUCCESS", fmt.Sprintf("Baseline recorded: %s", filename), filename, fmt.Sprintf("Supersedes: %s", strings.Join(squashes, ", ")))
+	return nil
+}
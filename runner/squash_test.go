@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// squashedFixture applies three migrations, then returns a runner for the
+// same database whose files squash the first two into a baseline and add a
+// fourth migration, plus any extra name and content pairs
+func squashedFixture(t *testing.T, opts Options) (*Runner, func(extra ...string) *Runner) {
+	t.Helper()
+	db := testDB(t)
+	ctx := context.Background()
+
+	before := testRunner(db, mapFS(
+		"20240101000001_a.sql", migration("20240101000001", "a"),
+		"20240101000002_b.sql", migration("20240101000002", "b"),
+		"20240101000003_c.sql", migration("20240101000003", "c"),
+	), opts)
+	if _, err := before.Migrate(ctx, ""); err != nil {
+		t.Fatalf("migrate before squash: %v", err)
+	}
+
+	after := func(extra ...string) *Runner {
+		return testRunner(db, mapFS(append([]string{
+			"20240101000002_squash.sql", migrationSQL("20240101000002",
+				"CREATE TABLE a (id INTEGER PRIMARY KEY);\nCREATE TABLE b (id INTEGER PRIMARY KEY);",
+				"DROP TABLE b;\nDROP TABLE a;",
+				"squashes 20240101000001_a.sql", "squashes 20240101000002_b.sql"),
+			"squashed/20240101000001_a.sql", migration("20240101000001", "a"),
+			"squashed/20240101000002_b.sql", migration("20240101000002", "b"),
+			"20240101000003_c.sql", migration("20240101000003", "c"),
+			"20240101000004_d.sql", migration("20240101000004", "d"),
+		}, extra...)...), opts)
+	}
+	return before, after
+}
+
+func TestRollbackAfterSquashUndoesNewestMigration(t *testing.T) {
+	ctx := context.Background()
+	before, after := squashedFixture(t, Options{AllowOutOfOrder: true})
+	r := after()
+
+	results, err := r.Migrate(ctx, "")
+	if err != nil {
+		t.Fatalf("migrate after squash: %v", err)
+	}
+	if len(results) != 2 || results[0].Status != ResultRecorded || results[1].Filename != "20240101000004_d.sql" {
+		t.Fatalf("results = %+v, want the baseline recorded and d applied", results)
+	}
+	expectTables(t, before.db, "a", "b", "c", "d")
+
+	if _, err := r.Rollback(ctx, 1); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	expectTables(t, before.db, "a", "b", "c")
+
+	if _, err := r.Rollback(ctx, 1); err != nil {
+		t.Fatalf("second rollback: %v", err)
+	}
+	expectTables(t, before.db, "a", "b")
+}
+
+func TestRedoAfterSquashSkipsBaseline(t *testing.T) {
+	ctx := context.Background()
+	_, after := squashedFixture(t, Options{AllowOutOfOrder: true})
+	r := after()
+	if _, err := r.Migrate(ctx, ""); err != nil {
+		t.Fatalf("migrate after squash: %v", err)
+	}
+
+	results, err := r.Redo(ctx, 2)
+	if err != nil {
+		t.Fatalf("redo: %v", err)
+	}
+	for _, res := range results {
+		if res.Filename == "20240101000002_squash.sql" {
+			t.Fatalf("redo touched the baseline: %+v", results)
+		}
+	}
+	expectTables(t, r.db, "a", "b", "c", "d")
+}
+
+func TestMigrateAfterSquashIsNotOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	_, after := squashedFixture(t, Options{})
+	r := after()
+
+	report, err := r.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if len(report.OutOfOrder) != 0 {
+		t.Fatalf("out of order = %v, want none", report.OutOfOrder)
+	}
+	if _, err := r.Plan(ctx, ""); err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if _, err := r.Migrate(ctx, ""); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	expectTables(t, r.db, "a", "b", "c", "d")
+}
+
+func TestOlderMigrationAfterSquashIsOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	_, after := squashedFixture(t, Options{})
+	r := after("20240101000000_early.sql", migration("20240101000000", "early"))
+
+	_, err := r.Migrate(ctx, "")
+	var outOfOrder *OutOfOrderError
+	if !errors.As(err, &outOfOrder) || len(outOfOrder.Files) != 1 || outOfOrder.Files[0] != "20240101000000_early.sql" {
+		t.Fatalf("migrate error = %v, want only the early migration out of order", err)
+	}
+}
@@ -0,0 +1,340 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+const (
+	upMarker       = "-- Up Migration"
+	downMarker     = "-- Down Migration (Rollback)"
+	sectionDivider = "-- ==="
+	copyEndOfData  = `\.`
+)
+
+// migrationSections holds the up and down SQL of a migration file together
+// with the file line each one starts on
+type migrationSections struct {
+	Up       string
+	Down     string
+	UpLine   int
+	DownLine int
+}
+
+// parseMigration splits a migration file into its up and down sections. The
+// section markers must start a line; the "-- ===" divider under a marker is
+// part of the header and not of the SQL.
+func parseMigration(filename, text string) (migrationSections, error) {
+	upStart, downStart, downLineEnd := -1, -1, -1
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case upStart == -1 && downStart == -1 && strings.HasPrefix(trimmed, upMarker):
+			// The up SQL starts right after the marker text, which keeps
+			// checksums recorded by earlier versions valid
+			upStart = offset + strings.Index(line, upMarker) + len(upMarker)
+		case downStart == -1 && strings.HasPrefix(trimmed, downMarker):
+			downStart = offset
+			downLineEnd = offset + len(line)
+		case downStart != -1 && downLineEnd == offset && strings.HasPrefix(trimmed, sectionDivider):
+			downLineEnd = offset + len(line)
+		}
+		offset += len(line)
+	}
+
+	if downStart == -1 {
+		return migrationSections{}, fmt.Errorf("migration file %s does not contain rollback section", filename)
+	}
+	if upStart == -1 {
+		return migrationSections{}, fmt.Errorf("migration file %s does not contain up migration section", filename)
+	}
+
+	sections := migrationSections{}
+	sections.Up, sections.UpLine = trimWithLine(text, upStart, downStart)
+	sections.Down, sections.DownLine = trimWithLine(text, downLineEnd, len(text))
+	return sections, nil
+}
+
+// trimWithLine trims text[start:end] and returns it with the line it starts on
+func trimWithLine(text string, start, end int) (string, int) {
+	section := text[start:end]
+	trimmed := strings.TrimLeft(section, " \t\r\n")
+	first := start + len(section) - len(trimmed)
+	return strings.TrimSpace(trimmed), strings.Count(text[:first], "\n") + 1
+}
+
+// statement is one SQL statement of a migration
+type statement struct {
+	SQL      string
+	Line     int    // file line the statement starts on
+	Column   int    // column the statement starts at
+	CopyData string // rows following a COPY ... FROM stdin statement
+}
+
+var copyFromStdin = regexp.MustCompile(`(?is)^COPY\b.*\bFROM\s+STDIN\b`)
+
+var dollarTag = regexp.MustCompile(`^\$([A-Za-z_\x80-\xff][A-Za-z0-9_\x80-\xff]*)?\$`)
+
+// splitStatements splits a SQL script into statements on semicolons outside
+// of string literals, quoted identifiers, dollar-quoted bodies and comments.
+// The data block following COPY ... FROM stdin, up to the \. line, is kept
+// with its statement. Statements holding nothing but comments are dropped.
+// firstLine is the file line the script starts on.
+func splitStatements(script string, firstLine int) []statement {
+	var statements []statement
+	start := -1 // offset of the first non-comment character of the current statement
+	line, lineStart := firstLine, 0
+	startLine, startColumn := 0, 0
+
+	begin := func(i int) {
+		if start == -1 {
+			start = i
+			startLine = line
+			startColumn = i - lineStart + 1
+		}
+	}
+	// advance moves i to j, keeping track of line numbers
+	advance := func(i, j int) int {
+		for k := i; k < j && k < len(script); k++ {
+			if script[k] == '\n' {
+				line++
+				lineStart = k + 1
+			}
+		}
+		return j
+	}
+
+	i := 0
+	for i < len(script) {
+		c := script[i]
+		switch {
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end == -1 {
+				end = len(script) - i
+			}
+			i = advance(i, i+end)
+
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			depth, j := 0, i
+			for j < len(script) {
+				if strings.HasPrefix(script[j:], "/*") {
+					depth++
+					j += 2
+				} else if strings.HasPrefix(script[j:], "*/") {
+					depth--
+					j += 2
+					if depth == 0 {
+						break
+					}
+				} else {
+					j++
+				}
+			}
+			i = advance(i, j)
+
+		case c == '\'':
+			begin(i)
+			escapes := i > 0 && (script[i-1] == 'E' || script[i-1] == 'e') && (i == 1 || !isIdentChar(script[i-2]))
+			j := i + 1
+			for j < len(script) {
+				if escapes && script[j] == '\\' {
+					j += 2
+					continue
+				}
+				if script[j] == '\'' {
+					if j+1 < len(script) && script[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					j++
+					break
+				}
+				j++
+			}
+			i = advance(i, j)
+
+		case c == '"':
+			begin(i)
+			j := i + 1
+			for j < len(script) {
+				if script[j] == '"' {
+					if j+1 < len(script) && script[j+1] == '"' {
+						j += 2
+						continue
+					}
+					j++
+					break
+				}
+				j++
+			}
+			i = advance(i, j)
+
+		case c == '$' && (i == 0 || !isIdentChar(script[i-1])) && dollarTag.MatchString(script[i:]):
+			begin(i)
+			tag := dollarTag.FindString(script[i:])
+			end := strings.Index(script[i+len(tag):], tag)
+			j := len(script)
+			if end != -1 {
+				j = i + len(tag) + end + len(tag)
+			}
+			i = advance(i, j)
+
+		case c == ';':
+			if start != -1 {
+				stmt := statement{SQL: script[start:i], Line: startLine, Column: startColumn}
+				i++
+				if copyFromStdin.MatchString(stmt.SQL) {
+					i = readCopyData(script, i, &stmt, advance)
+				}
+				statements = append(statements, stmt)
+				start = -1
+				continue
+			}
+			i++
+
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i = advance(i, i+1)
+
+		default:
+			begin(i)
+			i++
+		}
+	}
+
+	if start != -1 {
+		if sql := strings.TrimSpace(script[start:]); sql != "" {
+			statements = append(statements, statement{SQL: sql, Line: startLine, Column: startColumn})
+		}
+	}
+	return statements
+}
+
+// readCopyData reads the data lines following a COPY ... FROM stdin statement
+// up to the \. terminator and returns the offset after it
+func readCopyData(script string, i int, stmt *statement, advance func(i, j int) int) int {
+	// The data starts on the line after the statement
+	if end := strings.IndexByte(script[i:], '\n'); end != -1 {
+		i = advance(i, i+end+1)
+	} else {
+		return len(script)
+	}
+
+	var data strings.Builder
+	for i < len(script) {
+		end := strings.IndexByte(script[i:], '\n')
+		next := len(script)
+		if end != -1 {
+			next = i + end + 1
+		}
+		row := script[i:next]
+		i = advance(i, next)
+		if strings.TrimRight(row, "\r\n") == copyEndOfData {
+			break
+		}
+		data.WriteString(row)
+	}
+	stmt.CopyData = data.String()
+	return i
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// StatementError locates a failed statement in its migration file
+type StatementError struct {
+	File      string
+	Line      int
+	Column    int
+	Statement string
+	Err       error
+}
+
+func (e *StatementError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:%d:%d: %v", e.File, e.Line, e.Column, e.Err)
+
+	var pgErr *pgconn.PgError
+	if errors.As(e.Err, &pgErr) {
+		if pgErr.Detail != "" {
+			fmt.Fprintf(&b, "\n   DETAIL: %s", pgErr.Detail)
+		}
+		if pgErr.Hint != "" {
+			fmt.Fprintf(&b, "\n   HINT: %s", pgErr.Hint)
+		}
+		if pgErr.Where != "" {
+			fmt.Fprintf(&b, "\n   WHERE: %s", pgErr.Where)
+		}
+	}
+
+	statement := e.Statement
+	if lines := strings.Split(statement, "\n"); len(lines) > 5 {
+		statement = strings.Join(lines[:5], "\n") + "\n..."
+	}
+	fmt.Fprintf(&b, "\n   statement: %s", strings.ReplaceAll(statement, "\n", "\n              "))
+	return b.String()
+}
+
+func (e *StatementError) Unwrap() error { return e.Err }
+
+// newStatementError points the error at the statement, or at the exact
+// position inside it when PostgreSQL reports one
+func newStatementError(filename string, stmt statement, err error) *StatementError {
+	line, column := stmt.Line, stmt.Column
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Position > 0 {
+		runes := []rune(stmt.SQL)
+		if position := int(pgErr.Position) - 1; position <= len(runes) {
+			before := string(runes[:position])
+			if newlines := strings.Count(before, "\n"); newlines > 0 {
+				line += newlines
+				column = len([]rune(before[strings.LastIndex(before, "\n")+1:])) + 1
+			} else {
+				column += len(runes[:position])
+			}
+		}
+	}
+
+	return &StatementError{File: filename, Line: line, Column: column, Statement: stmt.SQL, Err: err}
+}
+
+// execScript runs a script statement by statement on ex, which is either conn
+// itself or a transaction on it. COPY ... FROM stdin data is streamed through
+// the PostgreSQL connection.
+func execScript(conn *sql.Conn, ex execer, ctx context.Context, filename, script string, firstLine int) error {
+	for _, stmt := range splitStatements(script, firstLine) {
+		var err error
+		if stmt.CopyData != "" || copyFromStdin.MatchString(stmt.SQL) {
+			err = copyFrom(conn, ctx, stmt)
+		} else {
+			_, err = ex.ExecContext(ctx, stmt.SQL)
+		}
+		if err != nil {
+			return newStatementError(filename, stmt, err)
+		}
+	}
+	return nil
+}
+
+// copyFrom runs COPY ... FROM stdin with the statement's data block
+func copyFrom(conn *sql.Conn, ctx context.Context, stmt statement) error {
+	return conn.Raw(func(driverConn interface{}) error {
+		pgConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("COPY ... FROM stdin is only supported on PostgreSQL")
+		}
+		_, err := pgConn.Conn().PgConn().CopyFrom(ctx, strings.NewReader(stmt.CopyData), stmt.SQL)
+		return err
+	})
+}
@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []statement
+	}{
+		{
+			name:   "empty",
+			script: "",
+		},
+		{
+			name:   "only comments",
+			script: "-- nothing here;\n/* nor; here */\n",
+		},
+		{
+			name:   "two statements",
+			script: "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);",
+			want: []statement{
+				{SQL: "CREATE TABLE a (id INT)", Line: 1, Column: 1},
+				{SQL: "CREATE TABLE b (id INT)", Line: 2, Column: 1},
+			},
+		},
+		{
+			name:   "last statement without semicolon",
+			script: "SELECT 1;\n  SELECT 2\n",
+			want: []statement{
+				{SQL: "SELECT 1", Line: 1, Column: 1},
+				{SQL: "SELECT 2", Line: 2, Column: 3},
+			},
+		},
+		{
+			name:   "semicolons in comments",
+			script: "-- drop; later\nSELECT 1; -- trailing; comment\n/* block; /* nested; */ still; */ SELECT 2;",
+			want: []statement{
+				{SQL: "SELECT 1", Line: 2, Column: 1},
+				{SQL: "SELECT 2", Line: 3, Column: 35},
+			},
+		},
+		{
+			name:   "semicolons in strings and identifiers",
+			script: `INSERT INTO "odd;name" VALUES ('a;b', 'it''s;', E'\';');`,
+			want: []statement{
+				{SQL: `INSERT INTO "odd;name" VALUES ('a;b', 'it''s;', E'\';')`, Line: 1, Column: 1},
+			},
+		},
+		{
+			name: "dollar-quoted function body",
+			script: "CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n  NEW.updated_at = now();\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;\n" +
+				"DO $body$ BEGIN PERFORM 1; END $body$;",
+			want: []statement{
+				{SQL: "CREATE FUNCTION f() RETURNS trigger AS $$\nBEGIN\n  NEW.updated_at = now();\n  RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql", Line: 1, Column: 1},
+				{SQL: "DO $body$ BEGIN PERFORM 1; END $body$", Line: 7, Column: 1},
+			},
+		},
+		{
+			name:   "positional parameters are not dollar quotes",
+			script: "PREPARE p AS SELECT $1;\nSELECT 2;",
+			want: []statement{
+				{SQL: "PREPARE p AS SELECT $1", Line: 1, Column: 1},
+				{SQL: "SELECT 2", Line: 2, Column: 1},
+			},
+		},
+		{
+			name:   "copy block",
+			script: "COPY users (id, name) FROM stdin;\n1\ta;b\n2\tc\n\\.\nSELECT 1;",
+			want: []statement{
+				{SQL: "COPY users (id, name) FROM stdin", Line: 1, Column: 1, CopyData: "1\ta;b\n2\tc\n"},
+				{SQL: "SELECT 1", Line: 5, Column: 1},
+			},
+		},
+		{
+			name:   "copy to file has no data block",
+			script: "COPY users TO '/tmp/users.csv';\nSELECT 1;",
+			want: []statement{
+				{SQL: "COPY users TO '/tmp/users.csv'", Line: 1, Column: 1},
+				{SQL: "SELECT 1", Line: 2, Column: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitStatements(tt.script, 1)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitStatementsFirstLine(t *testing.T) {
+	got := splitStatements("\n\nSELECT 1;", 10)
+	if len(got) != 1 || got[0].Line != 12 {
+		t.Fatalf("splitStatements = %#v, want one statement on line 12", got)
+	}
+}
+
+func TestParseMigration(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    migrationSections
+		wantErr string
+	}{
+		{
+			// The up divider stays in the SQL, which keeps old checksums valid
+			name: "generated layout",
+			text: migrationSQL("20240101000000", "CREATE TABLE a (id INT);", "DROP TABLE a;"),
+			want: migrationSections{Up: "-- ============\nCREATE TABLE a (id INT);", UpLine: 5, Down: "DROP TABLE a;", DownLine: 10},
+		},
+		{
+			name: "directives in the header",
+			text: migrationSQL("20240101000000", "SELECT 1;", "SELECT 2;", "no-transaction"),
+			want: migrationSections{Up: "-- ============\nSELECT 1;", UpLine: 6, Down: "SELECT 2;", DownLine: 11},
+		},
+		{
+			name: "markers inside the SQL are not sections",
+			text: "-- Up Migration\nSELECT '-- Down Migration (Rollback)';\n-- Down Migration (Rollback)\nSELECT 2;\n",
+			want: migrationSections{Up: "SELECT '-- Down Migration (Rollback)';", UpLine: 2, Down: "SELECT 2;", DownLine: 4},
+		},
+		{
+			name: "short down divider",
+			text: "-- Up Migration\nSELECT 1;\n-- Down Migration (Rollback)\n-- ===\nSELECT 2;\n",
+			want: migrationSections{Up: "SELECT 1;", UpLine: 2, Down: "SELECT 2;", DownLine: 5},
+		},
+		{
+			name: "empty down section",
+			text: "-- Up Migration\nSELECT 1;\n-- Down Migration (Rollback)\n",
+			want: migrationSections{Up: "SELECT 1;", UpLine: 2, Down: "", DownLine: 4},
+		},
+		{
+			name:    "no down section",
+			text:    "-- Up Migration\nSELECT 1;\n",
+			wantErr: "does not contain rollback section",
+		},
+		{
+			name:    "no up section",
+			text:    "SELECT 1;\n-- Down Migration (Rollback)\nSELECT 2;\n",
+			wantErr: "does not contain up migration section",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMigration("test.sql", tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseMigration error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("parseMigration = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package runner
+
+// Tables names the tables a Runner records migrations, their log and their
+// history in, and the table seed records applied seed files in
+type Tables struct {
+	Migrations string
+	Logs       string
+	History    string
+	Seeds      string
+}
+
+// DefaultTables are the tracking tables used unless configured otherwise
+var DefaultTables = Tables{Migrations: "schema_migrations", Logs: "migration_logs", History: "schema_migration_history", Seeds: "schema_seeds"}
+
+// TrackingTables are the tracking tables the CLI uses
+var TrackingTables = DefaultTables
+
+// WithDefaults fills unset table names with the default ones
+func (t Tables) WithDefaults() Tables {
+	if t.Migrations == "" {
+		t.Migrations = DefaultTables.Migrations
+	}
+	if t.Logs == "" {
+		t.Logs = DefaultTables.Logs
+	}
+	if t.History == "" {
+		t.History = DefaultTables.History
+	}
+	if t.Seeds == "" {
+		t.Seeds = DefaultTables.Seeds
+	}
+	return t
+}
+
+// Version names the table recording the layout version of the others
+func (t Tables) Version() string {
+	return t.Migrations + "_version"
+}
+
+// contains reports whether name is one of the tracking tables
+func (t Tables) contains(name string) bool {
+	return name == t.Migrations || name == t.Logs || name == t.History || name == t.Seeds || name == t.Version()
+}
@@ -0,0 +1,319 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ridoystarlord/migrato/database"
+)
+
+// Target is one schema or database a fan-out run handles. Each target keeps
+// its own tracking tables: schema targets in the schema, URL targets in
+// their database.
+type Target struct {
+	Name   string // the schema, or the URL without its password
+	URL    string
+	Schema string // empty for URL targets
+}
+
+// TargetResult is the outcome of a fan-out run on one target
+type TargetResult struct {
+	Target   Target
+	Results  []Result      // migrations handled by migrate and rollback
+	Status   *StatusReport // set by status
+	Output   string        // what the run printed
+	Duration time.Duration
+	Err      error
+}
+
+// SchemaTargets returns a target for each schema of the configured
+// PostgreSQL database, in order and without duplicates
+func SchemaTargets(schemas []string) ([]Target, error) {
+	var targets []Target
+	seen := map[string]bool{}
+	for _, schema := range schemas {
+		if schema == "" || seen[schema] {
+			continue
+		}
+		seen[schema] = true
+		connStr, err := database.SchemaURL(schema)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, Target{Name: schema, URL: connStr, Schema: schema})
+	}
+	return targets, nil
+}
+
+// URLTargets returns a target for each connection URL, in order and without
+// duplicates
+func URLTargets(urls []string) []Target {
+	var targets []Target
+	seen := map[string]bool{}
+	for _, connStr := range urls {
+		if connStr == "" || seen[connStr] {
+			continue
+		}
+		seen[connStr] = true
+		name := connStr
+		if u, err := url.Parse(connStr); err == nil {
+			name = u.Redacted()
+		}
+		targets = append(targets, Target{Name: name, URL: os.ExpandEnv(connStr)})
+	}
+	return targets
+}
+
+// ReadTargetList reads one schema or URL per line from a file, or from stdin
+// when path is "-". Blank lines and lines starting with # are skipped.
+func ReadTargetList(path string) ([]string, error) {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		f, err = os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("read targets: %v", err)
+		}
+		defer f.Close()
+	}
+
+	var list []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read targets from %s: %v", path, err)
+	}
+	return list, nil
+}
+
+// QueryTargetList runs a query on the configured database and returns the
+// value of every row, e.g. the schemas of a tenants table
+func QueryTargetList(ctx context.Context, query string) ([]string, error) {
+	db, _, err := database.GetDB()
+	if err != nil {
+		return nil, fmt.Errorf("get connection: %v", err)
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query targets: %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("query targets: %v", err)
+	}
+	if len(columns) != 1 {
+		return nil, fmt.Errorf("the targets query must return one column, it returns %d", len(columns))
+	}
+	var list []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("scan target: %v", err)
+		}
+		list = append(list, value)
+	}
+	return list, rows.Err()
+}
+
+// schemaLockKey derives the advisory lock key of a schema target, so that
+// schemas of one database migrate in parallel while runs on the same schema
+// stay serialized
+func schemaLockKey(key int64, schema string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(schema))
+	return key ^ int64(h.Sum64())
+}
+
+// targetRunner opens a target and returns a runner for it with the CLI's
+// settings, printing to out. release closes the target's connections.
+func targetRunner(ctx context.Context, target Target, out *bytes.Buffer) (r *Runner, release func(), err error) {
+	db, d, err := database.Open(ctx, target.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts := cliOptions()
+	opts.Output = out
+	if target.Schema != "" {
+		// A missing schema would leave the tables to the next schema on the path
+		var current string
+		if err := db.QueryRowContext(ctx, `SELECT COALESCE(current_schema(), '');`).Scan(&current); err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("read current schema: %v", err)
+		}
+		if current != target.Schema {
+			db.Close()
+			return nil, nil, fmt.Errorf("schema %s does not exist", target.Schema)
+		}
+		opts.LockKey = schemaLockKey(opts.LockKey, target.Schema)
+	}
+	return New(db, d, os.DirFS(MigrationsDir), opts), func() { db.Close() }, nil
+}
+
+// fanOut runs fn on every target, at most parallel at a time. A failing
+// target does not stop the others. Each target's output is printed in one
+// block when it finishes; the results keep the order of targets.
+func fanOut(ctx context.Context, targets []Target, parallel int, fn func(r *Runner, result *TargetResult) error) []TargetResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]TargetResult, len(targets))
+	slots := make(chan struct{}, parallel)
+	var printing sync.Mutex
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			result := &results[i]
+			result.Target = target
+			start := time.Now()
+			var out bytes.Buffer
+			if err := ctx.Err(); err != nil {
+				result.Err = err
+			} else if r, release, err := targetRunner(ctx, target, &out); err != nil {
+				result.Err = err
+			} else {
+				result.Err = fn(r, result)
+				release()
+			}
+			result.Duration = time.Since(start)
+			result.Output = out.String()
+
+			printing.Lock()
+			defer printing.Unlock()
+			icon := "✅"
+			if result.Err != nil {
+				icon = "❌"
+			}
+			fmt.Printf("\n%s %s (%v)\n", icon, target.Name, result.Duration.Round(time.Millisecond))
+			for _, line := range strings.Split(strings.TrimRight(result.Output, "\n"), "\n") {
+				if line != "" {
+					fmt.Println("   " + line)
+				}
+			}
+			if result.Err != nil {
+				fmt.Println("   ❌", result.Err)
+			}
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+// confirmTarget writes the confirmation of a protected run to the target's
+// migration_logs before the run changes it. A nil confirmation is skipped.
+func (r *Runner) confirmTarget(ctx context.Context, confirmation *Confirmation) error {
+	if confirmation == nil {
+		return nil
+	}
+	conn, err := r.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return r.recordConfirmation(conn, ctx, *confirmation)
+}
+
+// MigrateTargets applies the pending migrations up to version, or all of
+// them when it is empty, on every target, at most parallel at a time. Like
+// a single migrate, a target with destructive statements pending logs the
+// confirmation of ConfirmDestructive before it is migrated.
+func MigrateTargets(ctx context.Context, targets []Target, parallel int, version string) []TargetResult {
+	fmt.Printf("🔄 Migrating %d target(s), %d at a time...\n", len(targets), parallel)
+	return fanOut(ctx, targets, parallel, func(r *Runner, result *TargetResult) (err error) {
+		result.Results, err = r.Migrate(ctx, version)
+		return err
+	})
+}
+
+// RollbackTargets rolls back steps migrations on every target, or every
+// migration newer than version when it is set, at most parallel at a time.
+// A confirmation is logged on each target before it is rolled back.
+func RollbackTargets(ctx context.Context, targets []Target, parallel, steps int, version string, confirmation *Confirmation) []TargetResult {
+	fmt.Printf("🔄 Rolling back %d target(s), %d at a time...\n", len(targets), parallel)
+	return fanOut(ctx, targets, parallel, func(r *Runner, result *TargetResult) error {
+		if err := r.confirmTarget(ctx, confirmation); err != nil {
+			return err
+		}
+		var err error
+		if version != "" {
+			result.Results, err = r.RollbackTo(ctx, version)
+		} else {
+			result.Results, err = r.Rollback(ctx, steps)
+		}
+		return err
+	})
+}
+
+// StatusTargets reports the migrations of every target, at most parallel at
+// a time. Targets whose applied migrations no longer match their files fail.
+func StatusTargets(ctx context.Context, targets []Target, parallel int) []TargetResult {
+	fmt.Printf("🔍 Checking %d target(s), %d at a time...\n", len(targets), parallel)
+	return fanOut(ctx, targets, parallel, func(r *Runner, result *TargetResult) error {
+		report, err := r.Status(ctx)
+		if err != nil {
+			return err
+		}
+		result.Status = report
+		r.printf("%d applied, %d pending, %d failed\n", len(report.Applied), len(report.Pending), len(report.Failed))
+		if len(report.ChecksumIssues) > 0 {
+			return &ChecksumError{Issues: report.ChecksumIssues}
+		}
+		return nil
+	})
+}
+
+// PrintTargetSummary prints how many targets succeeded and lists the ones
+// that failed. It returns the number of failed targets.
+func PrintTargetSummary(results []TargetResult) int {
+	var failed []TargetResult
+	handled, pending := 0, 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+		if result.Status != nil && len(result.Status.Pending) > 0 {
+			pending++
+		}
+		for _, res := range result.Results {
+			if res.Status != ResultFailed {
+				handled++
+			}
+		}
+	}
+
+	fmt.Println("\n============================================================")
+	fmt.Printf("📊 %d target(s): %d succeeded, %d failed", len(results), len(results)-len(failed), len(failed))
+	if handled > 0 {
+		fmt.Printf(", %d migration(s) handled", handled)
+	}
+	fmt.Println()
+	if pending > 0 {
+		fmt.Printf("🕒 %d target(s) have pending migrations\n", pending)
+	}
+	for _, result := range failed {
+		fmt.Printf("   ❌ %s: %v\n", result.Target.Name, result.Err)
+	}
+	return len(failed)
+}
@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ridoystarlord/migrato/dialect"
+)
+
+// sqliteTarget creates a SQLite database running setup and returns it as a
+// URL target, with a handle to inspect it
+func sqliteTarget(t *testing.T, name, setup string) (Target, *sql.DB) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name+".db")
+	connStr := "sqlite://" + path
+	db, err := sql.Open(dialect.SQLite.DriverName(), dialect.SQLite.DataSourceName(connStr))
+	if err != nil {
+		t.Fatalf("open %s: %v", name, err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if setup != "" {
+		if _, err := db.Exec(setup); err != nil {
+			t.Fatalf("set up %s: %v", name, err)
+		}
+	}
+	return Target{Name: name, URL: connStr}, db
+}
+
+// useMigrationsDir points the CLI's migrations directory at files for the test
+func useMigrationsDir(t *testing.T, pairs ...string) {
+	t.Helper()
+	dir := t.TempDir()
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if err := os.WriteFile(filepath.Join(dir, pairs[i]), []byte(pairs[i+1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saved := MigrationsDir
+	MigrationsDir = dir
+	t.Cleanup(func() { MigrationsDir = saved })
+}
+
+func TestMigrateTargetsIsolatesFailures(t *testing.T) {
+	useMigrationsDir(t,
+		"20240101000000_a.sql", migration("20240101000000", "a"),
+		"20240101000001_b.sql", migration("20240101000001", "b"),
+	)
+	first, firstDB := sqliteTarget(t, "first", "")
+	// A table created by hand makes the first migration fail on this target
+	broken, brokenDB := sqliteTarget(t, "broken", "CREATE TABLE a (id INTEGER PRIMARY KEY);")
+	unreachable := Target{Name: "unreachable", URL: "oracle://db.example.com/app"}
+	last, lastDB := sqliteTarget(t, "last", "")
+	targets := []Target{first, broken, unreachable, last}
+
+	for _, parallel := range []int{1, 4} {
+		results := MigrateTargets(context.Background(), targets, parallel, "")
+		if len(results) != len(targets) {
+			t.Fatalf("parallel %d: %d results for %d targets", parallel, len(results), len(targets))
+		}
+		for i, result := range results {
+			if result.Target.Name != targets[i].Name {
+				t.Errorf("parallel %d: result %d is for %s, want %s", parallel, i, result.Target.Name, targets[i].Name)
+			}
+		}
+
+		if err := results[1].Err; err == nil || !strings.Contains(err.Error(), "table a already exists") {
+			t.Errorf("parallel %d: broken target error = %v", parallel, err)
+		}
+		if err := results[2].Err; err == nil || !strings.Contains(err.Error(), "cannot detect database dialect") {
+			t.Errorf("parallel %d: unreachable target error = %v", parallel, err)
+		}
+		if failed := PrintTargetSummary(results); failed != 2 {
+			t.Errorf("parallel %d: summary counts %d failed targets, want 2", parallel, failed)
+		}
+	}
+
+	// The targets around the failing ones are migrated all the same
+	expectTables(t, firstDB, "a", "b")
+	expectTables(t, lastDB, "a", "b")
+	expectTables(t, brokenDB, "a")
+}
+
+func TestMigrateTargetsCancelled(t *testing.T) {
+	useMigrationsDir(t, "20240101000000_a.sql", migration("20240101000000", "a"))
+	target, db := sqliteTarget(t, "tenant", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := MigrateTargets(ctx, []Target{target}, 1, "")
+	if len(results) != 1 || !errors.Is(results[0].Err, context.Canceled) {
+		t.Fatalf("results = %+v, want the target cancelled", results)
+	}
+	expectTables(t, db)
+}
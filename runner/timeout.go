@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LockTimeout bounds how long a migration statement waits for a table lock,
+// so a blocked ALTER TABLE fails instead of hanging. Zero waits indefinitely.
+var LockTimeout time.Duration
+
+// StatementTimeout bounds how long a single migration statement may run.
+// Zero disables it.
+var StatementTimeout time.Duration
+
+// recordTimeout bounds the writes recording the outcome of an interrupted run
+const recordTimeout = 10 * time.Second
+
+// setTimeouts applies the lock and statement timeouts to a migration. Inside
+// a transaction they are set with SET LOCAL and end with it; a migration
+// running without one sets them on the session and resets them afterwards.
+func (r *Runner) setTimeouts(ex execer, ctx context.Context, inTransaction bool) (func(), error) {
+	if !r.dialect.SupportsTimeouts() {
+		return func() {}, nil
+	}
+
+	scope := "SET LOCAL"
+	if !inTransaction {
+		scope = "SET"
+	}
+	settings := []struct {
+		name    string
+		timeout time.Duration
+	}{
+		{"lock_timeout", r.opts.LockTimeout},
+		{"statement_timeout", r.opts.StatementTimeout},
+	}
+
+	var set []string
+	reset := func() {
+		for _, name := range set {
+			ex.ExecContext(context.Background(), "RESET "+name)
+		}
+	}
+	for _, setting := range settings {
+		if setting.timeout <= 0 {
+			continue
+		}
+		query := fmt.Sprintf("%s %s = %d", scope, setting.name, setting.timeout.Milliseconds())
+		if _, err := ex.ExecContext(ctx, query); err != nil {
+			reset()
+			return nil, fmt.Errorf("set %s: %v", setting.name, err)
+		}
+		if !inTransaction {
+			set = append(set, setting.name)
+		}
+	}
+	return reset, nil
+}
+
+// warnUnsupportedTimeouts tells the user the configured timeouts have no effect
+func (r *Runner) warnUnsupportedTimeouts() {
+	if !r.dialect.SupportsTimeouts() && (r.opts.LockTimeout > 0 || r.opts.StatementTimeout > 0) {
+		r.printf("⚠️  Lock and statement timeouts are not supported on %s and are ignored\n", r.dialect.Name())
+	}
+}
+
+// recorder returns where to record the outcome of a migration. Once the run
+// is interrupted its context is cancelled and the driver may have closed
+// conn, so the outcome is written through the pool with a detached context.
+func (r *Runner) recorder(conn *sql.Conn, ctx context.Context) (execer, context.Context, context.CancelFunc) {
+	if ctx.Err() == nil {
+		return conn, ctx, func() {}
+	}
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	return r.db, recordCtx, cancel
+}
+
+// failureMessage describes a failed step, telling interrupts apart from errors
+func failureMessage(ctx context.Context, step, filename string) string {
+	if ctx.Err() != nil {
+		return fmt.Sprintf("%s interrupted: %s", step, filename)
+	}
+	return fmt.Sprintf("%s failed: %s", step, filename)
+}
@@ -0,0 +1,342 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/ridoystarlord/migrato/utils"
+)
+
+// metadataUpgrade brings the tracking tables from the previous layout
+// version to Version. Statements inspects the database and returns the SQL
+// still needed, so a step is a no-op on tables that already have its changes.
+type metadataUpgrade struct {
+	Version     int
+	Description string
+	Statements  func(r *Runner, q queryer, ctx context.Context) ([]string, error)
+}
+
+// metadataUpgrades lists every layout of the tracking tables in order. New
+// releases append a step; released steps never change.
+var metadataUpgrades = []metadataUpgrade{
+	{
+		Version:     1,
+		Description: "create the migrations and logs tables",
+		Statements: func(r *Runner, q queryer, ctx context.Context) ([]string, error) {
+			ddl := r.trackingDDL()
+			return []string{ddl[0], ddl[1]}, nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "add the execution, status and checksum columns missing from tables created by early releases",
+		Statements: func(r *Runner, q queryer, ctx context.Context) ([]string, error) {
+			return r.missingColumns(q, ctx, earlyReleaseColumns)
+		},
+	},
+	{
+		Version:     3,
+		Description: "create the history table and copy the applied migrations into it",
+		Statements: func(r *Runner, q queryer, ctx context.Context) ([]string, error) {
+			return []string{r.trackingDDL()[2], r.backfillHistorySQL()}, nil
+		},
+	},
+}
+
+// MetadataVersion is the layout version of the tracking tables this release writes
+func MetadataVersion() int {
+	return metadataUpgrades[len(metadataUpgrades)-1].Version
+}
+
+// PendingUpgrade is a layout upgrade of the tracking tables not yet applied
+type PendingUpgrade struct {
+	Version     int
+	Description string
+	Statements  []string
+}
+
+// MetadataTooNewError is returned when the tracking tables were upgraded by a
+// newer release of migrato than the running one
+type MetadataTooNewError struct {
+	Version, Supported int
+}
+
+func (e *MetadataTooNewError) Error() string {
+	return fmt.Sprintf("tracking tables are at version %d, newer than version %d supported by this migrato; upgrade migrato", e.Version, e.Supported)
+}
+
+// trackingDDL returns the statements creating the migrations, logs and history tables
+func (r *Runner) trackingDDL() []string {
+	ddl := r.dialect.TrackingTablesDDL(r.tables.Migrations, r.tables.Logs, r.tables.History)
+	for i := range ddl {
+		ddl[i] = strings.TrimSpace(ddl[i])
+	}
+	return ddl
+}
+
+// versionTableDDL creates the table recording each upgrade of the layout
+func (r *Runner) versionTableDDL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version INTEGER PRIMARY KEY,
+	description TEXT NOT NULL,
+	upgraded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	upgraded_by TEXT
+);`, r.tables.Version())
+}
+
+// trackingVersion reads the layout version of the tracking tables. Tables
+// created before the layout was versioned, or none at all, are version 0.
+func (r *Runner) trackingVersion(q queryer, ctx context.Context) (int, error) {
+	exists, err := r.tableExists(q, ctx, r.tables.Version())
+	if err != nil || !exists {
+		return 0, err
+	}
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`SELECT COALESCE(MAX(version), 0) FROM %s;`, r.tables.Version()))
+	if err != nil {
+		return 0, fmt.Errorf("read tracking table version: %v", err)
+	}
+	defer rows.Close()
+
+	var version int
+	if rows.Next() {
+		if err := rows.Scan(&version); err != nil {
+			return 0, fmt.Errorf("read tracking table version: %v", err)
+		}
+	}
+	return version, rows.Err()
+}
+
+// tableExists reports whether a table is visible on the search path
+func (r *Runner) tableExists(q queryer, ctx context.Context, table string) (bool, error) {
+	query := `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1;`
+	if r.dialect.Name() == "sqlite" {
+		query = `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = $1;`
+	}
+	rows, err := q.QueryContext(ctx, query, table)
+	if err != nil {
+		return false, fmt.Errorf("look up table %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	var count int
+	if rows.Next() {
+		if err := rows.Scan(&count); err != nil {
+			return false, fmt.Errorf("look up table %s: %v", table, err)
+		}
+	}
+	return count > 0, rows.Err()
+}
+
+// trackingColumn is a column an upgrade adds to a tracking table, with its
+// definition in each dialect. Columns that must be set carry a default, so
+// rows written before the upgrade stay valid.
+type trackingColumn struct {
+	Table    func(t Tables) string
+	Name     string
+	Postgres string
+	SQLite   string
+}
+
+func migrationsTable(t Tables) string { return t.Migrations }
+func logsTable(t Tables) string       { return t.Logs }
+
+// earlyReleaseColumns are the columns version 2 adds to tables created by
+// early releases. The key, filename, applied_at, level and message columns
+// are part of every released layout.
+var earlyReleaseColumns = []trackingColumn{
+	{Table: migrationsTable, Name: "execution_time", Postgres: "INTERVAL", SQLite: "INTEGER"},
+	{Table: migrationsTable, Name: "executed_by", Postgres: "TEXT", SQLite: "TEXT"},
+	{Table: migrationsTable, Name: "status", Postgres: "TEXT DEFAULT 'success'", SQLite: "TEXT DEFAULT 'success'"},
+	{Table: migrationsTable, Name: "error_message", Postgres: "TEXT", SQLite: "TEXT"},
+	{Table: migrationsTable, Name: "checksum", Postgres: "TEXT", SQLite: "TEXT"},
+	{Table: migrationsTable, Name: "table_affected", Postgres: "TEXT", SQLite: "TEXT"},
+	{Table: logsTable, Name: "user_name", Postgres: "TEXT", SQLite: "TEXT"},
+	{Table: logsTable, Name: "details", Postgres: "TEXT", SQLite: "TEXT"},
+	{Table: logsTable, Name: "migration_name", Postgres: "TEXT", SQLite: "TEXT"},
+}
+
+// tableColumns returns the lower-cased column names of a table
+func (r *Runner) tableColumns(q queryer, ctx context.Context, table string) (map[string]bool, error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`SELECT * FROM %s WHERE 1 = 0;`, table))
+	if err != nil {
+		return nil, fmt.Errorf("read columns of %s: %v", table, err)
+	}
+	columns, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read columns of %s: %v", table, err)
+	}
+	present := map[string]bool{}
+	for _, c := range columns {
+		present[strings.ToLower(c)] = true
+	}
+	return present, nil
+}
+
+// missingColumns returns ALTER TABLE statements adding the columns that
+// existing tables lack. Missing tables are skipped; the first step creates
+// them complete.
+func (r *Runner) missingColumns(q queryer, ctx context.Context, columns []trackingColumn) ([]string, error) {
+	present := map[string]map[string]bool{}
+	var statements []string
+	for _, column := range columns {
+		table := column.Table(r.tables)
+		if _, seen := present[table]; !seen {
+			exists, err := r.tableExists(q, ctx, table)
+			if err != nil {
+				return nil, err
+			}
+			present[table] = nil
+			if exists {
+				if present[table], err = r.tableColumns(q, ctx, table); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if present[table] == nil || present[table][column.Name] {
+			continue
+		}
+		definition := column.Postgres
+		if r.dialect.Name() == "sqlite" {
+			definition = column.SQLite
+		}
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column.Name, definition))
+	}
+	return statements, nil
+}
+
+// pendingUpgrades lists the upgrades the tracking tables need after version
+func (r *Runner) pendingUpgrades(q queryer, ctx context.Context, version int) ([]PendingUpgrade, error) {
+	var pending []PendingUpgrade
+	for _, upgrade := range metadataUpgrades {
+		if upgrade.Version <= version {
+			continue
+		}
+		statements, err := upgrade.Statements(r, q, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("plan tracking table upgrade %d: %v", upgrade.Version, err)
+		}
+		pending = append(pending, PendingUpgrade{Version: upgrade.Version, Description: upgrade.Description, Statements: statements})
+	}
+	return pending, nil
+}
+
+// upgradeMetadata brings the tracking tables to the layout of this release.
+// Upgrades run in one transaction under the migration lock; a database that
+// is up to date is only read.
+func (r *Runner) upgradeMetadata(conn *sql.Conn, ctx context.Context) error {
+	version, err := r.trackingVersion(conn, ctx)
+	if err != nil {
+		return err
+	}
+	if version > MetadataVersion() {
+		return &MetadataTooNewError{Version: version, Supported: MetadataVersion()}
+	}
+	if version == MetadataVersion() {
+		return nil
+	}
+
+	// Advisory locks are reentrant, so runs already holding the lock pass
+	unlock, err := r.acquireLock(conn, ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tracking table upgrade: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, r.versionTableDDL()); err != nil {
+		return fmt.Errorf("create %s: %v", r.tables.Version(), err)
+	}
+	// Another process may have upgraded while this one waited for the lock
+	version, err = r.trackingVersion(tx, ctx)
+	if err != nil {
+		return err
+	}
+	if version >= MetadataVersion() {
+		return tx.Commit()
+	}
+
+	r.printf("🔧 Upgrading tracking tables from version %d to %d...\n", version, MetadataVersion())
+	for _, upgrade := range metadataUpgrades {
+		if upgrade.Version <= version {
+			continue
+		}
+		// Each step inspects the tables as the previous steps left them
+		statements, err := upgrade.Statements(r, tx, ctx)
+		if err != nil {
+			return fmt.Errorf("plan tracking table upgrade %d: %v", upgrade.Version, err)
+		}
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("tracking table upgrade %d (%s): %v", upgrade.Version, upgrade.Description, err)
+			}
+		}
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (version, description, upgraded_by) VALUES ($1, $2, $3);`, r.tables.Version()),
+			upgrade.Version, upgrade.Description, utils.CurrentUser())
+		if err != nil {
+			return fmt.Errorf("record tracking table upgrade %d: %v", upgrade.Version, err)
+		}
+		r.printf("✅ Version %d: %s\n", upgrade.Version, upgrade.Description)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tracking table upgrade: %v", err)
+	}
+	return r.logMigrationActivity(conn, ctx, "INFO", fmt.Sprintf("Tracking tables upgraded to version %d", MetadataVersion()), "",
+		fmt.Sprintf("Upgraded from version %d", version))
+}
+
+// SelfUpgrade upgrades the tracking tables of the CLI's database to the
+// layout of this release. With dryRun it prints the pending upgrades and
+// their SQL without changing anything.
+func SelfUpgrade(ctx context.Context, dryRun bool) error {
+	r, err := cliRunner()
+	if err != nil {
+		return err
+	}
+	conn, err := r.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	version, err := r.trackingVersion(conn, ctx)
+	if err != nil {
+		return err
+	}
+	if version > MetadataVersion() {
+		return &MetadataTooNewError{Version: version, Supported: MetadataVersion()}
+	}
+	if version == MetadataVersion() {
+		r.printf("✅ Tracking tables are up to date (version %d)\n", version)
+		return nil
+	}
+	if !dryRun {
+		return r.upgradeMetadata(conn, ctx)
+	}
+
+	pending, err := r.pendingUpgrades(conn, ctx, version)
+	if err != nil {
+		return err
+	}
+	r.printf("🔧 Tracking tables are at version %d; this release uses version %d\n", version, MetadataVersion())
+	r.printf("\n-- Layout version table\n%s\n", r.versionTableDDL())
+	for _, upgrade := range pending {
+		r.printf("\n-- Version %d: %s\n", upgrade.Version, upgrade.Description)
+		if len(upgrade.Statements) == 0 {
+			r.println("-- (nothing to change in this database)")
+		}
+		for _, stmt := range upgrade.Statements {
+			r.println(stmt)
+		}
+	}
+	r.println("\n(Dry run only. The tracking tables were not changed.)")
+	return nil
+}
@@ -0,0 +1,235 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// earlyLayout creates tracking tables as the earliest releases did, with one
+// applied migration
+const earlyLayout = `
+CREATE TABLE schema_migrations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	filename TEXT NOT NULL UNIQUE,
+	applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE migration_logs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	level TEXT NOT NULL,
+	message TEXT NOT NULL
+);
+CREATE TABLE users (id INTEGER PRIMARY KEY);
+INSERT INTO schema_migrations (filename) VALUES ('20240101000000_users.sql');
+`
+
+func TestUpgradeEarlyLayout(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	if _, err := db.ExecContext(ctx, earlyLayout); err != nil {
+		t.Fatalf("create early layout: %v", err)
+	}
+
+	r := testRunner(db, mapFS(
+		"20240101000000_users.sql", migration("20240101000000", "users"),
+		"20240101000001_posts.sql", migration("20240101000001", "posts"),
+	), Options{})
+	results, err := r.Migrate(ctx, "")
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if len(results) != 1 || results[0].Filename != "20240101000001_posts.sql" {
+		t.Fatalf("migrate applied %+v, want only the posts migration", results)
+	}
+	expectTables(t, db, "users", "posts")
+
+	conn, err := r.conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	version, err := r.trackingVersion(conn, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != MetadataVersion() {
+		t.Errorf("tracking version = %d, want %d", version, MetadataVersion())
+	}
+	for _, table := range []string{r.tables.Migrations, r.tables.Logs} {
+		columns, err := r.tableColumns(conn, ctx, table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, column := range earlyReleaseColumns {
+			if column.Table(r.tables) == table && !columns[column.Name] {
+				t.Errorf("%s lacks column %s after the upgrade", table, column.Name)
+			}
+		}
+	}
+
+	// The row written before the upgrade takes the status default
+	var status string
+	if err := db.QueryRowContext(ctx, `SELECT status FROM schema_migrations WHERE filename = '20240101000000_users.sql';`).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status != "success" {
+		t.Errorf("status of the early row = %q, want success", status)
+	}
+}
+
+func TestMissingColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   []string
+	}{
+		{
+			name: "no tables",
+		},
+		{
+			name: "current layout",
+			schema: `CREATE TABLE schema_migrations (id INTEGER PRIMARY KEY, filename TEXT, execution_time INTEGER, executed_by TEXT,
+				status TEXT, error_message TEXT, checksum TEXT, table_affected TEXT);
+				CREATE TABLE migration_logs (id INTEGER PRIMARY KEY, level TEXT, message TEXT, user_name TEXT, details TEXT, migration_name TEXT);`,
+		},
+		{
+			name: "columns missing",
+			schema: `CREATE TABLE schema_migrations (id INTEGER PRIMARY KEY, filename TEXT, STATUS TEXT, checksum TEXT);
+				CREATE TABLE migration_logs (id INTEGER PRIMARY KEY, level TEXT, message TEXT, details TEXT);`,
+			want: []string{
+				"ALTER TABLE schema_migrations ADD COLUMN execution_time INTEGER;",
+				"ALTER TABLE schema_migrations ADD COLUMN executed_by TEXT;",
+				"ALTER TABLE schema_migrations ADD COLUMN error_message TEXT;",
+				"ALTER TABLE schema_migrations ADD COLUMN table_affected TEXT;",
+				"ALTER TABLE migration_logs ADD COLUMN user_name TEXT;",
+				"ALTER TABLE migration_logs ADD COLUMN migration_name TEXT;",
+			},
+		},
+		{
+			name:   "logs table missing",
+			schema: `CREATE TABLE schema_migrations (id INTEGER PRIMARY KEY, filename TEXT);`,
+			want: []string{
+				"ALTER TABLE schema_migrations ADD COLUMN execution_time INTEGER;",
+				"ALTER TABLE schema_migrations ADD COLUMN executed_by TEXT;",
+				"ALTER TABLE schema_migrations ADD COLUMN status TEXT DEFAULT 'success';",
+				"ALTER TABLE schema_migrations ADD COLUMN error_message TEXT;",
+				"ALTER TABLE schema_migrations ADD COLUMN checksum TEXT;",
+				"ALTER TABLE schema_migrations ADD COLUMN table_affected TEXT;",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := testDB(t)
+			if tt.schema != "" {
+				if _, err := db.ExecContext(ctx, tt.schema); err != nil {
+					t.Fatalf("create schema: %v", err)
+				}
+			}
+			r := testRunner(db, mapFS(), Options{})
+			got, err := r.missingColumns(db, ctx, earlyReleaseColumns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("missingColumns = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("statement %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestUpgradeFreshDatabase(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	r := testRunner(db, mapFS(), Options{})
+
+	// The second run finds the tables at the current version and only reads
+	for run := 1; run <= 2; run++ {
+		conn, err := r.open(ctx)
+		if err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		version, err := r.trackingVersion(conn, ctx)
+		conn.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != MetadataVersion() {
+			t.Fatalf("run %d: tracking version = %d, want %d", run, version, MetadataVersion())
+		}
+	}
+
+	var steps int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations_version;`).Scan(&steps); err != nil {
+		t.Fatal(err)
+	}
+	if steps != len(metadataUpgrades) {
+		t.Errorf("recorded %d upgrade steps, want %d", steps, len(metadataUpgrades))
+	}
+}
+
+func TestUpgradeRefusesNewerLayout(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	r := testRunner(db, mapFS(), Options{})
+	conn, err := r.open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if _, err := db.ExecContext(ctx, `INSERT INTO schema_migrations_version (version, description) VALUES ($1, 'from the future');`, MetadataVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.Migrate(ctx, "")
+	var tooNew *MetadataTooNewError
+	if !errors.As(err, &tooNew) || tooNew.Version != MetadataVersion()+1 {
+		t.Fatalf("migrate error = %v, want a MetadataTooNewError for version %d", err, MetadataVersion()+1)
+	}
+}
+
+func TestRollbackAfterUpgrade(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	if _, err := db.ExecContext(ctx, earlyLayout); err != nil {
+		t.Fatalf("create early layout: %v", err)
+	}
+	r := testRunner(db, mapFS("20240101000000_users.sql", migration("20240101000000", "users")), Options{})
+
+	// The migration applied before the upgrade is rolled back from its file
+	results, err := r.Rollback(ctx, 1)
+	if err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if len(results) != 1 || results[0].Filename != "20240101000000_users.sql" {
+		t.Fatalf("rollback handled %+v, want the users migration", results)
+	}
+	expectTables(t, db)
+
+	// The backfilled up event is followed by the rollback
+	rows, err := db.QueryContext(ctx, `SELECT direction FROM schema_migration_history ORDER BY id;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var directions []string
+	for rows.Next() {
+		var direction string
+		if err := rows.Scan(&direction); err != nil {
+			t.Fatal(err)
+		}
+		directions = append(directions, direction)
+	}
+	if want := []string{"up", "down"}; strings.Join(directions, ",") != strings.Join(want, ",") {
+		t.Errorf("history directions = %v, want %v", directions, want)
+	}
+}
@@ -0,0 +1,249 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/ridoystarlord/migrato/introspect"
+)
+
+// RoundTripError is returned by Verify when a migration's down SQL does not
+// restore the schema it started from, or applying it again differs
+type RoundTripError struct {
+	Filename string
+	Step     string // "up", "down" or "up again"
+	Problems []string
+	Err      error // set when the step's SQL failed
+}
+
+func (e *RoundTripError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s failed at %s: %v", e.Filename, e.Step, e.Err)
+	}
+	return fmt.Sprintf("%s failed the round trip at %s: %d difference(s)", e.Filename, e.Step, len(e.Problems))
+}
+
+func (e *RoundTripError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyMigrations checks every migration of the CLI's migrations directory
+// with an up, down and up round trip
+func VerifyMigrations(ctx context.Context) error {
+	r, err := cliRunner()
+	if err != nil {
+		return err
+	}
+	return r.Verify(ctx)
+}
+
+// Verify replays every migration into a scratch schema as up, down and up
+// again, introspecting after each step. The down SQL must restore exactly the
+// schema before the up SQL, and the second up must produce the same schema
+// as the first. The real tables are never touched.
+func (r *Runner) Verify(ctx context.Context) error {
+	files, err := r.migrationFiles()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		r.println("✅ No migrations to verify.")
+		return nil
+	}
+
+	scratch, err := openScratchSchema(ctx, r.dialect)
+	if err != nil {
+		return err
+	}
+	defer scratch.close()
+
+	before, err := scratch.introspect(ctx)
+	if err != nil {
+		return fmt.Errorf("introspecting scratch schema: %v", err)
+	}
+
+	r.printf("🔄 Verifying %d migration(s) in a scratch schema...\n", len(files))
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("interrupted before %s: %w", f, err)
+		}
+		sections, err := r.readMigration(f)
+		if err != nil {
+			return err
+		}
+
+		step := func(name, script string, line int) ([]introspect.ExistingTable, error) {
+			if err := scratch.exec(ctx, f, script, line); err != nil {
+				return nil, &RoundTripError{Filename: f, Step: name, Err: err}
+			}
+			tables, err := scratch.introspect(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("introspecting scratch schema after %s of %s: %v", name, f, err)
+			}
+			return tables, nil
+		}
+
+		up, err := step("up", sections.Up, sections.UpLine)
+		if err != nil {
+			return err
+		}
+		down, err := step("down", sections.Down, sections.DownLine)
+		if err != nil {
+			return err
+		}
+		if problems := compareSchemas(before, down); len(problems) > 0 {
+			r.printRoundTripProblems(f, "rolling back does not restore the previous schema", problems)
+			return &RoundTripError{Filename: f, Step: "down", Problems: problems}
+		}
+		again, err := step("up again", sections.Up, sections.UpLine)
+		if err != nil {
+			return err
+		}
+		if problems := compareSchemas(up, again); len(problems) > 0 {
+			r.printRoundTripProblems(f, "applying it again gives a different schema", problems)
+			return &RoundTripError{Filename: f, Step: "up again", Problems: problems}
+		}
+
+		r.printf("✅ %s\n", f)
+		before = again
+	}
+
+	r.printf("✅ All %d migration(s) round-trip cleanly.\n", len(files))
+	return nil
+}
+
+func (r *Runner) printRoundTripProblems(filename, summary string, problems []string) {
+	r.printf("❌ %s: %s:\n", filename, summary)
+	for _, problem := range problems {
+		r.printf("   - %s\n", problem)
+	}
+}
+
+// normalizeSchema orders tables, foreign keys and indexes by name so that
+// schemas can be compared regardless of catalog order
+func normalizeSchema(tables []introspect.ExistingTable) []introspect.ExistingTable {
+	for _, t := range tables {
+		sort.Slice(t.ForeignKeys, func(i, j int) bool { return t.ForeignKeys[i].ConstraintName < t.ForeignKeys[j].ConstraintName })
+		sort.Slice(t.Indexes, func(i, j int) bool { return t.Indexes[i].IndexName < t.Indexes[j].IndexName })
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].TableName < tables[j].TableName })
+	return tables
+}
+
+// compareSchemas lists how got differs from want
+func compareSchemas(want, got []introspect.ExistingTable) []string {
+	wantTables := map[string]introspect.ExistingTable{}
+	for _, t := range want {
+		wantTables[t.TableName] = t
+	}
+	gotTables := map[string]introspect.ExistingTable{}
+	for _, t := range got {
+		gotTables[t.TableName] = t
+	}
+
+	var problems []string
+	for _, t := range normalizeSchema(want) {
+		g, ok := gotTables[t.TableName]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("table %s is missing", t.TableName))
+			continue
+		}
+		problems = append(problems, compareTables(t, g)...)
+	}
+	for _, t := range normalizeSchema(got) {
+		if _, ok := wantTables[t.TableName]; !ok {
+			problems = append(problems, fmt.Sprintf("table %s is left over", t.TableName))
+		}
+	}
+	return problems
+}
+
+// compareTables lists the columns, foreign keys and indexes that differ
+func compareTables(want, got introspect.ExistingTable) []string {
+	var problems []string
+	table := want.TableName
+
+	gotColumns := map[string]introspect.ExistingColumn{}
+	for _, c := range got.Columns {
+		gotColumns[c.ColumnName] = c
+	}
+	wantColumns := map[string]bool{}
+	for i, c := range want.Columns {
+		wantColumns[c.ColumnName] = true
+		g, ok := gotColumns[c.ColumnName]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("column %s.%s is missing", table, c.ColumnName))
+		case !reflect.DeepEqual(c, g):
+			problems = append(problems, fmt.Sprintf("column %s.%s differs: %s, was %s", table, c.ColumnName, describeColumn(g), describeColumn(c)))
+		case i >= len(got.Columns) || got.Columns[i].ColumnName != c.ColumnName:
+			problems = append(problems, fmt.Sprintf("column %s.%s moved", table, c.ColumnName))
+		}
+	}
+	for _, c := range got.Columns {
+		if !wantColumns[c.ColumnName] {
+			problems = append(problems, fmt.Sprintf("column %s.%s is left over", table, c.ColumnName))
+		}
+	}
+
+	gotKeys := map[string]introspect.ExistingForeignKey{}
+	for _, fk := range got.ForeignKeys {
+		gotKeys[fk.ConstraintName] = fk
+	}
+	for _, fk := range want.ForeignKeys {
+		g, ok := gotKeys[fk.ConstraintName]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("foreign key %s on %s is missing", fk.ConstraintName, table))
+		} else if !reflect.DeepEqual(fk, g) {
+			problems = append(problems, fmt.Sprintf("foreign key %s on %s differs", fk.ConstraintName, table))
+		}
+		delete(gotKeys, fk.ConstraintName)
+	}
+	for _, fk := range got.ForeignKeys {
+		if _, ok := gotKeys[fk.ConstraintName]; ok {
+			problems = append(problems, fmt.Sprintf("foreign key %s on %s is left over", fk.ConstraintName, table))
+		}
+	}
+
+	gotIndexes := map[string]introspect.ExistingIndex{}
+	for _, idx := range got.Indexes {
+		gotIndexes[idx.IndexName] = idx
+	}
+	for _, idx := range want.Indexes {
+		g, ok := gotIndexes[idx.IndexName]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("index %s on %s is missing", idx.IndexName, table))
+		} else if !reflect.DeepEqual(idx, g) {
+			problems = append(problems, fmt.Sprintf("index %s on %s differs", idx.IndexName, table))
+		}
+		delete(gotIndexes, idx.IndexName)
+	}
+	for _, idx := range got.Indexes {
+		if _, ok := gotIndexes[idx.IndexName]; ok {
+			problems = append(problems, fmt.Sprintf("index %s on %s is left over", idx.IndexName, table))
+		}
+	}
+	return problems
+}
+
+func describeColumn(c introspect.ExistingColumn) string {
+	desc := c.ColumnType
+	if desc == "" {
+		desc = c.DataType
+	}
+	if !c.IsNullable {
+		desc += " NOT NULL"
+	}
+	if c.ColumnDefault != nil {
+		desc += " DEFAULT " + *c.ColumnDefault
+	}
+	if c.IsPrimaryKey {
+		desc += " PRIMARY KEY"
+	}
+	if c.IsUnique {
+		desc += " UNIQUE"
+	}
+	return desc
+}
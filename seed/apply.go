@@ -0,0 +1,239 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ridoystarlord/migrato/database"
+	"github.com/ridoystarlord/migrato/dialect"
+	"github.com/ridoystarlord/migrato/runner"
+	"github.com/ridoystarlord/migrato/utils"
+)
+
+// Options selects the seed files to apply
+type Options struct {
+	Dir    string // seeds directory
+	Env    string // environment whose subdirectory is applied as well
+	Table  string // table recording applied files, runner.DefaultTables.Seeds when empty
+	Force  bool   // reapply files whose checksum is unchanged
+	DryRun bool   // print what would be seeded without writing
+}
+
+// Apply upserts the seed files of an environment. Each file is applied in its
+// own transaction and recorded in the seeds table; files whose checksum
+// matches the recorded one are skipped.
+func Apply(ctx context.Context, opts Options) error {
+	files, err := Load(opts.Dir, opts.Env)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Printf("📭 No seed files found in %s\n", opts.Dir)
+		return nil
+	}
+
+	db, d, err := database.GetDB()
+	if err != nil {
+		return fmt.Errorf("get connection: %v", err)
+	}
+	return apply(ctx, db, d, files, opts)
+}
+
+// apply upserts the loaded files into db, which speaks dialect d
+func apply(ctx context.Context, db *sql.DB, d dialect.Dialect, files []File, opts Options) error {
+	table := opts.Table
+	if table == "" {
+		table = runner.DefaultTables.Seeds
+	}
+	if _, err := db.ExecContext(ctx, d.SeedTableDDL(table)); err != nil {
+		return fmt.Errorf("failed to create %s table: %v", table, err)
+	}
+
+	checksums, err := appliedChecksums(ctx, db, table)
+	if err != nil {
+		return err
+	}
+
+	seeded, skipped := 0, 0
+	for _, f := range files {
+		if checksums[f.Path] == f.Checksum && !opts.Force {
+			skipped++
+			continue
+		}
+
+		if opts.DryRun {
+			fmt.Printf("🌱 Would seed %s: %d row(s) into %s\n", f.Path, len(f.Rows), f.Table)
+			seeded++
+			continue
+		}
+
+		fmt.Printf("🌱 Seeding %s: %d row(s) into %s\n", f.Path, len(f.Rows), f.Table)
+		if err := applyFile(ctx, db, d, table, f, opts.Env); err != nil {
+			return fmt.Errorf("seed %s: %v", f.Path, err)
+		}
+		seeded++
+	}
+
+	switch {
+	case opts.DryRun:
+		fmt.Printf("(Dry run only. %d file(s) would be seeded, %d unchanged.)\n", seeded, skipped)
+	case seeded == 0:
+		fmt.Printf("✅ Seeds are up to date (%d unchanged file(s))\n", skipped)
+	default:
+		fmt.Printf("✅ Seeded %d file(s), %d unchanged\n", seeded, skipped)
+	}
+	return nil
+}
+
+func appliedChecksums(ctx context.Context, db *sql.DB, table string) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT filename, checksum FROM %s;`, table))
+	if err != nil {
+		return nil, fmt.Errorf("read applied seeds: %v", err)
+	}
+	defer rows.Close()
+
+	checksums := map[string]string{}
+	for rows.Next() {
+		var filename, checksum string
+		if err := rows.Scan(&filename, &checksum); err != nil {
+			return nil, err
+		}
+		checksums[filename] = checksum
+	}
+	return checksums, rows.Err()
+}
+
+func applyFile(ctx context.Context, db *sql.DB, d dialect.Dialect, table string, f File, env string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	resolver := &referenceResolver{tx: tx, cache: map[string]interface{}{}}
+	for i, row := range f.Rows {
+		columns := make([]string, 0, len(row))
+		for column := range row {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+
+		values := make([]interface{}, len(columns))
+		for j, column := range columns {
+			value, err := seedValue(row[column])
+			if err != nil {
+				return fmt.Errorf("row %d, column %s: %v", i+1, column, err)
+			}
+			if ref, ok := f.References[column]; ok && value != nil {
+				value, err = resolver.resolve(ctx, ref, value)
+				if err != nil {
+					return fmt.Errorf("row %d, column %s: %v", i+1, column, err)
+				}
+			}
+			values[j] = value
+		}
+
+		if _, err := tx.ExecContext(ctx, upsertSQL(f.Table, columns, f.Key), values...); err != nil {
+			return fmt.Errorf("row %d: %v", i+1, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (filename, environment, checksum, row_count, applied_at, applied_by)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, $5)
+		ON CONFLICT (filename) DO UPDATE SET
+			environment = EXCLUDED.environment,
+			checksum = EXCLUDED.checksum,
+			row_count = EXCLUDED.row_count,
+			applied_at = EXCLUDED.applied_at,
+			applied_by = EXCLUDED.applied_by;
+	`, table), f.Path, env, f.Checksum, len(f.Rows), utils.CurrentUser())
+	if err != nil {
+		return fmt.Errorf("record seed: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// upsertSQL inserts a row, updating the other columns when the key already exists
+func upsertSQL(table string, columns, key []string) string {
+	keys := map[string]bool{}
+	quotedKey := make([]string, len(key))
+	for i, column := range key {
+		keys[column] = true
+		quotedKey[i] = dialect.QuoteIdent(column)
+	}
+
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	var updates []string
+	for i, column := range columns {
+		quoted[i] = dialect.QuoteIdent(column)
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		if !keys[column] {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", quoted[i], quoted[i]))
+		}
+	}
+
+	action := "DO NOTHING"
+	if len(updates) > 0 {
+		action = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s;",
+		dialect.QuoteIdent(table), strings.Join(quoted, ", "), strings.Join(placeholders, ", "),
+		strings.Join(quotedKey, ", "), action)
+}
+
+// referenceResolver looks up referenced rows by natural key inside the seed transaction
+type referenceResolver struct {
+	tx    *sql.Tx
+	cache map[string]interface{}
+}
+
+func (r *referenceResolver) resolve(ctx context.Context, ref Reference, value interface{}) (interface{}, error) {
+	column := ref.Column
+	if column == "" {
+		column = "id"
+	}
+
+	cacheKey := fmt.Sprintf("%s.%s.%s=%v", ref.Table, ref.Key, column, value)
+	if resolved, ok := r.cache[cacheKey]; ok {
+		return resolved, nil
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1;", dialect.QuoteIdent(column), dialect.QuoteIdent(ref.Table), dialect.QuoteIdent(ref.Key))
+	var resolved interface{}
+	err := r.tx.QueryRowContext(ctx, query, value).Scan(&resolved)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no row in %s with %s = %v", ref.Table, ref.Key, value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s.%s = %v: %v", ref.Table, ref.Key, value, err)
+	}
+
+	r.cache[cacheKey] = resolved
+	return resolved, nil
+}
+
+// seedValue converts a decoded value into a query argument. Nested maps and
+// lists are stored as JSON.
+func seedValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	case map[string]interface{}, []interface{}:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(encoded), nil
+	}
+	return value, nil
+}
@@ -0,0 +1,143 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/ridoystarlord/migrato/dialect"
+)
+
+// testDB opens a SQLite database holding a roles and a users table
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open(dialect.SQLite.DriverName(), dialect.SQLite.DataSourceName("sqlite://"+path))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`
+		CREATE TABLE roles (id INTEGER PRIMARY KEY, name TEXT UNIQUE, level INTEGER);
+		CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE, role_id INTEGER REFERENCES roles (id));`)
+	if err != nil {
+		t.Fatalf("create tables: %v", err)
+	}
+	return db
+}
+
+// seedAll loads dir and applies it to db
+func seedAll(t *testing.T, db *sql.DB, dir string, opts Options) error {
+	t.Helper()
+	files, err := Load(dir, opts.Env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Dir = dir
+	return apply(context.Background(), db, dialect.SQLite, files, opts)
+}
+
+// count returns the result of a COUNT query
+func count(t *testing.T, db *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func TestApply(t *testing.T) {
+	db := testDB(t)
+	dir := writeSeeds(t,
+		"roles.yaml", "key: name\nrows:\n  - name: admin\n    level: 3\n  - name: viewer\n    level: 1\n",
+		"users.csv", "email:key,role_id->roles.name\nann@example.com,admin\nbob@example.com,viewer\n",
+	)
+	if err := seedAll(t, db, dir, Options{Env: "dev"}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	// References resolve through the natural key of the other table
+	var role string
+	if err := db.QueryRow(`SELECT roles.name FROM users JOIN roles ON roles.id = users.role_id WHERE email = 'bob@example.com'`).Scan(&role); err != nil {
+		t.Fatal(err)
+	}
+	if role != "viewer" {
+		t.Errorf("bob's role = %q, want viewer", role)
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM schema_seeds WHERE environment = 'dev'`); n != 2 {
+		t.Errorf("recorded %d seed files, want 2", n)
+	}
+
+	// Rows are upserted on the key, so an edited file updates them in place
+	writeSeedsInto(t, dir, "roles.yaml", "key: name\nrows:\n  - name: admin\n    level: 5\n  - name: viewer\n    level: 1\n")
+	if err := seedAll(t, db, dir, Options{}); err != nil {
+		t.Fatalf("seed again: %v", err)
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM roles`); n != 2 {
+		t.Errorf("roles has %d rows, want 2", n)
+	}
+	if n := count(t, db, `SELECT level FROM roles WHERE name = 'admin'`); n != 5 {
+		t.Errorf("admin level = %d, want 5", n)
+	}
+}
+
+func TestApplySkipsUnchanged(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		level int // the admin level after the second run
+	}{
+		{"unchanged files are skipped", Options{}, 9},
+		{"force reapplies them", Options{Force: true}, 3},
+		{"dry run writes nothing", Options{DryRun: true, Force: true}, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t)
+			dir := writeSeeds(t, "roles.yaml", "key: name\nrows:\n  - name: admin\n    level: 3\n")
+			if err := seedAll(t, db, dir, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.Exec(`UPDATE roles SET level = 9`); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := seedAll(t, db, dir, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			if n := count(t, db, `SELECT level FROM roles WHERE name = 'admin'`); n != tt.level {
+				t.Errorf("admin level = %d, want %d", n, tt.level)
+			}
+		})
+	}
+}
+
+func TestApplyCustomTable(t *testing.T) {
+	db := testDB(t)
+	dir := writeSeeds(t, "roles.yaml", "key: name\nrows:\n  - name: admin\n")
+	if err := seedAll(t, db, dir, Options{Table: "app_seeds"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM app_seeds`); n != 1 {
+		t.Errorf("app_seeds has %d rows, want 1", n)
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_seeds'`); n != 0 {
+		t.Error("the default seeds table was created as well")
+	}
+}
+
+func TestApplyRollsBackFailedFile(t *testing.T) {
+	db := testDB(t)
+	dir := writeSeeds(t, "users.yaml", "key: email\nreferences:\n  role_id: roles.name\nrows:\n  - email: a@example.com\n  - email: b@example.com\n    role_id: missing\n")
+	if err := seedAll(t, db, dir, Options{}); err == nil {
+		t.Fatal("seed succeeded with an unresolvable reference")
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM users`); n != 0 {
+		t.Errorf("users has %d rows after the failed file, want 0", n)
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM schema_seeds`); n != 0 {
+		t.Errorf("the failed file was recorded")
+	}
+}
@@ -0,0 +1,314 @@
+package seed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File holds the rows of one seed file. Every file seeds a single table.
+type File struct {
+	Path       string // relative to the seeds directory, e.g. dev/users.yaml
+	Table      string
+	Key        []string // columns of the unique key used as the ON CONFLICT target
+	References map[string]Reference
+	Rows       []map[string]interface{}
+	Checksum   string
+}
+
+// Reference resolves the value of a column through a natural key of another
+// table: the row value is looked up in Table.Key and replaced by Table.Column.
+type Reference struct {
+	Table  string `yaml:"table" json:"table"`
+	Key    string `yaml:"key" json:"key"`
+	Column string `yaml:"column,omitempty" json:"column,omitempty"` // defaults to id
+}
+
+// fileSpec is the layout of YAML and JSON seed files
+type fileSpec struct {
+	Table      string                   `yaml:"table" json:"table"`
+	Key        keyList                  `yaml:"key" json:"key"`
+	References map[string]referenceSpec `yaml:"references" json:"references"`
+	Rows       []map[string]interface{} `yaml:"rows" json:"rows"`
+}
+
+// keyList accepts a single column name or a list of them
+type keyList []string
+
+func (k *keyList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*k = keyList{node.Value}
+		return nil
+	}
+	var columns []string
+	if err := node.Decode(&columns); err != nil {
+		return err
+	}
+	*k = columns
+	return nil
+}
+
+func (k *keyList) UnmarshalJSON(data []byte) error {
+	var column string
+	if err := json.Unmarshal(data, &column); err == nil {
+		*k = keyList{column}
+		return nil
+	}
+	var columns []string
+	if err := json.Unmarshal(data, &columns); err != nil {
+		return err
+	}
+	*k = columns
+	return nil
+}
+
+// referenceSpec accepts either a mapping or the "table.key" shorthand
+type referenceSpec Reference
+
+func (r *referenceSpec) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		ref, err := parseReference(node.Value)
+		*r = referenceSpec(ref)
+		return err
+	}
+	var ref Reference
+	if err := node.Decode(&ref); err != nil {
+		return err
+	}
+	*r = referenceSpec(ref)
+	return nil
+}
+
+func (r *referenceSpec) UnmarshalJSON(data []byte) error {
+	var shorthand string
+	if err := json.Unmarshal(data, &shorthand); err == nil {
+		ref, err := parseReference(shorthand)
+		*r = referenceSpec(ref)
+		return err
+	}
+	var ref Reference
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return err
+	}
+	*r = referenceSpec(ref)
+	return nil
+}
+
+// parseReference parses the "table.key" shorthand
+func parseReference(value string) (Reference, error) {
+	parts := strings.Split(strings.TrimSpace(value), ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Reference{}, fmt.Errorf("invalid reference '%s', expected table.key", value)
+	}
+	return Reference{Table: parts[0], Key: parts[1]}, nil
+}
+
+// orderPrefix is an optional numeric prefix such as 01_ used to order files
+var orderPrefix = regexp.MustCompile(`^\d+[_-]`)
+
+// Load reads the seed files of an environment: the files directly in dir are
+// always included, those in dir/<env> only for that environment. A missing
+// dir/<env> counts as empty. Files are ordered so that referenced tables are
+// seeded first.
+func Load(dir, env string) ([]File, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("seeds directory '%s' does not exist", dir)
+	}
+
+	files, err := loadDir(dir, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if env != "" {
+		// --env selects the environment of every command, so most
+		// environments have no seeds of their own and only get the shared files
+		_, err := os.Stat(filepath.Join(dir, env))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read seeds dir: %v", err)
+		}
+		if err == nil {
+			envFiles, err := loadDir(dir, env)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, envFiles...)
+		}
+	}
+
+	return orderByReferences(files), nil
+}
+
+func loadDir(root, sub string) ([]File, error) {
+	entries, err := os.ReadDir(filepath.Join(root, sub))
+	if err != nil {
+		return nil, fmt.Errorf("read seeds dir: %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json", ".csv":
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var files []File
+	for _, name := range names {
+		f, err := loadFile(root, filepath.Join(sub, name))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func loadFile(root, path string) (File, error) {
+	content, err := os.ReadFile(filepath.Join(root, path))
+	if err != nil {
+		return File{}, fmt.Errorf("read seed file %s: %v", path, err)
+	}
+
+	base := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(base))
+	f := File{
+		Path:     filepath.ToSlash(path),
+		Table:    orderPrefix.ReplaceAllString(strings.TrimSuffix(base, filepath.Ext(base)), ""),
+		Checksum: fmt.Sprintf("%x", sha256.Sum256(content)),
+	}
+
+	switch ext {
+	case ".csv":
+		err = parseCSV(content, &f)
+	case ".json":
+		var spec fileSpec
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err = decoder.Decode(&spec); err == nil {
+			err = applySpec(spec, &f)
+		}
+	default:
+		var spec fileSpec
+		if err = yaml.Unmarshal(content, &spec); err == nil {
+			err = applySpec(spec, &f)
+		}
+	}
+	if err != nil {
+		return File{}, fmt.Errorf("parse seed file %s: %v", path, err)
+	}
+
+	if len(f.Key) == 0 {
+		return File{}, fmt.Errorf("seed file %s declares no key; upserts need the unique key columns", path)
+	}
+	for i, row := range f.Rows {
+		for _, column := range f.Key {
+			if _, ok := row[column]; !ok {
+				return File{}, fmt.Errorf("seed file %s: row %d has no value for key column %s", path, i+1, column)
+			}
+		}
+	}
+
+	return f, nil
+}
+
+func applySpec(spec fileSpec, f *File) error {
+	if spec.Table != "" {
+		f.Table = spec.Table
+	}
+	f.Key = spec.Key
+	f.Rows = spec.Rows
+	f.References = map[string]Reference{}
+	for column, ref := range spec.References {
+		if ref.Table == "" || ref.Key == "" {
+			return fmt.Errorf("reference for column %s needs a table and a key", column)
+		}
+		f.References[column] = Reference(ref)
+	}
+	return nil
+}
+
+// parseCSV reads a CSV seed file. The header names the columns; "name:key"
+// marks a key column and "column->table.key" resolves a reference. Empty
+// cells are NULL.
+func parseCSV(content []byte, f *File) error {
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("missing header row")
+	}
+
+	f.References = map[string]Reference{}
+	var columns []string
+	for _, cell := range records[0] {
+		cell = strings.TrimSpace(cell)
+		column, target, isReference := strings.Cut(cell, "->")
+		column = strings.TrimSpace(column)
+		if strings.HasSuffix(column, ":key") {
+			column = strings.TrimSuffix(column, ":key")
+			f.Key = append(f.Key, column)
+		}
+		if isReference {
+			ref, err := parseReference(target)
+			if err != nil {
+				return fmt.Errorf("column %s: %v", column, err)
+			}
+			f.References[column] = ref
+		}
+		columns = append(columns, column)
+	}
+
+	for _, record := range records[1:] {
+		row := map[string]interface{}{}
+		for i, column := range columns {
+			if i >= len(record) || record[i] == "" {
+				row[column] = nil
+				continue
+			}
+			row[column] = record[i]
+		}
+		f.Rows = append(f.Rows, row)
+	}
+	return nil
+}
+
+// orderByReferences moves files after the files seeding the tables they
+// reference, keeping the original order otherwise
+func orderByReferences(files []File) []File {
+	var ordered []File
+	visited := make([]bool, len(files))
+	var visit func(i int)
+	visit = func(i int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		for _, ref := range files[i].References {
+			for j := range files {
+				if files[j].Table == ref.Table && j != i {
+					visit(j)
+				}
+			}
+		}
+		ordered = append(ordered, files[i])
+	}
+	for i := range files {
+		visit(i)
+	}
+	return ordered
+}
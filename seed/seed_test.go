@@ -0,0 +1,147 @@
+package seed
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeSeeds writes name and content pairs under a new seeds directory
+func writeSeeds(t *testing.T, pairs ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeSeedsInto(t, dir, pairs...)
+	return dir
+}
+
+// writeSeedsInto writes name and content pairs under dir
+func writeSeedsInto(t *testing.T, dir string, pairs ...string) {
+	t.Helper()
+	for i := 0; i+1 < len(pairs); i += 2 {
+		path := filepath.Join(dir, pairs[i])
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(pairs[i+1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		env     string
+		want    []string // paths in the order they are applied
+		wantErr string
+	}{
+		{
+			name:  "shared files only",
+			files: []string{"roles.yaml", "table: roles\nkey: name\nrows:\n  - name: admin\n", "notes.txt", "ignored"},
+			want:  []string{"roles.yaml"},
+		},
+		{
+			name:  "environment files follow the shared ones",
+			files: []string{"roles.yaml", "key: name\nrows: []\n", "dev/users.csv", "email:key\n", "prod/users.csv", "email:key\n"},
+			env:   "dev",
+			want:  []string{"roles.yaml", "dev/users.csv"},
+		},
+		{
+			name:  "missing environment directory",
+			files: []string{"roles.yaml", "key: name\nrows: []\n"},
+			env:   "staging",
+			want:  []string{"roles.yaml"},
+		},
+		{
+			name: "referenced tables first",
+			files: []string{
+				"01_users.yaml", "key: email\nreferences:\n  role_id: roles.name\nrows: []\n",
+				"02_roles.json", `{"key": "name", "rows": []}`,
+			},
+			want: []string{"02_roles.json", "01_users.yaml"},
+		},
+		{
+			name:    "no key",
+			files:   []string{"roles.yaml", "rows:\n  - name: admin\n"},
+			wantErr: "declares no key",
+		},
+		{
+			name:    "row without its key",
+			files:   []string{"roles.yaml", "key: name\nrows:\n  - label: Admin\n"},
+			wantErr: "row 1 has no value for key column name",
+		},
+		{
+			name:    "bad reference shorthand",
+			files:   []string{"users.yaml", "key: email\nreferences:\n  role_id: roles\nrows: []\n"},
+			wantErr: "invalid reference 'roles', expected table.key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Load(writeSeeds(t, tt.files...), tt.env)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, f := range files {
+				paths = append(paths, f.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("Load = %q, want %q", paths, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMissingDir(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "seeds"), ""); err == nil {
+		t.Fatal("Load accepted a missing seeds directory")
+	}
+}
+
+func TestLoadFormats(t *testing.T) {
+	dir := writeSeeds(t,
+		"01_roles.yaml", "key: name\nrows:\n  - name: admin\n    level: 3\n",
+		"users.json", `{"table": "accounts", "key": ["org", "email"], "references": {"role_id": {"table": "roles", "key": "name"}}, "rows": [{"org": 1, "email": "a@example.com", "role_id": "admin"}]}`,
+		"tags.csv", "name:key,role_id->roles.name,note\nnew,admin,\n",
+	)
+	files, err := Load(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := map[string]File{}
+	for _, f := range files {
+		byPath[f.Path] = f
+	}
+
+	roles := byPath["01_roles.yaml"]
+	if roles.Table != "roles" || !reflect.DeepEqual(roles.Key, []string{"name"}) || len(roles.Rows) != 1 || roles.Rows[0]["level"] != 3 {
+		t.Errorf("roles.yaml = %+v", roles)
+	}
+
+	users := byPath["users.json"]
+	if users.Table != "accounts" || !reflect.DeepEqual(users.Key, []string{"org", "email"}) {
+		t.Errorf("users.json table %s key %v, want accounts [org email]", users.Table, users.Key)
+	}
+	if ref := users.References["role_id"]; ref != (Reference{Table: "roles", Key: "name"}) {
+		t.Errorf("users.json reference = %+v", ref)
+	}
+
+	tags := byPath["tags.csv"]
+	wantRow := map[string]interface{}{"name": "new", "role_id": "admin", "note": nil}
+	if tags.Table != "tags" || !reflect.DeepEqual(tags.Key, []string{"name"}) || !reflect.DeepEqual(tags.Rows, []map[string]interface{}{wantRow}) {
+		t.Errorf("tags.csv = %+v", tags)
+	}
+	if ref := tags.References["role_id"]; ref != (Reference{Table: "roles", Key: "name"}) {
+		t.Errorf("tags.csv reference = %+v", ref)
+	}
+}
@@ -907,15 +907,28 @@ class MigratoStudio {
     )
       return;
     try {
-      const response = await fetch(
-        `/api/table/${this.currentTable}/bulk-delete`,
-        {
+      const request = (confirm) =>
+        fetch(`/api/table/${this.currentTable}/bulk-delete`, {
           method: "DELETE",
           headers: { "Content-Type": "application/json" },
-          body: JSON.stringify({ ids: this.selectedRows }),
-        }
-      );
-      if (!response.ok) throw new Error("Failed to delete rows");
+          body: JSON.stringify({ ids: this.selectedRows, confirm }),
+        });
+      let response = await request("");
+      if (response.status === 428) {
+        // Protected environment: the name must be typed to continue
+        const { confirm: env } = await response.json();
+        const typed = prompt(
+          `${env} is a protected environment. Type ${env} to delete these rows:`
+        );
+        if (typed === null) return;
+        response = await request(typed.trim());
+        if (response.status === 428)
+          throw new Error(`the name did not match ${env}`);
+      }
+      if (!response.ok) {
+        const message = await response.text();
+        throw new Error(message.trim() || "Failed to delete rows");
+      }
       this.selectedRows = [];
       this.loadTableData();
       this.showNotification("Rows deleted successfully!", "success");
@@ -0,0 +1,25 @@
+// Package templates embeds the default SQL templates used by the generator.
+// Every file can be overridden per project, see generator.TemplateDir.
+package templates
+
+import "embed"
+
+// FS holds the default templates, one file per operation
+//
+//go:embed *.tmpl
+var FS embed.FS
+
+// Names lists the templates the generator renders, without the .tmpl extension
+var Names = []string{
+	"create_extension",
+	"create_table",
+	"drop_table",
+	"add_column",
+	"drop_column",
+	"modify_column",
+	"rename_column",
+	"add_foreign_key",
+	"drop_foreign_key",
+	"create_index",
+	"drop_index",
+}
@@ -6,9 +6,12 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// EnvFile is the dotenv file loaded before reading the environment
+var EnvFile = ".env"
+
 func LoadEnv() {
-	err := godotenv.Load()
+	err := godotenv.Load(EnvFile)
 	if err != nil {
-		log.Println("ℹ️  No .env file found, continuing...")
+		log.Printf("ℹ️  No %s file found, continuing...\n", EnvFile)
 	}
 }
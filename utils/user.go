@@ -0,0 +1,13 @@
+package utils
+
+import "os/user"
+
+// CurrentUser names the operating system user recorded with migrations,
+// seeds and plans
+func CurrentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return "unknown"
+	}
+	return u.Username
+}
@@ -46,7 +46,7 @@ func NewSchemaValidator() (*SchemaValidator, error) {
 }
 
 // ValidateSchema validates a complete schema against database constraints
-func (v *SchemaValidator) ValidateSchema(models []schema.Model) (*ValidationResult, error) {
+func (v *SchemaValidator) ValidateSchema(ctx context.Context, models []schema.Model) (*ValidationResult, error) {
 	result := &ValidationResult{
 		Valid:    true,
 		Errors:   []ValidationError{},
@@ -54,8 +54,6 @@ func (v *SchemaValidator) ValidateSchema(models []schema.Model) (*ValidationResu
 		Info:     []ValidationError{},
 	}
 
-	ctx := context.Background()
-
 	// Get current database state
 	dbTables, err := v.getDatabaseTables(ctx)
 	if err != nil {